@@ -0,0 +1,132 @@
+package go_cake
+
+import "github.com/thoas/go-funk"
+
+const OPERATION_READ = "read"
+const OPERATION_INSERT = "insert"
+const OPERATION_UPDATE = "update"
+const OPERATION_DELETE = "delete"
+const OPERATION_RESTORE = "restore"
+const OPERATION_REVERT = "revert"
+const OPERATION_INCLUDE_DELETED = "include_deleted"
+const OPERATION_ANY = "*"
+
+// Per role permissions, FIELD_ANY and OPERATION_ANY
+// can be used
+type RolePolicy struct {
+	Operations     []string
+	VisibleFields  []string // fields from JSONSchemaConfig.HiddenFields visible to the role
+	HiddenFields   []string // fields hidden from the role, cannot be projected, filtered or sorted
+	WritableFields []string // ProtectedFields the role can insert or update
+}
+
+// Overlays JSONSchemaConfig with role based permissions,
+// when the identity has many roles the most permissive
+// policy wins
+type AccessPolicy struct {
+	Roles           map[string]*RolePolicy
+	Anonymous       *RolePolicy // requests without identity, unauthorized if nil
+	Default         *RolePolicy // identities without matching role, forbidden if nil
+	ProtectedFields []string    // writable only by roles listing them in WritableFields
+}
+
+func NewAccessPolicy() *AccessPolicy {
+	return &AccessPolicy{
+		Roles: make(map[string]*RolePolicy),
+	}
+}
+
+func (ap *AccessPolicy) AddRole(role string, policy *RolePolicy) *AccessPolicy {
+	ap.Roles[role] = policy
+
+	return ap
+}
+
+// Returns nil if no policy applies to the identity
+func (ap *AccessPolicy) GetRolePolicies(identity *AuthIdentity) []*RolePolicy {
+	if identity == nil {
+		if ap.Anonymous == nil {
+			return nil
+		}
+
+		return []*RolePolicy{ap.Anonymous}
+	}
+
+	policies := make([]*RolePolicy, 0)
+
+	for _, role := range identity.Roles {
+		if policy, ok := ap.Roles[role]; ok && policy != nil {
+			policies = append(policies, policy)
+		}
+	}
+
+	if len(policies) == 0 && ap.Default != nil {
+		policies = append(policies, ap.Default)
+	}
+
+	if len(policies) == 0 {
+		return nil
+	}
+
+	return policies
+}
+
+func (ap *AccessPolicy) IsOperationAllowed(policies []*RolePolicy, operation string) bool {
+	for _, policy := range policies {
+		if funk.ContainsString(policy.Operations, OPERATION_ANY) ||
+			funk.ContainsString(policy.Operations, operation) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Fields visible to any of the roles
+func (ap *AccessPolicy) GetVisibleFields(policies []*RolePolicy) []string {
+	visibleFields := make([]string, 0)
+
+	for _, policy := range policies {
+		visibleFields = append(visibleFields, policy.VisibleFields...)
+	}
+
+	return visibleFields
+}
+
+// Fields hidden from all of the roles
+func (ap *AccessPolicy) GetHiddenFields(policies []*RolePolicy, allFields []string) []string {
+	hiddenFields := make([]string, 0)
+
+	for _, field := range allFields {
+		hidden := true
+
+		for _, policy := range policies {
+			if !funk.ContainsString(policy.HiddenFields, FIELD_ANY) &&
+				!funk.ContainsString(policy.HiddenFields, field) {
+				hidden = false
+				break
+			}
+		}
+
+		if hidden && len(policies) > 0 {
+			hiddenFields = append(hiddenFields, field)
+		}
+	}
+
+	return hiddenFields
+}
+
+func (ap *AccessPolicy) IsFieldWritable(policies []*RolePolicy, field string) bool {
+	if !funk.ContainsString(ap.ProtectedFields, field) {
+		return true
+	}
+
+	for _, policy := range policies {
+		if funk.ContainsString(policy.WritableFields, FIELD_ANY) ||
+			funk.ContainsString(policy.WritableFields, field) {
+			return true
+		}
+	}
+
+	return false
+}
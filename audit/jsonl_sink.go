@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+)
+
+// go_cake.AuditSink writing one JSON entry per line
+type JSONLSink struct {
+	writer      io.Writer
+	writerMutex sync.Mutex
+}
+
+func NewJSONLSink(writer io.Writer) *JSONLSink {
+	return &JSONLSink{writer: writer}
+}
+
+// Appends to the file, created with 0600 permissions
+// if not exists
+func NewJSONLFileSink(path string) (*JSONLSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJSONLSink(file), nil
+}
+
+func (js *JSONLSink) WriteAuditEntry(entry *go_cake.AuditEntry, driver go_cake.DatabaseDriver) error {
+	line, err := json.Marshal(entry)
+
+	if err != nil {
+		return err
+	}
+
+	line = append(line, '\n')
+
+	js.writerMutex.Lock()
+	defer js.writerMutex.Unlock()
+
+	// single write so lines are not interleaved with
+	// other processes appending to the same file
+	_, err = js.writer.Write(line)
+
+	return err
+}
+
+// Closes the writer if it is io.Closer
+func (js *JSONLSink) Close() error {
+	if closer, ok := js.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package go_cake
+
+import "time"
+
+const AUDIT_MASKED_VALUE = "***"
+
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+type AuditDocument struct {
+	ID         any                     `json:"id"`
+	StatusCode int                     `json:"status_code"`
+	Changes    map[string]*FieldChange `json:"changes,omitempty"` // by JSON field, hidden and erased fields are masked
+}
+
+// Who changed what and when, one entry per mutating request
+type AuditEntry struct {
+	Time            time.Time        `json:"time"`
+	RequestUniqueID string           `json:"request_unique_id"`
+	Resource        string           `json:"resource"`
+	Operation       string           `json:"operation"`
+	Subject         string           `json:"subject,omitempty"`
+	Provider        string           `json:"provider,omitempty"`
+	Roles           []string         `json:"roles,omitempty"`
+	Tenant          string           `json:"tenant,omitempty"`
+	RemoteAddr      string           `json:"remote_addr,omitempty"`
+	StatusCode      int              `json:"status_code"`
+	Documents       []*AuditDocument `json:"documents"`
+}
+
+// Called after every insert, update, delete, restore and revert
+// request, errors are only logged since the change is already
+// done, driver is the one resolved for the request (like the
+// tenant's database)
+type AuditSink interface {
+	WriteAuditEntry(entry *AuditEntry, driver DatabaseDriver) error
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"net/http"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+)
+
+// Tries the callbacks in order until one authenticates the
+// request, like JWT for users and API keys for other services,
+// errors other than 401 are returned immediately and the
+// request is anonymous only if no callback returned 401
+func AnyOf(callbacks ...go_cake.AuthErrorCallback) go_cake.AuthErrorCallback {
+	return func(
+		resource *go_cake.Resource,
+		request *go_cake.Request,
+		response *go_cake.ResponseJSON) go_cake.HTTPError {
+		var lastHttpErr go_cake.HTTPError
+
+		for _, callback := range callbacks {
+			httpErr := callback(resource, request, response)
+
+			if httpErr == nil {
+				if request.IsAuthenticated() {
+					return nil
+				}
+
+				// optional callback without credentials
+				continue
+			}
+
+			if httpErr.GetStatusCode() != http.StatusUnauthorized {
+				return httpErr
+			}
+
+			lastHttpErr = httpErr
+		}
+
+		return lastHttpErr
+	}
+}
@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"container/heap"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+)
+
+const API_KEY_PROVIDER = "api_key"
+const HMAC_PROVIDER = "hmac"
+const API_KEY_MODE_PLAIN = "plain"
+const API_KEY_MODE_HMAC = "hmac"
+const API_KEY_HEADER = "X-API-Key"
+const API_KEY_ID_HEADER = "X-API-Key-ID"
+const API_KEY_TIMESTAMP_HEADER = "X-Timestamp"
+const API_KEY_SIGNATURE_HEADER = "X-Signature"
+const API_KEY_DEFAULT_TIME_WINDOW = 5 * time.Minute
+const API_KEY_SCOPE_ANY = "*"
+
+type APIKeyAuthenticatorConfig struct {
+	Store      APIKeyStore
+	Mode       string        // API_KEY_MODE_PLAIN by default
+	TimeWindow time.Duration // allowed timestamp difference in HMAC mode
+	Optional   bool          // requests without the key are passed as anonymous
+}
+
+// Plain mode checks X-API-Key header, HMAC mode checks
+// X-API-Key-ID, X-Timestamp and X-Signature headers, see
+// SignRequest(), Authenticate can be used as
+// go_cake.AuthErrorCallback
+//
+// Replay protection keeps the seen signatures in memory, so it
+// is per process only, a signed request can be replayed once
+// against each instance behind a load balancer
+type APIKeyAuthenticator struct {
+	config            APIKeyAuthenticatorConfig
+	seenSignatures    map[string]time.Time // signature expire time
+	signatureExpiries signatureExpiries
+	signaturesMutex   sync.Mutex
+}
+
+func NewAPIKeyAuthenticator(config APIKeyAuthenticatorConfig) (*APIKeyAuthenticator, error) {
+	if config.Store == nil {
+		return nil, errors.New("no API key store set")
+	}
+
+	if config.Mode == "" {
+		config.Mode = API_KEY_MODE_PLAIN
+	}
+
+	if config.Mode != API_KEY_MODE_PLAIN && config.Mode != API_KEY_MODE_HMAC {
+		return nil, fmt.Errorf("unknown API key mode %v", config.Mode)
+	}
+
+	if config.TimeWindow == 0 {
+		config.TimeWindow = API_KEY_DEFAULT_TIME_WINDOW
+	}
+
+	authenticator := APIKeyAuthenticator{
+		config:         config,
+		seenSignatures: make(map[string]time.Time),
+	}
+
+	return &authenticator, nil
+}
+
+// Hex encoded HMAC-SHA256 of method, request URI (path and
+// query), unix timestamp and hex encoded SHA256 of the body,
+// separated by new lines
+func SignRequest(method, requestURI string, timestamp int64, body []byte, secret string) string {
+	bodyHash := sha256.Sum256(body)
+
+	payload := strings.Join([]string{
+		strings.ToUpper(method),
+		requestURI,
+		strconv.FormatInt(timestamp, 10),
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (aka *APIKeyAuthenticator) Authenticate(
+	resource *go_cake.Resource,
+	request *go_cake.Request,
+	response *go_cake.ResponseJSON) go_cake.HTTPError {
+	var key *APIKey
+	var httpErr go_cake.HTTPError
+
+	if request.Request == nil {
+		return go_cake.NewUnauthorizedHTTPError(nil)
+	}
+
+	provider := API_KEY_PROVIDER
+
+	if aka.config.Mode == API_KEY_MODE_HMAC {
+		provider = HMAC_PROVIDER
+		key, httpErr = aka.authenticateHMAC(request)
+	} else {
+		key, httpErr = aka.authenticatePlain(request)
+	}
+
+	if httpErr != nil {
+		return httpErr
+	}
+
+	if key == nil {
+		// optional and no credentials
+		return nil
+	}
+
+	if !aka.isOperationAllowed(key, request) {
+		return go_cake.NewForbiddenHTTPError(
+			fmt.Errorf("operation %v not allowed on %v", request.Operation(), request.Resource))
+	}
+
+	subject := key.Subject
+
+	if subject == "" {
+		subject = key.ID
+	}
+
+	request.Identity = &go_cake.AuthIdentity{
+		Subject:  subject,
+		Provider: provider,
+		Roles:    key.Roles,
+		Scopes:   key.Scopes,
+		Claims:   map[string]any{"key_id": key.ID},
+	}
+
+	return nil
+}
+
+func (aka *APIKeyAuthenticator) authenticatePlain(request *go_cake.Request) (*APIKey, go_cake.HTTPError) {
+	secret := strings.TrimSpace(request.Request.Header.Get(API_KEY_HEADER))
+
+	if secret == "" {
+		if aka.config.Optional {
+			return nil, nil
+		}
+
+		return nil, go_cake.NewUnauthorizedHTTPError(errors.New("missing API key"))
+	}
+
+	key, err := aka.config.Store.GetAPIKeyBySecret(secret)
+
+	if err != nil {
+		return nil, go_cake.NewInternalServerErrorHTTPError(err)
+	}
+
+	if key == nil || key.Disabled {
+		return nil, go_cake.NewUnauthorizedHTTPError(errors.New("invalid API key"))
+	}
+
+	return key, nil
+}
+
+func (aka *APIKeyAuthenticator) authenticateHMAC(request *go_cake.Request) (*APIKey, go_cake.HTTPError) {
+	header := request.Request.Header
+	keyID := strings.TrimSpace(header.Get(API_KEY_ID_HEADER))
+	timestampStr := strings.TrimSpace(header.Get(API_KEY_TIMESTAMP_HEADER))
+	signature := strings.ToLower(strings.TrimSpace(header.Get(API_KEY_SIGNATURE_HEADER)))
+
+	if keyID == "" && signature == "" && aka.config.Optional {
+		return nil, nil
+	}
+
+	if keyID == "" || timestampStr == "" || signature == "" {
+		return nil, go_cake.NewUnauthorizedHTTPError(errors.New("missing signature headers"))
+	}
+
+	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+
+	if err != nil {
+		return nil, go_cake.NewUnauthorizedHTTPError(errors.New("invalid timestamp"))
+	}
+
+	signedAt := time.Unix(timestamp, 0)
+
+	if time.Since(signedAt).Abs() > aka.config.TimeWindow {
+		return nil, go_cake.NewUnauthorizedHTTPError(errors.New("timestamp outside of the time window"))
+	}
+
+	key, err := aka.config.Store.GetAPIKeyByID(keyID)
+
+	if err != nil {
+		return nil, go_cake.NewInternalServerErrorHTTPError(err)
+	}
+
+	if key == nil || key.Disabled {
+		return nil, go_cake.NewUnauthorizedHTTPError(errors.New("invalid API key"))
+	}
+
+	expected := SignRequest(
+		request.Request.Method,
+		request.Request.URL.RequestURI(),
+		timestamp,
+		request.Body,
+		key.Secret)
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, go_cake.NewUnauthorizedHTTPError(errors.New("invalid signature"))
+	}
+
+	if !aka.rememberSignature(signature, signedAt) {
+		return nil, go_cake.NewUnauthorizedHTTPError(errors.New("replayed request"))
+	}
+
+	return key, nil
+}
+
+// Returns false if the signature was already used, signatures
+// are kept only for the time window since older are rejected
+// by the timestamp check, the expired are removed from the top
+// of the heap so each call costs O(log n)
+func (aka *APIKeyAuthenticator) rememberSignature(signature string, signedAt time.Time) bool {
+	aka.signaturesMutex.Lock()
+	defer aka.signaturesMutex.Unlock()
+
+	now := time.Now()
+
+	for aka.signatureExpiries.Len() > 0 && !aka.signatureExpiries[0].expireTime.After(now) {
+		expired := heap.Pop(&aka.signatureExpiries).(signatureExpiry)
+
+		delete(aka.seenSignatures, expired.signature)
+	}
+
+	if _, seen := aka.seenSignatures[signature]; seen {
+		return false
+	}
+
+	expireTime := signedAt.Add(aka.config.TimeWindow)
+
+	aka.seenSignatures[signature] = expireTime
+	heap.Push(&aka.signatureExpiries, signatureExpiry{signature: signature, expireTime: expireTime})
+
+	return true
+}
+
+func (aka *APIKeyAuthenticator) isOperationAllowed(key *APIKey, request *go_cake.Request) bool {
+	operation := request.Operation()
+
+	if operation == "" {
+		// CORS
+		return true
+	}
+
+	for _, scope := range key.Scopes {
+		if scope == API_KEY_SCOPE_ANY {
+			return true
+		}
+
+		scopeResource, scopeOperation, found := strings.Cut(scope, ":")
+
+		if !found {
+			continue
+		}
+
+		if scopeResource != API_KEY_SCOPE_ANY && scopeResource != request.Resource {
+			continue
+		}
+
+		if scopeOperation == API_KEY_SCOPE_ANY || scopeOperation == operation {
+			return true
+		}
+	}
+
+	return false
+}
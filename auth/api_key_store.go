@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"sync"
+)
+
+// Scopes are "<resource name>:<operation>", "*" can be used
+// for both parts, like "devices:read" or "*:read"
+type APIKey struct {
+	ID       string
+	Secret   string
+	Subject  string
+	Roles    []string
+	Scopes   []string
+	Disabled bool
+}
+
+// Returns nil key and nil error when the key does not exist
+type APIKeyStore interface {
+	GetAPIKeyByID(id string) (*APIKey, error)
+	GetAPIKeyBySecret(secret string) (*APIKey, error)
+}
+
+type MemoryAPIKeyStore struct {
+	keysByID     map[string]*APIKey
+	keysByDigest map[string]*APIKey
+	mutex        sync.RWMutex
+}
+
+func NewMemoryAPIKeyStore(keys ...*APIKey) *MemoryAPIKeyStore {
+	store := MemoryAPIKeyStore{
+		keysByID:     make(map[string]*APIKey),
+		keysByDigest: make(map[string]*APIKey),
+	}
+
+	for _, key := range keys {
+		store.AddAPIKey(key)
+	}
+
+	return &store
+}
+
+func (s *MemoryAPIKeyStore) AddAPIKey(key *APIKey) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.keysByID[key.ID] = key
+	s.keysByDigest[s.digest(key.Secret)] = key
+}
+
+func (s *MemoryAPIKeyStore) RemoveAPIKey(id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key, exists := s.keysByID[id]
+
+	if !exists {
+		return
+	}
+
+	delete(s.keysByID, id)
+	delete(s.keysByDigest, s.digest(key.Secret))
+}
+
+func (s *MemoryAPIKeyStore) GetAPIKeyByID(id string) (*APIKey, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.keysByID[id], nil
+}
+
+// Secrets are looked up by the digest so the lookup time
+// does not depend on the secret prefix
+func (s *MemoryAPIKeyStore) GetAPIKeyBySecret(secret string) (*APIKey, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	key, exists := s.keysByDigest[s.digest(secret)]
+
+	if !exists {
+		return nil, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Secret), []byte(secret)) != 1 {
+		return nil, nil
+	}
+
+	return key, nil
+}
+
+func (s *MemoryAPIKeyStore) digest(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+)
+
+const JWKS_MAX_SIZE = 1024 * 1024
+const JWKS_FETCH_TIMEOUT = 10 * time.Second
+
+// RFC 7517 JSON Web Key, only fields needed for the signature
+// verification
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	K         string `json:"k"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// Public key (or secret for "oct" keys) with its JWK metadata
+type verificationKey struct {
+	keyID     string
+	algorithm string
+	key       any
+}
+
+func LoadJWKSFile(path string) (*JSONWebKeySet, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseJWKS(data)
+}
+
+func LoadJWKSURL(url string) (*JSONWebKeySet, error) {
+	client := http.Client{Timeout: JWKS_FETCH_TIMEOUT}
+
+	response, err := client.Get(url)
+
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch JWKS from %v, status code %v", url, response.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, JWKS_MAX_SIZE))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseJWKS(data)
+}
+
+func ParseJWKS(data []byte) (*JSONWebKeySet, error) {
+	var jwks JSONWebKeySet
+
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+
+	return &jwks, nil
+}
+
+// Keys with unsupported types or not meant for signatures
+// are skipped
+func (jwks *JSONWebKeySet) verificationKeys() ([]verificationKey, error) {
+	keys := make([]verificationKey, 0)
+
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if key == nil {
+			continue
+		}
+
+		keys = append(keys, verificationKey{
+			keyID:     jwk.KeyID,
+			algorithm: jwk.Algorithm,
+			key:       key,
+		})
+	}
+
+	return keys, nil
+}
+
+func (jwk *JSONWebKey) publicKey() (any, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeBase64URLBigInt(jwk.N)
+
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBase64URLBigInt(jwk.E)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %v in key %v", jwk.Curve, jwk.KeyID)
+		}
+
+		x, err := decodeBase64URLBigInt(jwk.X)
+
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBase64URLBigInt(jwk.Y)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		if jwk.K == "" {
+			return nil, errors.New("empty symmetric key " + jwk.KeyID)
+		}
+
+		return base64.RawURLEncoding.DecodeString(jwk.K)
+	}
+
+	return nil, nil
+}
+
+func decodeBase64URLBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,319 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	go_cake "github.com/skazanyNaGlany/go-cake"
+)
+
+const JWT_PROVIDER = "jwt"
+const JWT_DEFAULT_REALM = "go-cake"
+const JWT_DEFAULT_ROLES_CLAIM = "roles"
+const JWT_DEFAULT_SCOPES_CLAIM = "scope"
+const JWKS_RELOAD_INTERVAL = time.Minute
+
+var jwtDefaultAlgorithms = []string{"HS256", "RS256", "ES256"}
+
+type JWTAuthenticatorConfig struct {
+	Algorithms     []string // allowed signing algorithms, HS256, RS256 and ES256 by default
+	HMACSecret     []byte
+	JWKSFile       string
+	JWKSURL        string
+	Issuer         string
+	Audience       string
+	Leeway         time.Duration // allowed clock skew
+	RequiredScopes []string
+	RolesClaim     string
+	ScopesClaim    string
+	Realm          string
+	Optional       bool // requests without the token are passed as anonymous
+}
+
+// Verifies "Authorization: Bearer <token>" header, Authenticate
+// can be used as go_cake.AuthErrorCallback
+type JWTAuthenticator struct {
+	config         JWTAuthenticatorConfig
+	keys           []verificationKey
+	keysLoadedTime time.Time
+	keysMutex      sync.RWMutex
+}
+
+func NewJWTAuthenticator(config JWTAuthenticatorConfig) (*JWTAuthenticator, error) {
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = jwtDefaultAlgorithms
+	}
+
+	if config.RolesClaim == "" {
+		config.RolesClaim = JWT_DEFAULT_ROLES_CLAIM
+	}
+
+	if config.ScopesClaim == "" {
+		config.ScopesClaim = JWT_DEFAULT_SCOPES_CLAIM
+	}
+
+	if config.Realm == "" {
+		config.Realm = JWT_DEFAULT_REALM
+	}
+
+	if len(config.HMACSecret) == 0 && config.JWKSFile == "" && config.JWKSURL == "" {
+		return nil, errors.New("no HMAC secret or JWKS set")
+	}
+
+	authenticator := JWTAuthenticator{config: config}
+
+	if err := authenticator.ReloadKeys(); err != nil {
+		return nil, err
+	}
+
+	return &authenticator, nil
+}
+
+// Loads the keys from JWKS file or URL, called automatically
+// when the token is signed by an unknown key ID
+func (ja *JWTAuthenticator) ReloadKeys() error {
+	var jwks *JSONWebKeySet
+	var err error
+
+	if ja.config.JWKSFile != "" {
+		jwks, err = LoadJWKSFile(ja.config.JWKSFile)
+	} else if ja.config.JWKSURL != "" {
+		jwks, err = LoadJWKSURL(ja.config.JWKSURL)
+	} else {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	keys, err := jwks.verificationKeys()
+
+	if err != nil {
+		return err
+	}
+
+	ja.keysMutex.Lock()
+	defer ja.keysMutex.Unlock()
+
+	ja.keys = keys
+	ja.keysLoadedTime = time.Now()
+
+	return nil
+}
+
+func (ja *JWTAuthenticator) Authenticate(
+	resource *go_cake.Resource,
+	request *go_cake.Request,
+	response *go_cake.ResponseJSON) go_cake.HTTPError {
+	tokenString, found := ja.getBearerToken(request)
+
+	if !found {
+		if ja.config.Optional {
+			return nil
+		}
+
+		ja.writeChallenge(request, "", "")
+
+		return go_cake.NewUnauthorizedHTTPError(errors.New("missing bearer token"))
+	}
+
+	token, err := jwt.Parse(tokenString, ja.getKey, ja.getParserOptions()...)
+
+	if err != nil {
+		ja.writeChallenge(request, "invalid_token", err.Error())
+
+		return go_cake.NewUnauthorizedHTTPError(err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+
+	if !ok {
+		ja.writeChallenge(request, "invalid_token", "")
+
+		return go_cake.NewUnauthorizedHTTPError(nil)
+	}
+
+	identity := ja.claimsToIdentity(claims)
+
+	for _, scope := range ja.config.RequiredScopes {
+		if !identity.HasScope(scope) {
+			ja.writeChallenge(request, "insufficient_scope", "missing scope "+scope)
+
+			return go_cake.NewForbiddenHTTPError(fmt.Errorf("missing scope %v", scope))
+		}
+	}
+
+	request.Identity = identity
+
+	return nil
+}
+
+func (ja *JWTAuthenticator) getBearerToken(request *go_cake.Request) (string, bool) {
+	if request.Request == nil {
+		return "", false
+	}
+
+	header := strings.TrimSpace(request.Request.Header.Get("Authorization"))
+
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return "", false
+	}
+
+	token := strings.TrimSpace(header[7:])
+
+	return token, token != ""
+}
+
+func (ja *JWTAuthenticator) getParserOptions() []jwt.ParserOption {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(ja.config.Algorithms),
+		jwt.WithLeeway(ja.config.Leeway),
+		jwt.WithExpirationRequired(),
+	}
+
+	if ja.config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(ja.config.Issuer))
+	}
+
+	if ja.config.Audience != "" {
+		options = append(options, jwt.WithAudience(ja.config.Audience))
+	}
+
+	return options
+}
+
+func (ja *JWTAuthenticator) getKey(token *jwt.Token) (any, error) {
+	algorithm := token.Method.Alg()
+	keyID, _ := token.Header["kid"].(string)
+
+	if strings.HasPrefix(algorithm, "HS") && len(ja.config.HMACSecret) > 0 {
+		return ja.config.HMACSecret, nil
+	}
+
+	if key := ja.findKey(algorithm, keyID); key != nil {
+		return key, nil
+	}
+
+	if keyID != "" && ja.config.JWKSURL != "" && ja.canReloadKeys() {
+		// keys can be rotated
+		if err := ja.ReloadKeys(); err != nil {
+			return nil, err
+		}
+
+		if key := ja.findKey(algorithm, keyID); key != nil {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no key found for algorithm %v and key ID %v", algorithm, keyID)
+}
+
+func (ja *JWTAuthenticator) canReloadKeys() bool {
+	ja.keysMutex.RLock()
+	defer ja.keysMutex.RUnlock()
+
+	return time.Since(ja.keysLoadedTime) > JWKS_RELOAD_INTERVAL
+}
+
+// The key type must match the algorithm so a public key
+// cannot be used as HMAC secret
+func (ja *JWTAuthenticator) findKey(algorithm string, keyID string) any {
+	ja.keysMutex.RLock()
+	defer ja.keysMutex.RUnlock()
+
+	for _, key := range ja.keys {
+		if keyID != "" && key.keyID != keyID {
+			continue
+		}
+
+		if key.algorithm != "" && key.algorithm != algorithm {
+			continue
+		}
+
+		switch key.key.(type) {
+		case *rsa.PublicKey:
+			if strings.HasPrefix(algorithm, "RS") || strings.HasPrefix(algorithm, "PS") {
+				return key.key
+			}
+		case *ecdsa.PublicKey:
+			if strings.HasPrefix(algorithm, "ES") {
+				return key.key
+			}
+		case []byte:
+			if strings.HasPrefix(algorithm, "HS") {
+				return key.key
+			}
+		}
+	}
+
+	return nil
+}
+
+func (ja *JWTAuthenticator) claimsToIdentity(claims jwt.MapClaims) *go_cake.AuthIdentity {
+	subject, _ := claims.GetSubject()
+
+	scopes := claimToStrings(claims[ja.config.ScopesClaim])
+
+	if len(scopes) == 0 && ja.config.ScopesClaim == JWT_DEFAULT_SCOPES_CLAIM {
+		// used by some providers instead of "scope"
+		scopes = claimToStrings(claims["scp"])
+	}
+
+	return &go_cake.AuthIdentity{
+		Subject:  subject,
+		Provider: JWT_PROVIDER,
+		Roles:    claimToStrings(claims[ja.config.RolesClaim]),
+		Scopes:   scopes,
+		Claims:   claims,
+	}
+}
+
+// RFC 6750 challenge
+func (ja *JWTAuthenticator) writeChallenge(request *go_cake.Request, errorCode string, description string) {
+	if request.ResponseWriter == nil {
+		return
+	}
+
+	challenge := fmt.Sprintf(`Bearer realm="%v"`, ja.config.Realm)
+
+	if errorCode != "" {
+		challenge += fmt.Sprintf(`, error="%v"`, errorCode)
+	}
+
+	if description != "" {
+		challenge += fmt.Sprintf(`, error_description="%v"`, strings.ReplaceAll(description, `"`, `'`))
+	}
+
+	if errorCode == "insufficient_scope" {
+		challenge += fmt.Sprintf(`, scope="%v"`, strings.Join(ja.config.RequiredScopes, " "))
+	}
+
+	request.ResponseWriter.Header().Set("WWW-Authenticate", challenge)
+}
+
+// Claim can be space separated string or array of strings
+func claimToStrings(claim any) []string {
+	values := make([]string, 0)
+
+	switch typedClaim := claim.(type) {
+	case string:
+		values = append(values, strings.Fields(typedClaim)...)
+	case []any:
+		for _, item := range typedClaim {
+			if itemStr, ok := item.(string); ok {
+				values = append(values, itemStr)
+			}
+		}
+	case []string:
+		values = append(values, typedClaim...)
+	}
+
+	return values
+}
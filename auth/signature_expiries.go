@@ -0,0 +1,36 @@
+package auth
+
+import "time"
+
+type signatureExpiry struct {
+	signature  string
+	expireTime time.Time
+}
+
+// container/heap of the seen signatures, the signature
+// which expires first is on the top
+type signatureExpiries []signatureExpiry
+
+func (se signatureExpiries) Len() int {
+	return len(se)
+}
+
+func (se signatureExpiries) Less(i, j int) bool {
+	return se[i].expireTime.Before(se[j].expireTime)
+}
+
+func (se signatureExpiries) Swap(i, j int) {
+	se[i], se[j] = se[j], se[i]
+}
+
+func (se *signatureExpiries) Push(x any) {
+	*se = append(*se, x.(signatureExpiry))
+}
+
+func (se *signatureExpiries) Pop() any {
+	old := *se
+	last := old[len(old)-1]
+	*se = old[:len(old)-1]
+
+	return last
+}
@@ -5,3 +5,12 @@ type AuthCallback func(
 	resource *Resource,
 	request *Request,
 	response *ResponseJSON) bool
+
+// app callback, should return UnauthorizedHTTPError when the
+// credentials are missing or invalid and ForbiddenHTTPError
+// when the client is not allowed to access the resource, used
+// instead of AuthCallback if set
+type AuthErrorCallback func(
+	resource *Resource,
+	request *Request,
+	response *ResponseJSON) HTTPError
@@ -0,0 +1,43 @@
+package go_cake
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAuthCallbacks(t *testing.T) {
+	allowed := func(resource *Resource, request *Request, response *ResponseJSON) bool {
+		return request.Request.Header.Get("Authorization") == "Bearer valid"
+	}
+	forbidden := func(resource *Resource, request *Request, response *ResponseJSON) HTTPError {
+		return NewForbiddenHTTPError(nil)
+	}
+
+	tests := []struct {
+		name          string
+		authCallback  AuthCallback
+		errorCallback AuthErrorCallback
+		authorization string
+		want          int
+	}{
+		{name: "no callbacks", want: http.StatusOK},
+		{name: "allowed", authCallback: allowed, authorization: "Bearer valid", want: http.StatusOK},
+		{name: "not allowed", authCallback: allowed, authorization: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "error callback first", authCallback: allowed, errorCallback: forbidden, authorization: "Bearer valid", want: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler, resource := newTestHandler(t, newTestDriver("a"))
+
+			resource.ResourceCallback.AuthCallback = test.authCallback
+			resource.ResourceCallback.AuthErrorCallback = test.errorCallback
+
+			recorder := serveTestRequest(handler, http.MethodGet, "/v1/api/items", "", "Authorization", test.authorization)
+
+			if recorder.Code != test.want {
+				t.Errorf("status = %v, want %v", recorder.Code, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package go_cake
+
+// Authenticated client, set on the Request by the auth callback
+type AuthIdentity struct {
+	Subject  string
+	Provider string
+	Roles    []string
+	Scopes   []string
+	Claims   map[string]any
+}
+
+func (ai *AuthIdentity) HasRole(role string) bool {
+	for _, iRole := range ai.Roles {
+		if iRole == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ai *AuthIdentity) HasScope(scope string) bool {
+	for _, iScope := range ai.Scopes {
+		if iScope == scope {
+			return true
+		}
+	}
+
+	return false
+}
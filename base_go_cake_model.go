@@ -3,8 +3,9 @@ package go_cake
 import "encoding/json"
 
 type BaseGoCakeModel struct {
-	httpError HTTPError
-	subModel  GoCakeModel
+	httpError  HTTPError
+	subModel   GoCakeModel
+	metaFields map[string]any
 }
 
 func (bgkm *BaseGoCakeModel) SetSubModel(model GoCakeModel) {
@@ -19,6 +20,18 @@ func (bgkm *BaseGoCakeModel) SetHTTPError(httpError HTTPError) {
 	bgkm.httpError = httpError
 }
 
+func (bgkm *BaseGoCakeModel) SetMetaField(name string, value any) {
+	if bgkm.metaFields == nil {
+		bgkm.metaFields = make(map[string]any)
+	}
+
+	bgkm.metaFields[name] = value
+}
+
+func (bgkm *BaseGoCakeModel) GetMetaFields() map[string]any {
+	return bgkm.metaFields
+}
+
 func (bgkm *BaseGoCakeModel) CreateInstance() GoCakeModel {
 	panic("not implemented")
 }
@@ -51,12 +64,18 @@ func (bgkm *BaseGoCakeModel) ToMap() (map[string]any, error) {
 		return nil, err
 	}
 
-	if httpErr := bgkm.GetHTTPError(); httpErr != nil {
-		_meta := make(map[string]any)
+	_meta := make(map[string]any)
+
+	for name, value := range bgkm.metaFields {
+		_meta[name] = value
+	}
 
+	if httpErr := bgkm.GetHTTPError(); httpErr != nil {
 		_meta["status_code"] = httpErr.GetStatusCode()
 		_meta["status_message"] = httpErr.GetStatusMessage()
+	}
 
+	if len(_meta) > 0 {
 		objectMap["_meta"] = _meta
 	}
 
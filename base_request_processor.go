@@ -1,23 +1,42 @@
 package go_cake
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"maps"
 	"net/http"
+	"net/url"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/skazanyNaGlany/go-cake/utils"
 	"github.com/thoas/go-funk"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type BaseRequestProcessor struct {
 	request             *Request
 	resource            *Resource
 	subRequestProcessor RequestProcessor
+	rolePolicies        []*RolePolicy
+	roleVisibleFields   []string
+	roleHiddenFields    []string
+	serverFilter        map[string]any
+	driver              DatabaseDriver
+	dbPath              string
+	releaseDriver       func() // of the tenant, by TenantConfig.GetDriver()
+	previousDocuments   map[GoCakeModel]map[string]any
+	idempotencyRecord   *IdempotencyRecord // reserved by this request
+	cacheKey            string             // response to be cached by storeCachedResponse()
+	cacheTime           time.Time
+	storedResponse      bool // loaded from the idempotency store or the cache
+	processed           bool // passed all the checks and reached the "process" stage
 }
 
 func (brp *BaseRequestProcessor) ProcessRequest(response *ResponseJSON) {
@@ -26,6 +45,30 @@ func (brp *BaseRequestProcessor) ProcessRequest(response *ResponseJSON) {
 
 	timeStart := time.Now()
 
+	parentTraceContext := brp.request.TraceContext()
+	traceContext, span := startSpan(
+		parentTraceContext,
+		"BaseRequestProcessor.ProcessRequest",
+		attribute.String(SPAN_ATTRIBUTE_PREFIX+"resource", brp.resource.ResourceName),
+		attribute.String(SPAN_ATTRIBUTE_PREFIX+"operation", brp.request.Operation()))
+	brp.request.traceContext = traceContext
+
+	defer func() {
+		if brp.releaseDriver != nil {
+			brp.releaseDriver()
+		}
+
+		brp.request.traceContext = parentTraceContext
+
+		span.SetAttributes(attribute.Int(SPAN_ATTRIBUTE_PREFIX+"status_code", response.Meta.StatusCode))
+		span.End()
+	}()
+
+	if brp.resource.TenantConfig == nil {
+		brp.driver = brp.resource.DatabaseDriver
+		brp.dbPath = brp.resource.DbPath
+	}
+
 	response.Meta.RequestUniqueID = brp.request.UniqueID
 	response.Meta.Version = brp.request.Version
 	response.Meta.URL = brp.request.URL
@@ -33,191 +76,1574 @@ func (brp *BaseRequestProcessor) ProcessRequest(response *ResponseJSON) {
 	response.Meta.StatusMessage = httpErr.GetStatusMessage()
 	response.Meta.StatusCode = httpErr.GetStatusCode()
 
-	defer func() {
-		defer func() {
-			brp.catchInternalError(response, recover())
-		}()
+	defer func() {
+		defer func() {
+			brp.catchInternalError(response, recover())
+			brp.completeIdempotency(response)
+			brp.storeCachedResponse(response)
+		}()
+
+		if brp.catchInternalError(response, recover()) {
+			return
+		}
+
+		if brp.storedResponse {
+			// stored response is already processed
+			return
+		}
+
+		brp.postRequestResponseActions(response)
+
+		response.Meta.TotalTimeMs = time.Since(timeStart).Seconds() * 1000
+
+		brp.traceStage("totals", func() HTTPError {
+			brp.processTotals(response)
+
+			return nil
+		})
+		brp.processLinks(response)
+		brp.writeAuditEntry(documents, response)
+
+		if httpErr = brp.traceStage("post_request_callbacks", func() HTTPError {
+			return brp.callPostRequestHandlers(response)
+		}); httpErr != nil {
+			response.Meta.StatusMessage = httpErr.GetStatusMessage()
+			response.Meta.StatusCode = httpErr.GetStatusCode()
+		}
+	}()
+
+	if httpErr = brp.traceStage("cors", brp.processCORS); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("item_check", brp.checkItemRequest); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("version_check", brp.checkSupportedVersion); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("projection_check", brp.preRequestProjectableChecks); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("filter_check", brp.preRequestFilterableChecks); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("sort_check", brp.preRequestSortableChecks); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("geo_check", brp.preRequestGeoChecks); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("search_check", brp.preRequestSearchableChecks); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("pre_request_callbacks", func() HTTPError {
+		return brp.callPreRequestHandlers(response)
+	}); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("auth_rate_limit", brp.checkAuthRateLimit); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("auth", func() HTTPError {
+		return brp.callAuthHandlers(response)
+	}); httpErr != nil {
+		brp.chargeAuthFailure(httpErr)
+
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("rate_limit", brp.checkRateLimit); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("access_policy", brp.checkAccessPolicy); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("soft_delete_access", brp.checkSoftDeleteAccess); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("tenant", brp.resolveTenant); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("idempotency", func() HTTPError {
+		return brp.checkIdempotency(response)
+	}); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("cache", func() HTTPError {
+		return brp.loadCachedResponse(response)
+	}); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if brp.storedResponse {
+		return
+	}
+
+	if httpErr = brp.traceStage("server_filter", func() HTTPError {
+		_, httpErr := brp.createServerFilter()
+
+		return httpErr
+	}); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return
+	}
+
+	if httpErr = brp.traceStage("process", func() HTTPError {
+		var httpErr HTTPError
+
+		brp.processed = true
+		documents, httpErr = brp.subRequestProcessor.ProcessRequest(response)
+
+		return httpErr
+	}); httpErr != nil {
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+	}
+
+	brp.documentsToJsonMapObjects(documents, response)
+}
+
+// Runs the stage in its own span, the span is the parent of
+// the driver calls made by the stage
+func (brp *BaseRequestProcessor) traceStage(name string, stage func() HTTPError) (httpErr HTTPError) {
+	parentTraceContext := brp.request.TraceContext()
+	traceContext, span := startSpan(parentTraceContext, "stage."+name)
+
+	brp.request.traceContext = traceContext
+
+	defer func() {
+		if brp.releaseDriver != nil {
+			brp.releaseDriver()
+		}
+
+		brp.request.traceContext = parentTraceContext
+
+		endSpan(span, httpErr)
+	}()
+
+	return stage()
+}
+
+func (brp *BaseRequestProcessor) catchInternalError(response *ResponseJSON, r any) bool {
+	if r != nil {
+		message := fmt.Sprint(r)
+
+		httpErr := NewInternalServerErrorHTTPError(errors.New(message))
+
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		return true
+	}
+
+	return false
+}
+
+func (brp *BaseRequestProcessor) processTotals(response *ResponseJSON) {
+	if !brp.processed || response.Meta.StatusCode != http.StatusOK {
+		// do not leak the number of documents to requests
+		// which failed the checks (auth, access policy, etc.)
+		return
+	}
+
+	if brp.request.HasAction() {
+		// set by the action processors
+		return
+	}
+
+	ctx, cancel := brp.resource.ResourceCallback.CreateContext(
+		brp.resource,
+		brp.request,
+		response,
+		ctxDbDriverTotal)
+	defer cancel()
+
+	if brp.driver == nil {
+		// tenant not resolved
+		return
+	}
+
+	serverFilter, httpErr := brp.createServerFilter()
+
+	if httpErr != nil {
+		return
+	}
+
+	response.Meta.Total, _ = brp.driver.Total(
+		brp.resource.DbModel,
+		brp.dbPath,
+		brp.request.Where,
+		brp.createSearchQuery(),
+		brp.request.Geo,
+		serverFilter,
+		ctx,
+		brp.request.UserData)
+}
+
+// Sets the driver and database path for the request
+func (brp *BaseRequestProcessor) resolveTenant() HTTPError {
+	tenantConfig := brp.resource.TenantConfig
+
+	if tenantConfig == nil {
+		return nil
+	}
+
+	tenant := ""
+
+	if tenantConfig.Resolver != nil {
+		var httpErr HTTPError
+
+		if tenant, httpErr = tenantConfig.Resolver(brp.resource, brp.request); httpErr != nil {
+			return httpErr
+		}
+	}
+
+	if tenant == "" {
+		if !tenantConfig.Optional {
+			return NewInvalidTenantHTTPError(nil)
+		}
+
+		brp.driver = brp.resource.DatabaseDriver
+		brp.dbPath = brp.resource.DbPath
+
+		return nil
+	}
+
+	if !tenantConfig.IsValidTenant(tenant) {
+		return NewInvalidTenantHTTPError(nil)
+	}
+
+	driver, dbPath, releaseDriver, err := tenantConfig.GetDriver(brp.resource, tenant)
+
+	if err != nil {
+		return NewLowLevelDriverHTTPError(err)
+	}
+
+	brp.request.Tenant = tenant
+	brp.driver = driver
+	brp.dbPath = dbPath
+	brp.releaseDriver = releaseDriver
+
+	return nil
+}
+
+// Equality conditions set by the server, AND-ed with the
+// conditions from the request, created once per request
+func (brp *BaseRequestProcessor) createServerFilter() (map[string]any, HTTPError) {
+	if brp.serverFilter != nil {
+		return brp.serverFilter, nil
+	}
+
+	serverFilter := make(map[string]any)
+
+	if brp.resource.ResourceCallback != nil &&
+		brp.resource.ResourceCallback.ServerFilter != nil {
+		callbackFilter, httpErr := brp.resource.ResourceCallback.ServerFilter(
+			brp.resource,
+			brp.request)
+
+		if httpErr != nil {
+			return nil, httpErr
+		}
+
+		for iJsonField, value := range callbackFilter {
+			if !funk.ContainsString(brp.resource.DbModelJSONFields, iJsonField) {
+				return nil, NewFieldNotExistsHTTPError(iJsonField, nil)
+			}
+
+			serverFilter[iJsonField] = value
+		}
+	}
+
+	if brp.request.HasItemID() {
+		serverFilter[brp.resource.JSONSchemaConfig.IDField] = brp.request.ItemID
+	}
+
+	if brp.resource.IsSoftDelete() && !brp.isIncludeDeleted() {
+		if !funk.ContainsString(brp.resource.DbModelJSONFields, brp.resource.SoftDeleteField) {
+			return nil, NewInternalServerErrorHTTPError(
+				fmt.Errorf("soft delete field %v not exists", brp.resource.SoftDeleteField))
+		}
+
+		serverFilter[brp.resource.SoftDeleteField] = brp.resource.GetSoftDeleteFilter()
+	}
+
+	brp.serverFilter = serverFilter
+
+	return serverFilter, nil
+}
+
+// Server filter fields are set by the server, the payload can
+// contain them only with the same values
+func (brp *BaseRequestProcessor) preRequestServerFilterActions(jsonObjectMap map[string]any) HTTPError {
+	for iJsonField, value := range brp.serverFilter {
+		if payloadValue, keyIn := jsonObjectMap[iJsonField]; keyIn {
+			if !brp.serverFilterValueMatches(payloadValue, value) {
+				return NewFieldAccessDeniedHTTPError(iJsonField, nil)
+			}
+
+			continue
+		}
+
+		if _, isIn := value.(InFilter); isIn {
+			continue
+		}
+
+		if notEqual, isNotEqual := value.(NotEqualFilter); isNotEqual {
+			if notEqual.Default != nil {
+				jsonObjectMap[iJsonField] = notEqual.Default
+			}
+
+			continue
+		}
+
+		jsonObjectMap[iJsonField] = value
+	}
+
+	return nil
+}
+
+// Checks the value of the document against the server filter
+// value, nil matches NotEqualFilter like NULL in the database
+func (brp *BaseRequestProcessor) serverFilterValueMatches(value any, filterValue any) bool {
+	if notEqual, isNotEqual := filterValue.(NotEqualFilter); isNotEqual {
+		return value == nil || !brp.jsonValuesEqual(value, notEqual.Value)
+	}
+
+	if in, isIn := filterValue.(InFilter); isIn {
+		for _, inValue := range in.Values {
+			if brp.jsonValuesEqual(value, inValue) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return brp.jsonValuesEqual(value, filterValue)
+}
+
+func (brp *BaseRequestProcessor) getServerFilterFields() []string {
+	return funk.Keys(brp.serverFilter).([]string)
+}
+
+func (brp *BaseRequestProcessor) jsonValuesEqual(value1 any, value2 any) bool {
+	json1, err1 := json.Marshal(value1)
+	json2, err2 := json.Marshal(value2)
+
+	if err1 != nil || err2 != nil {
+		return false
+	}
+
+	return string(json1) == string(json2)
+}
+
+// Soft deleted documents are visible only for reads with
+// include_deleted and for the restore
+func (brp *BaseRequestProcessor) isIncludeDeleted() bool {
+	if brp.request.Action == ACTION_RESTORE {
+		return true
+	}
+
+	return brp.request.IsGet && brp.request.IncludeDeleted
+}
+
+// Including and restoring soft deleted documents requires
+// the role to be granted OPERATION_INCLUDE_DELETED or
+// OPERATION_RESTORE
+func (brp *BaseRequestProcessor) checkSoftDeleteAccess() HTTPError {
+	if !brp.resource.IsSoftDelete() || !brp.isIncludeDeleted() {
+		return nil
+	}
+
+	operation := OPERATION_INCLUDE_DELETED
+
+	if brp.request.Action == ACTION_RESTORE {
+		operation = OPERATION_RESTORE
+	}
+
+	accessPolicy := brp.resource.AccessPolicy
+
+	if accessPolicy == nil {
+		return NewForbiddenHTTPError(fmt.Errorf("operation %v not allowed", operation))
+	}
+
+	policies := accessPolicy.GetRolePolicies(brp.request.Identity)
+
+	if policies == nil {
+		if !brp.request.IsAuthenticated() {
+			return NewUnauthorizedHTTPError(nil)
+		}
+
+		return NewForbiddenHTTPError(nil)
+	}
+
+	if !accessPolicy.IsOperationAllowed(policies, operation) {
+		return NewForbiddenHTTPError(fmt.Errorf("operation %v not allowed", operation))
+	}
+
+	return nil
+}
+
+// Item URLs can be only fetched, actions are checked
+// by their processors
+func (brp *BaseRequestProcessor) checkItemRequest() HTTPError {
+	if !brp.request.HasItemID() || brp.request.HasAction() {
+		return nil
+	}
+
+	if brp.request.IsGet || brp.request.IsCORS {
+		return nil
+	}
+
+	return NewMethodNotAllowedHTTPError(nil)
+}
+
+func (brp *BaseRequestProcessor) itemURL(id any) string {
+	return strings.TrimSuffix(brp.request.CollectionPath, "/") + "/" + url.PathEscape(fmt.Sprint(id))
+}
+
+func (brp *BaseRequestProcessor) processLinks(response *ResponseJSON) {
+	if !brp.resource.HATEOAS || !brp.request.IsGet || brp.request.Request == nil {
+		return
+	}
+
+	if response.Meta.StatusCode != http.StatusOK {
+		return
+	}
+
+	links := make(map[string]string)
+
+	if paginationLinks := newResponsePaginationLinks(brp.request, response); paginationLinks != nil {
+		links = paginationLinks.ToMap()
+	}
+
+	links["self"] = brp.request.Request.URL.String()
+
+	response.Links = links
+
+	idField := brp.resource.JSONSchemaConfig.IDField
+
+	for _, jsonObject := range response.Items {
+		id, hasID := jsonObject[idField]
+
+		if !hasID || id == nil {
+			// ID field can be hidden or not projected
+			continue
+		}
+
+		selfURL := brp.itemURL(id)
+
+		if brp.request.Action == ACTION_VERSIONS {
+			if meta, ok := jsonObject["_meta"].(map[string]any); ok {
+				selfURL += fmt.Sprintf("?version=%v", meta[VERSION_META_FIELD])
+			}
+		}
+
+		jsonObject[LINKS_FIELD] = map[string]string{"self": selfURL}
+	}
+}
+
+// JSON value of the ID field
+func (brp *BaseRequestProcessor) getDocumentID(document GoCakeModel) (any, HTTPError) {
+	jsonObject, err := document.ToMap()
+
+	if err != nil {
+		return nil, NewServerObjectMalformedHTTPError(document, err)
+	}
+
+	id := jsonObject[brp.resource.JSONSchemaConfig.IDField]
+
+	if id == nil {
+		return nil, NewFieldRequiredHTTPError(brp.resource.JSONSchemaConfig.IDField, nil)
+	}
+
+	return id, nil
+}
+
+// Current JSON fields of the document matching the server
+// filter, nil if not found
+func (brp *BaseRequestProcessor) findCurrentDocument(id any, ctx context.Context) (map[string]any, HTTPError) {
+	return brp.findDocument(id, brp.serverFilter, ctx)
+}
+
+func (brp *BaseRequestProcessor) findDocument(
+	id any,
+	serverFilter map[string]any,
+	ctx context.Context) (map[string]any, HTTPError) {
+	serverFilter = maps.Clone(serverFilter)
+
+	if serverFilter == nil {
+		serverFilter = make(map[string]any)
+	}
+
+	serverFilter[brp.resource.JSONSchemaConfig.IDField] = id
+
+	documents, httpErr := brp.driver.Find(
+		brp.resource.DbModel,
+		brp.dbPath,
+		"",
+		"",
+		nil,
+		nil,
+		serverFilter,
+		0,
+		1,
+		ctx,
+		brp.request.UserData)
+
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	if len(documents) == 0 {
+		return nil, nil
+	}
+
+	jsonObject, err := documents[0].ToMap()
+
+	if err != nil {
+		return nil, NewServerObjectMalformedHTTPError(documents[0], err)
+	}
+
+	delete(jsonObject, "_meta")
+
+	return jsonObject, nil
+}
+
+// Fetches current versions of the documents before they are
+// changed with one query, for the HistoryStore, the AuditSink
+// and the events
+func (brp *BaseRequestProcessor) preChangeActions(documents []GoCakeModel, ctx context.Context) {
+	if brp.resource.HistoryStore == nil &&
+		brp.resource.AuditSink == nil &&
+		len(brp.resource.EventListeners) == 0 {
+		return
+	}
+
+	brp.previousDocuments = make(map[GoCakeModel]map[string]any)
+
+	changed := make([]GoCakeModel, 0, len(documents))
+	ids := make([]any, 0, len(documents))
+
+	for _, document := range documents {
+		if document.GetHTTPError() != nil {
+			continue
+		}
+
+		id, httpErr := brp.getDocumentID(document)
+
+		if httpErr != nil {
+			document.SetHTTPError(httpErr)
+			continue
+		}
+
+		changed = append(changed, document)
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	currentDocuments, httpErr := brp.findCurrentDocuments(ids, ctx)
+
+	if httpErr != nil {
+		for _, document := range changed {
+			document.SetHTTPError(httpErr)
+		}
+
+		return
+	}
+
+	for i, document := range changed {
+		// not found ones will fail with ObjectNotFound
+		if current, found := currentDocuments[fmt.Sprint(ids[i])]; found {
+			brp.previousDocuments[document] = current
+		}
+	}
+}
+
+// Current JSON fields of the documents matching the server
+// filter by ID
+func (brp *BaseRequestProcessor) findCurrentDocuments(
+	ids []any,
+	ctx context.Context) (map[string]map[string]any, HTTPError) {
+	idField := brp.resource.JSONSchemaConfig.IDField
+	serverFilter := maps.Clone(brp.serverFilter)
+
+	if serverFilter == nil {
+		serverFilter = make(map[string]any)
+	}
+
+	serverFilter[idField] = InFilter{Values: ids}
+
+	documents, httpErr := brp.driver.Find(
+		brp.resource.DbModel,
+		brp.dbPath,
+		"",
+		"",
+		nil,
+		nil,
+		serverFilter,
+		0,
+		int64(len(ids)),
+		ctx,
+		brp.request.UserData)
+
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	currentDocuments := make(map[string]map[string]any, len(documents))
+
+	for _, document := range documents {
+		jsonObject, err := document.ToMap()
+
+		if err != nil {
+			return nil, NewServerObjectMalformedHTTPError(document, err)
+		}
+
+		delete(jsonObject, "_meta")
+
+		currentDocuments[fmt.Sprint(jsonObject[idField])] = jsonObject
+	}
+
+	return currentDocuments, nil
+}
+
+// Saves the previous versions of the successfully changed
+// documents in the HistoryStore, skipped if the driver write
+// failed, the change is already made so errors are only logged
+func (brp *BaseRequestProcessor) saveDocumentVersions(
+	documents []GoCakeModel,
+	operation string,
+	driverHttpErr HTTPError,
+	ctx context.Context) {
+	historyStore := brp.resource.HistoryStore
+	etagField := brp.resource.JSONSchemaConfig.ETagField
+	idField := brp.resource.JSONSchemaConfig.IDField
+
+	if historyStore == nil || driverHttpErr != nil {
+		return
+	}
+
+	for _, document := range documents {
+		current, found := brp.previousDocuments[document]
+
+		if !found || document.GetHTTPError() != nil {
+			continue
+		}
+
+		version := DocumentVersion{
+			DocumentID: fmt.Sprint(current[idField]),
+			Operation:  operation,
+			Time:       time.Now().UTC(),
+			Document:   current,
+		}
+
+		if etagField != "" {
+			version.ETag = current[etagField]
+		}
+
+		if err := historyStore.SaveVersion(brp.resource, brp.driver, brp.dbPath, &version, ctx); err != nil {
+			log.Printf("Unable to save version of %v %v: %v", brp.resource.ResourceName, version.DocumentID, err)
+		}
+	}
+}
+
+// Writes who changed what to the AuditSink, called for every
+// mutating request, also the failed ones
+func (brp *BaseRequestProcessor) writeAuditEntry(documents []GoCakeModel, response *ResponseJSON) {
+	auditSink := brp.resource.AuditSink
+	operation := brp.request.Operation()
+
+	if auditSink == nil || operation == "" || operation == OPERATION_READ {
+		return
+	}
+
+	entry := AuditEntry{
+		Time:            time.Now().UTC(),
+		RequestUniqueID: brp.request.UniqueID,
+		Resource:        brp.resource.ResourceName,
+		Operation:       operation,
+		Tenant:          brp.request.Tenant,
+		StatusCode:      response.Meta.StatusCode,
+		Documents:       make([]*AuditDocument, 0, len(documents)),
+	}
+
+	if identity := brp.request.Identity; identity != nil {
+		entry.Subject = identity.Subject
+		entry.Provider = identity.Provider
+		entry.Roles = identity.Roles
+	}
+
+	if brp.request.Request != nil {
+		entry.RemoteAddr = brp.request.Request.RemoteAddr
+	}
+
+	if len(documents) > 0 && brp.driver != nil {
+		ctx, cancel := brp.resource.ResourceCallback.CreateContext(
+			brp.resource,
+			brp.request,
+			response,
+			ctxDbDriverFind)
+		defer cancel()
+
+		for _, document := range documents {
+			entry.Documents = append(entry.Documents, brp.createAuditDocument(document, operation, ctx))
+		}
+	}
+
+	if err := auditSink.WriteAuditEntry(&entry, brp.driver); err != nil {
+		log.Printf("Unable to write audit entry %v: %v", entry.RequestUniqueID, err)
+	}
+}
+
+func (brp *BaseRequestProcessor) createAuditDocument(
+	document GoCakeModel,
+	operation string,
+	ctx context.Context) *AuditDocument {
+	var after map[string]any
+	var httpErr HTTPError
+
+	jsonObject, _ := document.ToMap()
+	delete(jsonObject, "_meta")
+
+	id := jsonObject[brp.resource.JSONSchemaConfig.IDField]
+
+	auditDocument := AuditDocument{
+		ID:         id,
+		StatusCode: http.StatusOK,
+	}
+
+	if httpErr = document.GetHTTPError(); httpErr != nil {
+		auditDocument.StatusCode = httpErr.GetStatusCode()
+
+		return &auditDocument
+	}
+
+	if operation == OPERATION_INSERT {
+		after = jsonObject
+	} else if operation != OPERATION_DELETE || brp.resource.IsSoftDelete() {
+		// state after the change, soft deleted documents too
+		serverFilter := maps.Clone(brp.serverFilter)
+		delete(serverFilter, brp.resource.SoftDeleteField)
+
+		if after, httpErr = brp.findDocument(id, serverFilter, ctx); httpErr != nil {
+			after = jsonObject
+		}
+	}
+
+	auditDocument.Changes = brp.diffDocuments(brp.previousDocuments[document], after)
+
+	return &auditDocument
+}
+
+// Field level diff, values of hidden and erased fields are
+// masked
+func (brp *BaseRequestProcessor) diffDocuments(before map[string]any, after map[string]any) map[string]*FieldChange {
+	changes := make(map[string]*FieldChange)
+
+	maskedFields := append(
+		slices.Clone(brp.resource.JSONSchemaConfig.HiddenFields),
+		brp.resource.JSONSchemaConfig.ErasedFields...)
+
+	maskAnyField := funk.ContainsString(maskedFields, FIELD_ANY)
+
+	fields := funk.UniqString(append(funk.Keys(before).([]string), funk.Keys(after).([]string)...))
+
+	for _, iJsonField := range fields {
+		beforeValue := before[iJsonField]
+		afterValue := after[iJsonField]
+
+		if brp.jsonValuesEqual(beforeValue, afterValue) {
+			continue
+		}
+
+		masked := funk.ContainsString(maskedFields, iJsonField) ||
+			(maskAnyField && funk.ContainsString(brp.resource.DbModelJSONFieldsNoReserved, iJsonField))
+
+		if masked && beforeValue != nil {
+			beforeValue = AUDIT_MASKED_VALUE
+		}
+
+		if masked && afterValue != nil {
+			afterValue = AUDIT_MASKED_VALUE
+		}
+
+		changes[iJsonField] = &FieldChange{
+			Before: beforeValue,
+			After:  afterValue,
+		}
+	}
+
+	return changes
+}
+
+// Notifies the EventListeners and the CacheControl about
+// successfully changed documents, skipped if the driver write
+// failed
+func (brp *BaseRequestProcessor) emitResourceEvent(
+	eventType string,
+	documents []GoCakeModel,
+	driverHttpErr HTTPError) {
+	if (len(brp.resource.EventListeners) == 0 && brp.resource.CacheControl == nil) ||
+		driverHttpErr != nil {
+		return
+	}
+
+	jsonDocuments := make([]map[string]any, 0, len(documents))
+
+	for _, document := range documents {
+		if document.GetHTTPError() != nil {
+			continue
+		}
+
+		if previous, ok := brp.previousDocuments[document]; ok && eventType == EVENT_DELETED {
+			// deleted documents are sent with their last state
+			jsonDocuments = append(jsonDocuments, maps.Clone(previous))
+			continue
+		}
+
+		jsonObject, err := document.ToMap()
+
+		if err != nil {
+			continue
+		}
+
+		jsonDocuments = append(jsonDocuments, jsonObject)
+	}
+
+	if len(jsonDocuments) == 0 {
+		return
+	}
+
+	event := NewResourceEvent(
+		brp.resource,
+		eventType,
+		brp.request.Tenant,
+		brp.request.UniqueID,
+		jsonDocuments)
+
+	if brp.resource.CacheControl != nil {
+		brp.resource.CacheControl.OnResourceEvent(event)
+	}
+
+	for _, listener := range brp.resource.EventListeners {
+		listener.OnResourceEvent(event)
+	}
+}
+
+// Document of the version with its number, operation and
+// time in the meta fields
+func (brp *BaseRequestProcessor) versionToDocument(version *DocumentVersion) GoCakeModel {
+	document := brp.resource.DbModel.CreateInstance()
+
+	jsonBytes, err := json.Marshal(version.Document)
+
+	if err == nil {
+		err = json.Unmarshal(jsonBytes, document)
+	}
+
+	if err != nil {
+		document.SetHTTPError(NewServerObjectMalformedHTTPError(version.Document, err))
+	}
+
+	document.SetMetaField(VERSION_META_FIELD, version.Version)
+	document.SetMetaField(VERSION_OPERATION_META_FIELD, version.Operation)
+	document.SetMetaField(VERSION_TIME_META_FIELD, version.Time)
+
+	return document
+}
+
+func (brp *BaseRequestProcessor) createSearchQuery() *SearchQuery {
+	if !brp.request.HasSearch() {
+		return nil
+	}
+
+	searchableFields := brp.resource.JSONSchemaConfig.SearchableFields
+
+	if funk.ContainsString(searchableFields, FIELD_ANY) {
+		searchableFields = brp.resource.DbModelJSONFieldsNoReserved
+	}
+
+	if len(brp.roleHiddenFields) > 0 {
+		searchableFields = funk.SubtractString(searchableFields, brp.roleHiddenFields)
+	}
+
+	return &SearchQuery{
+		Text:        brp.request.Search,
+		Fields:      searchableFields,
+		Language:    brp.resource.JSONSchemaConfig.SearchLanguage,
+		SortByScore: brp.request.SortByScore,
+	}
+}
+
+func (brp *BaseRequestProcessor) checkSupportedVersion() HTTPError {
+	if !utils.RegExUtilsInstance.HasMatch(
+		brp.resource.compiledSupportedVersion,
+		brp.request.Version) {
+		return NewUnsupportedVersionHTTPError(brp.request.Version, nil)
+	}
+
+	return nil
+}
+
+func (brp *BaseRequestProcessor) processCORS() HTTPError {
+	if brp.resource.CORSConfig == nil {
+		return nil
+	}
+
+	if brp.request.IsCORS {
+		return brp.processCORSbyCORSRequest()
+	} else {
+		return brp.processCORSbyOtherRequest()
+	}
+}
+
+func (brp *BaseRequestProcessor) processCORSbyCORSRequest() HTTPError {
+	origin := brp.request.Request.Header.Get("Origin")
+	supportedMethods := brp.getSupportedMethodsForOrigin(origin)
+
+	brp.CORSwriteAccessControlAllowMethods(
+		brp.request.ResponseWriter,
+		supportedMethods)
+
+	method := brp.request.Request.Header.Get("Access-Control-Request-Method")
+	methodIsSupported := funk.ContainsString(supportedMethods, method)
+
+	if !methodIsSupported {
+		brp.CORSwriteAccessControlAllowOrigin(brp.request.ResponseWriter, "null")
+	} else {
+		brp.CORSwriteAccessControlAllowOrigin(brp.request.ResponseWriter, origin)
+	}
+
+	return nil
+}
+
+func (brp *BaseRequestProcessor) processCORSbyOtherRequest() HTTPError {
+	origin := brp.request.Request.Header.Get("Origin")
+	supportedMethods := brp.getSupportedMethodsForOrigin(origin)
+
+	brp.CORSwriteAccessControlAllowMethods(
+		brp.request.ResponseWriter,
+		supportedMethods)
+
+	method := brp.request.Method
+	methodIsSupported := funk.ContainsString(supportedMethods, method)
+
+	if !methodIsSupported {
+		brp.CORSwriteAccessControlAllowOrigin(brp.request.ResponseWriter, "null")
+	} else {
+		brp.CORSwriteAccessControlAllowOrigin(brp.request.ResponseWriter, origin)
+	}
+
+	if !methodIsSupported {
+		return NewMethodNotAllowedHTTPError(nil)
+	}
+
+	return nil
+}
+
+func (brp *BaseRequestProcessor) callAuthHandlers(response *ResponseJSON) HTTPError {
+	resourceCallback := brp.resource.ResourceCallback
+
+	if resourceCallback == nil {
+		return nil
+	}
+
+	if resourceCallback.AuthErrorCallback != nil {
+		return resourceCallback.AuthErrorCallback(
+			brp.resource,
+			brp.request,
+			response)
+	}
+
+	if resourceCallback.AuthCallback != nil && !resourceCallback.AuthCallback(
+		brp.resource,
+		brp.request,
+		response) {
+		return NewUnauthorizedHTTPError(nil)
+	}
+
+	return nil
+}
+
+// Rejects the client IP with no tokens left for failed auths,
+// the tokens are taken by chargeAuthFailure
+func (brp *BaseRequestProcessor) checkAuthRateLimit() HTTPError {
+	limit, key := brp.getAuthFailuresBucket()
+
+	if limit == nil {
+		return nil
+	}
+
+	result, err := brp.resource.RateLimitConfig.GetStore().Take(key, limit, 0)
+
+	if err != nil {
+		return NewInternalServerErrorHTTPError(err)
+	}
+
+	if result.Remaining >= 1 {
+		return nil
+	}
+
+	// time of one token
+	result.Allowed = false
+	result.RetryAfter = limit.Period / time.Duration(limit.Requests)
+
+	brp.writeRateLimitHeaders(limit, result)
+
+	return NewTooManyRequestsHTTPError(result.RetryAfter, nil)
+}
+
+func (brp *BaseRequestProcessor) chargeAuthFailure(httpErr HTTPError) {
+	if httpErr.GetStatusCode() != http.StatusUnauthorized {
+		return
+	}
+
+	limit, key := brp.getAuthFailuresBucket()
+
+	if limit == nil {
+		return
+	}
+
+	// the auth error is returned anyway
+	brp.resource.RateLimitConfig.GetStore().Take(key, limit, 1)
+}
+
+func (brp *BaseRequestProcessor) getAuthFailuresBucket() (*RateLimit, string) {
+	rateLimitConfig := brp.resource.RateLimitConfig
+
+	if rateLimitConfig == nil || brp.request.IsCORS {
+		return nil, ""
+	}
+
+	limit := rateLimitConfig.GetAuthFailuresLimit()
+
+	if limit == nil || limit.Requests <= 0 || limit.Period <= 0 {
+		return nil, ""
+	}
+
+	clientIPKey := rateLimitConfig.GetClientIPKey(brp.request)
+
+	if clientIPKey == "" {
+		return nil, ""
+	}
+
+	return limit, RATE_LIMIT_AUTH_FAILURES_KEY_PREFIX + clientIPKey
+}
+
+// Takes tokens from the bucket of the client, after the auth
+// so authenticated clients have their own buckets
+func (brp *BaseRequestProcessor) checkRateLimit() HTTPError {
+	rateLimitConfig := brp.resource.RateLimitConfig
+
+	if rateLimitConfig == nil || brp.request.IsCORS {
+		return nil
+	}
+
+	operation := brp.request.Operation()
+	limit := rateLimitConfig.GetLimit(operation)
+
+	if limit == nil || limit.Requests <= 0 || limit.Period <= 0 {
+		return nil
+	}
+
+	clientKey := rateLimitConfig.GetClientKey(brp.resource, brp.request)
 
-		if brp.catchInternalError(response, recover()) {
-			return
-		}
+	if clientKey == "" {
+		return nil
+	}
 
-		brp.postRequestResponseActions(response)
+	cost := int64(1)
 
-		response.Meta.TotalTimeMs = time.Since(timeStart).Seconds() * 1000
+	if rateLimitConfig.CostPerItem && len(brp.request.DecodedJsonSlice) > 1 {
+		// bulk requests larger than the bucket take the full
+		// bucket, they would never be allowed otherwise
+		cost = min(int64(len(brp.request.DecodedJsonSlice)), limit.Requests)
+	}
 
-		brp.processTotals(response)
+	result, err := rateLimitConfig.GetStore().Take(
+		brp.resource.ResourceName+"|"+operation+"|"+clientKey,
+		limit,
+		cost)
 
-		if httpErr = brp.callPostRequestHandlers(response); httpErr != nil {
-			response.Meta.StatusMessage = httpErr.GetStatusMessage()
-			response.Meta.StatusCode = httpErr.GetStatusCode()
-		}
-	}()
+	if err != nil {
+		return NewInternalServerErrorHTTPError(err)
+	}
 
-	if httpErr = brp.processCORS(); httpErr != nil {
-		response.Meta.StatusMessage = httpErr.GetStatusMessage()
-		response.Meta.StatusCode = httpErr.GetStatusCode()
+	brp.writeRateLimitHeaders(limit, result)
 
-		return
+	if !result.Allowed {
+		return NewTooManyRequestsHTTPError(result.RetryAfter, nil)
 	}
 
-	if httpErr = brp.checkSupportedVersion(); httpErr != nil {
-		response.Meta.StatusMessage = httpErr.GetStatusMessage()
-		response.Meta.StatusCode = httpErr.GetStatusCode()
+	return nil
+}
 
+func (brp *BaseRequestProcessor) writeRateLimitHeaders(limit *RateLimit, result *RateLimitResult) {
+	if brp.request.ResponseWriter == nil {
 		return
 	}
 
-	if httpErr = brp.preRequestProjectableChecks(); httpErr != nil {
-		response.Meta.StatusMessage = httpErr.GetStatusMessage()
-		response.Meta.StatusCode = httpErr.GetStatusCode()
+	header := brp.request.ResponseWriter.Header()
+
+	header.Set("RateLimit-Limit", strconv.FormatInt(limit.Requests, 10))
+	header.Set("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+	header.Set("RateLimit-Reset", strconv.FormatInt(durationToSeconds(result.Reset), 10))
+	header.Set("RateLimit-Policy", fmt.Sprintf("%v;w=%v", limit.Requests, durationToSeconds(limit.Period)))
+
+	if !result.Allowed {
+		header.Set("Retry-After", strconv.FormatInt(durationToSeconds(result.RetryAfter), 10))
+	}
+}
+
+// Reserves the Idempotency-Key of the request, or loads the stored
+// response into the response if the request was already processed
+func (brp *BaseRequestProcessor) checkIdempotency(response *ResponseJSON) HTTPError {
+	idempotencyConfig := brp.resource.IdempotencyConfig
+
+	if idempotencyConfig == nil || brp.request.IdempotencyKey == "" || brp.request.IsCORS {
+		return nil
+	}
+
+	record := &IdempotencyRecord{
+		Key:         brp.createIdempotencyKey(),
+		RequestHash: brp.createIdempotencyRequestHash(),
+		ExpireTime:  time.Now().Add(idempotencyConfig.GetPendingTimeout()),
+	}
+
+	existing, err := idempotencyConfig.GetStore().Reserve(record)
+
+	if err != nil {
+		return NewInternalServerErrorHTTPError(err)
+	}
+
+	if existing == nil {
+		brp.idempotencyRecord = record
+
+		return nil
+	}
+
+	if existing.RequestHash != record.RequestHash {
+		return NewIdempotencyKeyReusedHTTPError(nil)
+	}
+
+	if !existing.Completed {
+		return NewIdempotencyKeyInUseHTTPError(nil)
+	}
+
+	if err := json.Unmarshal(existing.Body, response); err != nil {
+		return NewInternalServerErrorHTTPError(err)
+	}
+
+	brp.storedResponse = true
+
+	if brp.request.ResponseWriter != nil {
+		brp.request.ResponseWriter.Header().Set(IDEMPOTENCY_REPLAYED_HEADER, "true")
+	}
+
+	return nil
+}
+
+// Stores the response of the request which reserved the key,
+// server errors release the key so the client can retry
+func (brp *BaseRequestProcessor) completeIdempotency(response *ResponseJSON) {
+	record := brp.idempotencyRecord
 
+	if record == nil {
 		return
 	}
 
-	if httpErr = brp.preRequestFilterableChecks(); httpErr != nil {
-		response.Meta.StatusMessage = httpErr.GetStatusMessage()
-		response.Meta.StatusCode = httpErr.GetStatusCode()
+	brp.idempotencyRecord = nil
+
+	store := brp.resource.IdempotencyConfig.GetStore()
+
+	if response.Meta.StatusCode >= http.StatusInternalServerError {
+		if err := store.Remove(record.Key); err != nil {
+			log.Printf("Unable to remove idempotency key of %v: %v", brp.request.UniqueID, err)
+		}
 
 		return
 	}
 
-	if httpErr = brp.preRequestSortableChecks(); httpErr != nil {
-		response.Meta.StatusMessage = httpErr.GetStatusMessage()
-		response.Meta.StatusCode = httpErr.GetStatusCode()
+	body, err := json.Marshal(response)
+
+	if err != nil {
+		log.Printf("Unable to store response of %v: %v", brp.request.UniqueID, err)
 
 		return
 	}
 
-	if httpErr = brp.callPreRequestHandlers(response); httpErr != nil {
-		response.Meta.StatusMessage = httpErr.GetStatusMessage()
-		response.Meta.StatusCode = httpErr.GetStatusCode()
+	record.Completed = true
+	record.StatusCode = response.Meta.StatusCode
+	record.Body = body
+	record.ExpireTime = time.Now().Add(brp.resource.IdempotencyConfig.GetTTL())
+
+	if err := store.Complete(record); err != nil {
+		log.Printf("Unable to store response of %v: %v", brp.request.UniqueID, err)
+	}
+}
+
+// Client's key scoped to the resource, tenant and identity,
+// hashed so the stores do not keep the identities
+func (brp *BaseRequestProcessor) createIdempotencyKey() string {
+	parts := []string{
+		brp.resource.ResourceName,
+		brp.request.Tenant,
+		"",
+		"",
+		brp.request.IdempotencyKey,
+	}
+
+	if identity := brp.request.Identity; identity != nil {
+		parts[2] = identity.Provider
+		parts[3] = identity.Subject
+	}
+
+	return sha256Hex([]byte(strings.Join(parts, "\x00")))
+}
+
+func (brp *BaseRequestProcessor) createIdempotencyRequestHash() string {
+	data := strings.Join([]string{brp.request.Method, brp.request.URL, ""}, "\x00")
+
+	return sha256Hex(append([]byte(data), brp.request.Body...))
+}
+
+// Loads the cached response into the response, on miss the
+// response will be cached by storeCachedResponse()
+func (brp *BaseRequestProcessor) loadCachedResponse(response *ResponseJSON) HTTPError {
+	cacheControl := brp.resource.CacheControl
+
+	if cacheControl == nil ||
+		!brp.request.IsGet ||
+		brp.request.IsHead ||
+		brp.request.HasAction() {
+		return nil
+	}
+
+	cacheKey := brp.createCacheKey()
+	cacheTime := time.Now()
+
+	if !brp.isCacheBypassed() {
+		cached, err := cacheControl.GetCache().Get(brp.resource.ResourceName, cacheKey)
+
+		if err != nil {
+			return NewInternalServerErrorHTTPError(err)
+		}
+
+		if cached != nil {
+			if err := json.Unmarshal(cached.Body, response); err != nil {
+				return NewInternalServerErrorHTTPError(err)
+			}
+
+			response.Meta.RequestUniqueID = brp.request.UniqueID
+			response.Meta.URL = brp.request.URL
+
+			brp.storedResponse = true
+			brp.writeCacheHeaders(cacheTime.Sub(cached.StoreTime))
+
+			return nil
+		}
+	}
+
+	brp.cacheKey = cacheKey
+	brp.cacheTime = cacheTime
+
+	return nil
+}
 
+func (brp *BaseRequestProcessor) storeCachedResponse(response *ResponseJSON) {
+	cacheKey := brp.cacheKey
+
+	if cacheKey == "" || response.Meta.StatusCode != http.StatusOK {
 		return
 	}
 
-	if httpErr = brp.callAuthHandlers(response); httpErr != nil {
-		response.Meta.StatusMessage = httpErr.GetStatusMessage()
-		response.Meta.StatusCode = httpErr.GetStatusCode()
+	brp.cacheKey = ""
+
+	cacheControl := brp.resource.CacheControl
+
+	body, err := json.Marshal(response)
+
+	if err != nil {
+		log.Printf("Unable to cache response of %v: %v", brp.request.UniqueID, err)
 
 		return
 	}
 
-	if documents, httpErr = brp.subRequestProcessor.ProcessRequest(response); httpErr != nil {
-		response.Meta.StatusMessage = httpErr.GetStatusMessage()
-		response.Meta.StatusCode = httpErr.GetStatusCode()
+	err = cacheControl.GetCache().Set(brp.resource.ResourceName, cacheKey, &CachedResponse{
+		Body:       body,
+		StoreTime:  brp.cacheTime,
+		ExpireTime: brp.cacheTime.Add(cacheControl.GetTTL()),
+	})
+
+	if err != nil {
+		log.Printf("Unable to cache response of %v: %v", brp.request.UniqueID, err)
+
+		return
 	}
 
-	brp.documentsToJsonMapObjects(documents, response)
+	brp.writeCacheHeaders(0)
 }
 
-func (brp *BaseRequestProcessor) catchInternalError(response *ResponseJSON, r any) bool {
-	if r != nil {
-		message := fmt.Sprint(r)
+func (brp *BaseRequestProcessor) writeCacheHeaders(age time.Duration) {
+	if brp.request.ResponseWriter == nil {
+		return
+	}
 
-		httpErr := NewInternalServerErrorHTTPError(errors.New(message))
+	header := brp.request.ResponseWriter.Header()
 
-		response.Meta.StatusMessage = httpErr.GetStatusMessage()
-		response.Meta.StatusCode = httpErr.GetStatusCode()
+	header.Set("Cache-Control", brp.resource.CacheControl.GetHeaderValue(brp.request.IsAuthenticated()))
+	header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+}
 
-		return true
+// Client's Cache-Control: no-cache skips the lookup, the response
+// is still cached
+func (brp *BaseRequestProcessor) isCacheBypassed() bool {
+	if brp.request.Request == nil {
+		return false
+	}
+
+	for _, directive := range strings.Split(brp.request.Request.Header.Get("Cache-Control"), ",") {
+		if strings.TrimSpace(directive) == CACHE_CONTROL_BYPASS_VALUE {
+			return true
+		}
 	}
 
 	return false
 }
 
-func (brp *BaseRequestProcessor) processTotals(response *ResponseJSON) {
-	ctx, cancel := brp.resource.ResourceCallback.CreateContext(
-		brp.resource,
-		brp.request,
-		response,
-		ctxDbDriverTotal)
-	defer cancel()
+// Normalised query of the request scoped to the tenant and
+// identity, same queries written differently share the key
+func (brp *BaseRequestProcessor) createCacheKey() string {
+	request := brp.request
 
-	response.Meta.Total, _ = brp.resource.DatabaseDriver.Total(
-		brp.resource.DbModel,
-		brp.request.Where,
-		ctx,
-		nil)
-}
+	projection := make([]string, 0, len(request.Projection))
 
-func (brp *BaseRequestProcessor) checkSupportedVersion() HTTPError {
-	if !utils.RegExUtilsInstance.HasMatch(
-		brp.resource.compiledSupportedVersion,
-		brp.request.Version) {
-		return NewUnsupportedVersionHTTPError(brp.request.Version, nil)
+	for field, include := range request.Projection {
+		projection = append(projection, fmt.Sprintf("%v:%v", field, include))
 	}
 
-	return nil
-}
+	slices.Sort(projection)
 
-func (brp *BaseRequestProcessor) processCORS() HTTPError {
-	if brp.resource.CORSConfig == nil {
-		return nil
+	sortFields := strings.Split(request.Sort, ",")
+
+	for i := range sortFields {
+		sortFields[i] = strings.TrimSpace(sortFields[i])
 	}
 
-	if brp.request.IsCORS {
-		return brp.processCORSbyCORSRequest()
-	} else {
-		return brp.processCORSbyOtherRequest()
+	geo, _ := json.Marshal(request.Geo)
+
+	parts := []string{
+		request.Version,
+		request.CollectionPath,
+		request.ItemID,
+		brp.normalizeWhere(request.Where),
+		strings.Join(sortFields, ","),
+		request.Search,
+		strconv.FormatBool(request.SortByScore),
+		string(geo),
+		strings.Join(projection, ","),
+		strconv.FormatInt(request.Page, 10),
+		strconv.FormatInt(request.PerPage, 10),
+		strconv.FormatBool(request.IncludeDeleted),
+		strconv.FormatInt(request.DocumentVersion, 10),
+		request.Tenant,
+	}
+
+	if identity := request.Identity; identity != nil {
+		roles := slices.Clone(identity.Roles)
+
+		slices.Sort(roles)
+
+		parts = append(parts, identity.Provider, identity.Subject, strings.Join(roles, ","))
 	}
+
+	return sha256Hex([]byte(strings.Join(parts, "\x00")))
 }
 
-func (brp *BaseRequestProcessor) processCORSbyCORSRequest() HTTPError {
-	origin := brp.request.Request.Header.Get("Origin")
-	supportedMethods := brp.getSupportedMethodsForOrigin(origin)
+// JSON where is re-encoded with sorted keys and without
+// whitespaces, other syntaxes are kept as they are
+func (brp *BaseRequestProcessor) normalizeWhere(where string) string {
+	var decoded any
 
-	brp.CORSwriteAccessControlAllowMethods(
-		brp.request.ResponseWriter,
-		supportedMethods)
+	if err := json.Unmarshal([]byte(where), &decoded); err != nil {
+		return where
+	}
 
-	method := brp.request.Request.Header.Get("Access-Control-Request-Method")
-	methodIsSupported := funk.ContainsString(supportedMethods, method)
+	normalized, err := json.Marshal(decoded)
 
-	if !methodIsSupported {
-		brp.CORSwriteAccessControlAllowOrigin(brp.request.ResponseWriter, "null")
-	} else {
-		brp.CORSwriteAccessControlAllowOrigin(brp.request.ResponseWriter, origin)
+	if err != nil {
+		return where
 	}
 
-	return nil
+	return string(normalized)
 }
 
-func (brp *BaseRequestProcessor) processCORSbyOtherRequest() HTTPError {
-	origin := brp.request.Request.Header.Get("Origin")
-	supportedMethods := brp.getSupportedMethodsForOrigin(origin)
+func (brp *BaseRequestProcessor) checkAccessPolicy() HTTPError {
+	accessPolicy := brp.resource.AccessPolicy
+	operation := brp.request.Operation()
 
-	brp.CORSwriteAccessControlAllowMethods(
-		brp.request.ResponseWriter,
-		supportedMethods)
+	if accessPolicy == nil || operation == "" {
+		return nil
+	}
 
-	method := brp.request.Method
-	methodIsSupported := funk.ContainsString(supportedMethods, method)
+	policies := accessPolicy.GetRolePolicies(brp.request.Identity)
 
-	if !methodIsSupported {
-		brp.CORSwriteAccessControlAllowOrigin(brp.request.ResponseWriter, "null")
-	} else {
-		brp.CORSwriteAccessControlAllowOrigin(brp.request.ResponseWriter, origin)
+	if policies == nil {
+		if !brp.request.IsAuthenticated() {
+			return NewUnauthorizedHTTPError(nil)
+		}
+
+		return NewForbiddenHTTPError(nil)
 	}
 
-	if !methodIsSupported {
-		return NewMethodNotAllowedHTTPError(nil)
+	if !accessPolicy.IsOperationAllowed(policies, operation) {
+		return NewForbiddenHTTPError(fmt.Errorf("operation %v not allowed", operation))
+	}
+
+	brp.rolePolicies = policies
+	brp.roleVisibleFields = accessPolicy.GetVisibleFields(policies)
+	brp.roleHiddenFields = accessPolicy.GetHiddenFields(
+		policies,
+		brp.resource.DbModelJSONFieldsNoReserved)
+
+	if httpErr := brp.checkRoleHiddenFields(); httpErr != nil {
+		return httpErr
+	}
+
+	if !brp.request.IsInsert && !brp.request.IsUpdate {
+		return nil
+	}
+
+	for _, jsonObject := range brp.request.DecodedJsonSlice {
+		for iJsonField := range jsonObject {
+			if !accessPolicy.IsFieldWritable(policies, iJsonField) {
+				return NewFieldAccessDeniedHTTPError(iJsonField, nil)
+			}
+		}
 	}
 
 	return nil
 }
 
-func (brp *BaseRequestProcessor) callAuthHandlers(response *ResponseJSON) HTTPError {
-	if brp.resource.ResourceCallback == nil ||
-		brp.resource.ResourceCallback.AuthCallback == nil {
+// Fields hidden from the role cannot be used in the request
+func (brp *BaseRequestProcessor) checkRoleHiddenFields() HTTPError {
+	if len(brp.roleHiddenFields) == 0 {
 		return nil
 	}
 
-	if !brp.resource.ResourceCallback.AuthCallback(
-		brp.resource,
-		brp.request,
-		response) {
-		return NewUnauthorizedHTTPError(nil)
+	usedFields := make([]string, 0)
+
+	if brp.request.HasWhere() {
+		whereFields, httpErr := brp.resource.DatabaseDriver.GetWhereFields(
+			brp.resource.DbModel,
+			brp.request.Where)
+
+		if httpErr != nil {
+			return httpErr
+		}
+
+		usedFields = append(usedFields, whereFields...)
+	}
+
+	if brp.request.HasSort() {
+		sortFields, httpErr := brp.resource.DatabaseDriver.GetSortFields(
+			brp.resource.DbModel,
+			brp.request.Sort)
+
+		if httpErr != nil {
+			return httpErr
+		}
+
+		usedFields = append(usedFields, sortFields...)
+	}
+
+	if brp.request.HasGeo() {
+		usedFields = append(usedFields, brp.request.Geo.Field)
+	}
+
+	for iJsonField, projected := range brp.request.Projection {
+		if projected {
+			usedFields = append(usedFields, iJsonField)
+		}
+	}
+
+	for _, iJsonField := range usedFields {
+		if funk.ContainsString(brp.roleHiddenFields, iJsonField) {
+			return NewFieldAccessDeniedHTTPError(iJsonField, nil)
+		}
 	}
 
 	return nil
@@ -228,7 +1654,7 @@ func (brp *BaseRequestProcessor) callFetchedDocumentsHandlers(
 	currentHttpErr HTTPError) HTTPError {
 	if brp.resource.ResourceCallback == nil ||
 		brp.resource.ResourceCallback.FetchedDocuments == nil {
-		return nil
+		return currentHttpErr
 	}
 
 	return brp.resource.ResourceCallback.FetchedDocuments(
@@ -243,7 +1669,7 @@ func (brp *BaseRequestProcessor) callUpdatingDocumentsHandlers(
 	currentHttpErr HTTPError) HTTPError {
 	if brp.resource.ResourceCallback == nil ||
 		brp.resource.ResourceCallback.UpdatingDocuments == nil {
-		return nil
+		return currentHttpErr
 	}
 
 	return brp.resource.ResourceCallback.UpdatingDocuments(
@@ -258,7 +1684,7 @@ func (brp *BaseRequestProcessor) callUpdatedDocumentsHandlers(
 	currentHttpErr HTTPError) HTTPError {
 	if brp.resource.ResourceCallback == nil ||
 		brp.resource.ResourceCallback.UpdatedDocuments == nil {
-		return nil
+		return currentHttpErr
 	}
 
 	return brp.resource.ResourceCallback.UpdatedDocuments(
@@ -272,7 +1698,7 @@ func (brp *BaseRequestProcessor) callInsertingDocumentsHandlers(
 	documents []GoCakeModel, currentHttpErr HTTPError) HTTPError {
 	if brp.resource.ResourceCallback == nil ||
 		brp.resource.ResourceCallback.InsertingDocuments == nil {
-		return nil
+		return currentHttpErr
 	}
 
 	return brp.resource.ResourceCallback.InsertingDocuments(
@@ -287,7 +1713,7 @@ func (brp *BaseRequestProcessor) callInsertedDocumentsHandlers(
 	currentHttpErr HTTPError) HTTPError {
 	if brp.resource.ResourceCallback == nil ||
 		brp.resource.ResourceCallback.InsertedDocuments == nil {
-		return nil
+		return currentHttpErr
 	}
 
 	return brp.resource.ResourceCallback.InsertedDocuments(
@@ -302,7 +1728,7 @@ func (brp *BaseRequestProcessor) callDeletingDocumentsHandlers(
 	currentHttpErr HTTPError) HTTPError {
 	if brp.resource.ResourceCallback == nil ||
 		brp.resource.ResourceCallback.DeletingDocuments == nil {
-		return nil
+		return currentHttpErr
 	}
 
 	return brp.resource.ResourceCallback.DeletingDocuments(
@@ -317,7 +1743,7 @@ func (brp *BaseRequestProcessor) callDeletedDocumentsHandlers(
 	currentHttpErr HTTPError) HTTPError {
 	if brp.resource.ResourceCallback == nil ||
 		brp.resource.ResourceCallback.DeletedDocuments == nil {
-		return nil
+		return currentHttpErr
 	}
 
 	return brp.resource.ResourceCallback.DeletedDocuments(
@@ -437,6 +1863,42 @@ func (brp *BaseRequestProcessor) preRequestSortableChecks() HTTPError {
 	return nil
 }
 
+func (brp *BaseRequestProcessor) preRequestGeoChecks() HTTPError {
+	if !brp.request.HasGeo() {
+		return nil
+	}
+
+	geoField := brp.request.Geo.Field
+
+	if !funk.ContainsString(brp.resource.DbModelJSONFields, geoField) {
+		return NewFieldNotExistsHTTPError(geoField, nil)
+	}
+
+	geoFields := brp.resource.JSONSchemaConfig.GeoFields
+
+	if funk.ContainsString(geoFields, FIELD_ANY) {
+		return nil
+	}
+
+	if !funk.ContainsString(geoFields, geoField) {
+		return NewFieldNotGeoFilterableHTTPError(geoField, nil)
+	}
+
+	return nil
+}
+
+func (brp *BaseRequestProcessor) preRequestSearchableChecks() HTTPError {
+	if !brp.request.HasSearch() {
+		return nil
+	}
+
+	if len(brp.resource.JSONSchemaConfig.SearchableFields) == 0 {
+		return NewSearchNotAllowedHTTPError(nil)
+	}
+
+	return nil
+}
+
 func (brp *BaseRequestProcessor) preRequestProjectableChecks() HTTPError {
 	if len(brp.request.Projection) == 0 {
 		return nil
@@ -633,6 +2095,12 @@ func (brp *BaseRequestProcessor) postRequestResponseActions(response *ResponseJS
 		erasedFields = brp.resource.DbModelJSONFieldsNoReserved
 	}
 
+	if funk.ContainsString(brp.roleVisibleFields, FIELD_ANY) {
+		hiddenFields = nil
+	} else if len(brp.roleVisibleFields) > 0 {
+		hiddenFields = funk.SubtractString(hiddenFields, brp.roleVisibleFields)
+	}
+
 	// projection fields was validated at preRequestProjectableChecks()
 	for _, jsonObject := range response.Items {
 		brp.postRequestResponseHiddenAction(
@@ -646,6 +2114,12 @@ func (brp *BaseRequestProcessor) postRequestResponseActions(response *ResponseJS
 		brp.postRequestResponseErasedAction(
 			jsonObject,
 			erasedFields)
+
+		// fields hidden from the role cannot be projected
+		brp.postRequestResponseHiddenAction(
+			jsonObject,
+			nil,
+			brp.roleHiddenFields)
 	}
 
 	return nil
@@ -675,6 +2149,7 @@ func (brp *BaseRequestProcessor) getSupportedMethodsForOrigin(origin string) []s
 		brp.resource.CORSConfig.getCompiledOrigins,
 		origin) {
 		supported = append(supported, brp.request.GetGetMethods()...)
+		supported = append(supported, brp.request.GetHeadMethods()...)
 	}
 
 	// Delete
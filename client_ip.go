@@ -0,0 +1,37 @@
+package go_cake
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IP address of the client, with trustedProxies > 0 it is taken
+// from X-Forwarded-For skipping the addresses appended by the
+// trusted proxies, from the right, since the leftmost ones can
+// be set by the client
+func GetClientIP(request *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		forwardedFor := make([]string, 0)
+
+		for _, header := range request.Header.Values("X-Forwarded-For") {
+			for _, address := range strings.Split(header, ",") {
+				if address = strings.TrimSpace(address); address != "" {
+					forwardedFor = append(forwardedFor, address)
+				}
+			}
+		}
+
+		if len(forwardedFor) > 0 {
+			return forwardedFor[max(len(forwardedFor)-trustedProxies, 0)]
+		}
+	}
+
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+
+	if err != nil {
+		return request.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,64 @@
+package go_cake
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		forwardedFor   []string // X-Forwarded-For headers
+		trustedProxies int
+		want           string
+	}{
+		{
+			name:         "proxies not trusted",
+			forwardedFor: []string{"203.0.113.9"},
+			want:         "192.0.2.1",
+		},
+		{
+			name:           "no X-Forwarded-For",
+			trustedProxies: 1,
+			want:           "192.0.2.1",
+		},
+		{
+			name:           "address appended by the proxy",
+			forwardedFor:   []string{"203.0.113.9"},
+			trustedProxies: 1,
+			want:           "203.0.113.9",
+		},
+		{
+			name:           "address set by the client skipped",
+			forwardedFor:   []string{"10.0.0.1, 203.0.113.9"},
+			trustedProxies: 1,
+			want:           "203.0.113.9",
+		},
+		{
+			name:           "two proxies",
+			forwardedFor:   []string{"10.0.0.1, 203.0.113.9", "198.51.100.2"},
+			trustedProxies: 2,
+			want:           "203.0.113.9",
+		},
+		{
+			name:           "more proxies than addresses",
+			forwardedFor:   []string{"203.0.113.9"},
+			trustedProxies: 3,
+			want:           "203.0.113.9",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/", nil) // from 192.0.2.1:1234
+
+			for _, forwardedFor := range test.forwardedFor {
+				request.Header.Add("X-Forwarded-For", forwardedFor)
+			}
+
+			if got := GetClientIP(request, test.trustedProxies); got != test.want {
+				t.Errorf("GetClientIP() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// All the problems found in the configuration, reported at once
+// so they can be fixed in one go
+type ConfigError struct {
+	Source   string // file name or "config"
+	Problems []string
+}
+
+func (ce *ConfigError) Error() string {
+	if len(ce.Problems) == 1 {
+		return fmt.Sprintf("%v: %v", ce.Source, ce.Problems[0])
+	}
+
+	return fmt.Sprintf(
+		"%v: %v problems:\n  %v",
+		ce.Source,
+		len(ce.Problems),
+		strings.Join(ce.Problems, "\n  "))
+}
+
+func (ce *ConfigError) add(format string, args ...any) {
+	ce.Problems = append(ce.Problems, fmt.Sprintf(format, args...))
+}
+
+func (ce *ConfigError) hasProblems() bool {
+	return len(ce.Problems) > 0
+}
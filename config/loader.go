@@ -0,0 +1,504 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+	"github.com/thoas/go-funk"
+	"gopkg.in/yaml.v3"
+)
+
+const FORMAT_YAML = "yaml"
+const FORMAT_JSON = "json"
+
+var allowedMethods = []string{
+	go_cake.HTTP_REQUEST_GET_METHOD,
+	go_cake.HTTP_REQUEST_HEAD_METHOD,
+	go_cake.HTTP_REQUEST_POST_METHOD,
+	go_cake.HTTP_REQUEST_PUT_METHOD,
+	go_cake.HTTP_REQUEST_PATCH_METHOD,
+	go_cake.HTTP_REQUEST_DELETE_METHOD,
+}
+
+// Creates the resources described by YAML or JSON configuration,
+// models and drivers are referenced by the names they are
+// registered with, callbacks and other Go values are set on the
+// returned resources
+type Loader struct {
+	models  map[string]go_cake.GoCakeModel
+	drivers map[string]go_cake.DatabaseDriver
+}
+
+func NewLoader() *Loader {
+	return &Loader{
+		models:  make(map[string]go_cake.GoCakeModel),
+		drivers: make(map[string]go_cake.DatabaseDriver),
+	}
+}
+
+func (l *Loader) RegisterModel(name string, model go_cake.GoCakeModel) *Loader {
+	l.models[name] = model
+
+	return l
+}
+
+func (l *Loader) RegisterDriver(name string, driver go_cake.DatabaseDriver) *Loader {
+	l.drivers[name] = driver
+
+	return l
+}
+
+// Format is taken from the extension, .yaml, .yml or .json
+func (l *Loader) LoadFile(path string) ([]*go_cake.Resource, error) {
+	var format string
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = FORMAT_YAML
+	case ".json":
+		format = FORMAT_JSON
+	default:
+		return nil, fmt.Errorf("%v: unknown config format, expected .yaml, .yml or .json file", path)
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return l.load(data, format, path)
+}
+
+// Format is FORMAT_YAML or FORMAT_JSON
+func (l *Loader) Load(data []byte, format string) ([]*go_cake.Resource, error) {
+	return l.load(data, format, "config")
+}
+
+// Loads the resources from the file and adds them to the handler
+func (l *Loader) LoadIntoHandler(path string, handler *go_cake.Handler) ([]*go_cake.Resource, error) {
+	resources, err := l.LoadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, resource := range resources {
+		if err := handler.AddResource(resource); err != nil {
+			return nil, fmt.Errorf("%v: resource %v: %w", path, resource.ResourceName, err)
+		}
+	}
+
+	return resources, nil
+}
+
+func (l *Loader) load(data []byte, format string, source string) ([]*go_cake.Resource, error) {
+	config, err := l.decode(data, format)
+
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", source, err)
+	}
+
+	configErr := &ConfigError{Source: source}
+
+	l.validate(config, configErr)
+
+	if configErr.hasProblems() {
+		return nil, configErr
+	}
+
+	resources := make([]*go_cake.Resource, 0, len(config.Resources))
+
+	for i, resourceConfig := range config.Resources {
+		resource, err := l.createResource(resourceConfig)
+
+		if err != nil {
+			configErr.add("%v: %v", l.describe(i, resourceConfig), err)
+			continue
+		}
+
+		l.validateFields(resource, resourceConfig.Fields, l.describe(i, resourceConfig), configErr)
+
+		resources = append(resources, resource)
+	}
+
+	if configErr.hasProblems() {
+		return nil, configErr
+	}
+
+	return resources, nil
+}
+
+// Unknown keys are errors, they are usually typos
+func (l *Loader) decode(data []byte, format string) (*Config, error) {
+	var config Config
+
+	switch format {
+	case FORMAT_YAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+
+		if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	case FORMAT_JSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&config); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown config format %q, expected %v or %v", format, FORMAT_YAML, FORMAT_JSON)
+	}
+
+	return &config, nil
+}
+
+func (l *Loader) validate(config *Config, configErr *ConfigError) {
+	names := make(map[string]int)
+	patterns := make(map[string]int)
+
+	if len(config.Resources) == 0 {
+		configErr.add("no resources defined")
+	}
+
+	for i, resourceConfig := range config.Resources {
+		if resourceConfig == nil {
+			configErr.add("resources[%v]: empty resource", i)
+			continue
+		}
+
+		describe := l.describe(i, resourceConfig)
+
+		l.validateRequired(resourceConfig, describe, configErr)
+
+		if first, exists := names[resourceConfig.Name]; exists && resourceConfig.Name != "" {
+			configErr.add("%v: name already used by resources[%v]", describe, first)
+		} else {
+			names[resourceConfig.Name] = i
+		}
+
+		if first, exists := patterns[resourceConfig.Pattern]; exists && resourceConfig.Pattern != "" {
+			configErr.add("%v: pattern already used by resources[%v]", describe, first)
+		} else {
+			patterns[resourceConfig.Pattern] = i
+		}
+
+		if resourceConfig.Pattern != "" {
+			if _, err := regexp.Compile(resourceConfig.Pattern); err != nil {
+				configErr.add("%v: invalid pattern: %v", describe, err)
+			}
+		}
+
+		if _, ok := l.drivers[resourceConfig.Driver]; !ok && resourceConfig.Driver != "" {
+			configErr.add(
+				"%v: unknown driver %q, registered drivers: %v",
+				describe,
+				resourceConfig.Driver,
+				l.registeredNames(l.drivers))
+		}
+
+		if _, ok := l.models[resourceConfig.Model]; !ok && resourceConfig.Model != "" {
+			configErr.add(
+				"%v: unknown model %q, registered models: %v",
+				describe,
+				resourceConfig.Model,
+				l.registeredNames(l.models))
+		}
+
+		for _, method := range resourceConfig.AllowedMethods {
+			if !slices.Contains(allowedMethods, strings.ToUpper(method)) {
+				configErr.add(
+					"%v: unknown method %q in allowed_methods, expected one of %v",
+					describe,
+					method,
+					strings.Join(allowedMethods, ", "))
+			}
+		}
+
+		l.validateLimits(resourceConfig.Limits, describe, configErr)
+		l.validateCORS(resourceConfig.CORS, describe, configErr)
+	}
+}
+
+func (l *Loader) validateRequired(resourceConfig *ResourceConfig, describe string, configErr *ConfigError) {
+	required := []struct {
+		key   string
+		value string
+	}{
+		{"name", resourceConfig.Name},
+		{"pattern", resourceConfig.Pattern},
+		{"db_path", resourceConfig.DbPath},
+		{"driver", resourceConfig.Driver},
+		{"model", resourceConfig.Model},
+		{"id_field", resourceConfig.IDField},
+		{"json_id_field", resourceConfig.JSONIDField},
+	}
+
+	for _, field := range required {
+		if strings.TrimSpace(field.value) == "" {
+			configErr.add("%v: %v is required", describe, field.key)
+		}
+	}
+
+	if (resourceConfig.ETagField == "") != (resourceConfig.JSONETagField == "") {
+		configErr.add("%v: etag_field and json_etag_field must be set together", describe)
+	}
+}
+
+func (l *Loader) validateLimits(limits *LimitsConfig, describe string, configErr *ConfigError) {
+	if limits == nil {
+		return
+	}
+
+	values := map[string]int64{
+		"get_max_output_items":          limits.GetMaxOutputItems,
+		"insert_max_input_items":        limits.InsertMaxInputItems,
+		"insert_max_input_payload_size": limits.InsertMaxInputPayloadSize,
+		"update_max_input_items":        limits.UpdateMaxInputItems,
+		"update_max_input_payload_size": limits.UpdateMaxInputPayloadSize,
+		"delete_max_input_items":        limits.DeleteMaxInputItems,
+		"delete_max_input_payload_size": limits.DeleteMaxInputPayloadSize,
+	}
+
+	keys := funk.Keys(values).([]string)
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if values[key] < 0 {
+			configErr.add("%v: limits.%v cannot be negative", describe, key)
+		}
+	}
+}
+
+func (l *Loader) validateCORS(cors *CORSConfig, describe string, configErr *ConfigError) {
+	if cors == nil {
+		return
+	}
+
+	lists := []struct {
+		key     string
+		origins []string
+	}{
+		{"allowed_origins", cors.AllowedOrigins},
+		{"get_allowed_origins", cors.GetAllowedOrigins},
+		{"insert_allowed_origins", cors.InsertAllowedOrigins},
+		{"update_allowed_origins", cors.UpdateAllowedOrigins},
+		{"delete_allowed_origins", cors.DeleteAllowedOrigins},
+	}
+
+	for _, list := range lists {
+		for _, origin := range list.origins {
+			if _, err := regexp.Compile(origin); err != nil {
+				configErr.add("%v: invalid origin %q in cors.%v: %v", describe, origin, list.key, err)
+			}
+		}
+	}
+}
+
+// Fields of the lists must exist in the model, NewResource()
+// checked only the default lists
+func (l *Loader) validateFields(
+	resource *go_cake.Resource,
+	fields *FieldsConfig,
+	describe string,
+	configErr *ConfigError) {
+	if fields == nil {
+		return
+	}
+
+	lists := []struct {
+		key    string
+		fields []string
+	}{
+		{"filterable", fields.Filterable},
+		{"projectable", fields.Projectable},
+		{"sortable", fields.Sortable},
+		{"insertable", fields.Insertable},
+		{"updatable", fields.Updatable},
+		{"hidden", fields.Hidden},
+		{"erased", fields.Erased},
+		{"required_on_insert", fields.RequiredOnInsert},
+		{"required_on_update", fields.RequiredOnUpdate},
+		{"required_on_delete", fields.RequiredOnDelete},
+		{"searchable", fields.Searchable},
+		{"geo", fields.Geo},
+	}
+
+	for _, list := range lists {
+		for _, field := range list.fields {
+			if field == go_cake.FIELD_ANY || funk.ContainsString(resource.DbModelJSONFields, field) {
+				continue
+			}
+
+			configErr.add(
+				"%v: unknown field %q in fields.%v, %T model fields: %v",
+				describe,
+				field,
+				list.key,
+				resource.DbModel,
+				strings.Join(resource.DbModelJSONFields, ", "))
+		}
+	}
+}
+
+func (l *Loader) createResource(resourceConfig *ResourceConfig) (*go_cake.Resource, error) {
+	resource, err := go_cake.NewResource(
+		resourceConfig.Pattern,
+		resourceConfig.DbPath,
+		resourceConfig.Name,
+		l.drivers[resourceConfig.Driver],
+		l.models[resourceConfig.Model],
+		resourceConfig.IDField,
+		resourceConfig.JSONIDField,
+		resourceConfig.ETagField,
+		resourceConfig.JSONETagField,
+		resourceConfig.Versions,
+		nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	l.applyAllowedMethods(resource, resourceConfig.AllowedMethods)
+	l.applyLimits(resource, resourceConfig.Limits)
+	l.applyFields(resource, resourceConfig.Fields)
+
+	if err := l.applyCORS(resource, resourceConfig.CORS); err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+func (l *Loader) applyAllowedMethods(resource *go_cake.Resource, methods []string) {
+	if methods == nil {
+		return
+	}
+
+	hasMethod := func(allowed ...string) bool {
+		for _, method := range methods {
+			if slices.Contains(allowed, strings.ToUpper(method)) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	resource.GetAllowed = hasMethod(go_cake.HTTP_REQUEST_GET_METHOD, go_cake.HTTP_REQUEST_HEAD_METHOD)
+	resource.InsertAllowed = hasMethod(go_cake.HTTP_REQUEST_POST_METHOD)
+	resource.UpdateAllowed = hasMethod(go_cake.HTTP_REQUEST_PUT_METHOD, go_cake.HTTP_REQUEST_PATCH_METHOD)
+	resource.DeleteAllowed = hasMethod(go_cake.HTTP_REQUEST_DELETE_METHOD)
+}
+
+func (l *Loader) applyLimits(resource *go_cake.Resource, limits *LimitsConfig) {
+	if limits == nil {
+		return
+	}
+
+	setLimit := func(target *int64, value int64) {
+		if value > 0 {
+			*target = value
+		}
+	}
+
+	setLimit(&resource.GetMaxOutputItems, limits.GetMaxOutputItems)
+	setLimit(&resource.InsertMaxInputItems, limits.InsertMaxInputItems)
+	setLimit(&resource.InsertMaxInputPayloadSize, limits.InsertMaxInputPayloadSize)
+	setLimit(&resource.UpdateMaxInputItems, limits.UpdateMaxInputItems)
+	setLimit(&resource.UpdateMaxInputPayloadSize, limits.UpdateMaxInputPayloadSize)
+	setLimit(&resource.DeleteMaxInputItems, limits.DeleteMaxInputItems)
+	setLimit(&resource.DeleteMaxInputPayloadSize, limits.DeleteMaxInputPayloadSize)
+}
+
+func (l *Loader) applyFields(resource *go_cake.Resource, fields *FieldsConfig) {
+	if fields == nil {
+		return
+	}
+
+	schemaConfig := resource.JSONSchemaConfig
+
+	setFields := func(target *[]string, value []string) {
+		if value != nil {
+			*target = value
+		}
+	}
+
+	setFields(&schemaConfig.FilterableFields, fields.Filterable)
+	setFields(&schemaConfig.ProjectableFields, fields.Projectable)
+	setFields(&schemaConfig.SortableFields, fields.Sortable)
+	setFields(&schemaConfig.InsertableFields, fields.Insertable)
+	setFields(&schemaConfig.UpdatableFields, fields.Updatable)
+	setFields(&schemaConfig.HiddenFields, fields.Hidden)
+	setFields(&schemaConfig.ErasedFields, fields.Erased)
+	setFields(&schemaConfig.RequiredOnInsertFields, fields.RequiredOnInsert)
+	setFields(&schemaConfig.RequiredOnUpdateFields, fields.RequiredOnUpdate)
+	setFields(&schemaConfig.RequiredOnDeleteFields, fields.RequiredOnDelete)
+	setFields(&schemaConfig.SearchableFields, fields.Searchable)
+	setFields(&schemaConfig.GeoFields, fields.Geo)
+}
+
+func (l *Loader) applyCORS(resource *go_cake.Resource, cors *CORSConfig) error {
+	if cors == nil {
+		return nil
+	}
+
+	origins := func(operationOrigins []string) []string {
+		if operationOrigins != nil {
+			return operationOrigins
+		}
+
+		if cors.AllowedOrigins != nil {
+			return cors.AllowedOrigins
+		}
+
+		// default of NewResource()
+		return []string{".*"}
+	}
+
+	corsConfig, err := go_cake.NewCORSConfig(
+		origins(cors.GetAllowedOrigins),
+		origins(cors.DeleteAllowedOrigins),
+		origins(cors.InsertAllowedOrigins),
+		origins(cors.UpdateAllowedOrigins))
+
+	if err != nil {
+		return err
+	}
+
+	resource.CORSConfig = corsConfig
+
+	return nil
+}
+
+func (l *Loader) describe(index int, resourceConfig *ResourceConfig) string {
+	if resourceConfig.Name == "" {
+		return fmt.Sprintf("resources[%v]", index)
+	}
+
+	return fmt.Sprintf("resources[%v] (%v)", index, resourceConfig.Name)
+}
+
+func (l *Loader) registeredNames(registered any) string {
+	names := funk.Keys(registered).([]string)
+
+	if len(names) == 0 {
+		return "none"
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
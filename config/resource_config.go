@@ -0,0 +1,63 @@
+package config
+
+// File with the resources, see Loader
+type Config struct {
+	Resources []*ResourceConfig `json:"resources" yaml:"resources"`
+}
+
+// Arguments of go_cake.NewResource() and the settings applied to
+// the created resource, omitted settings keep their defaults
+type ResourceConfig struct {
+	Name           string        `json:"name" yaml:"name"`
+	Pattern        string        `json:"pattern" yaml:"pattern"`
+	DbPath         string        `json:"db_path" yaml:"db_path"`
+	Driver         string        `json:"driver" yaml:"driver"` // registered by Loader.RegisterDriver()
+	Model          string        `json:"model" yaml:"model"`   // registered by Loader.RegisterModel()
+	IDField        string        `json:"id_field" yaml:"id_field"`
+	JSONIDField    string        `json:"json_id_field" yaml:"json_id_field"`
+	ETagField      string        `json:"etag_field" yaml:"etag_field"`
+	JSONETagField  string        `json:"json_etag_field" yaml:"json_etag_field"`
+	Versions       []string      `json:"versions" yaml:"versions"`
+	AllowedMethods []string      `json:"allowed_methods" yaml:"allowed_methods"` // all if omitted
+	Limits         *LimitsConfig `json:"limits" yaml:"limits"`
+	Fields         *FieldsConfig `json:"fields" yaml:"fields"`
+	CORS           *CORSConfig   `json:"cors" yaml:"cors"`
+}
+
+// 0 keeps the default limit
+type LimitsConfig struct {
+	GetMaxOutputItems         int64 `json:"get_max_output_items" yaml:"get_max_output_items"`
+	InsertMaxInputItems       int64 `json:"insert_max_input_items" yaml:"insert_max_input_items"`
+	InsertMaxInputPayloadSize int64 `json:"insert_max_input_payload_size" yaml:"insert_max_input_payload_size"`
+	UpdateMaxInputItems       int64 `json:"update_max_input_items" yaml:"update_max_input_items"`
+	UpdateMaxInputPayloadSize int64 `json:"update_max_input_payload_size" yaml:"update_max_input_payload_size"`
+	DeleteMaxInputItems       int64 `json:"delete_max_input_items" yaml:"delete_max_input_items"`
+	DeleteMaxInputPayloadSize int64 `json:"delete_max_input_payload_size" yaml:"delete_max_input_payload_size"`
+}
+
+// JSON fields of go_cake.JSONSchemaConfig, omitted lists keep
+// their defaults, "*" means any field
+type FieldsConfig struct {
+	Filterable       []string `json:"filterable" yaml:"filterable"`
+	Projectable      []string `json:"projectable" yaml:"projectable"`
+	Sortable         []string `json:"sortable" yaml:"sortable"`
+	Insertable       []string `json:"insertable" yaml:"insertable"`
+	Updatable        []string `json:"updatable" yaml:"updatable"`
+	Hidden           []string `json:"hidden" yaml:"hidden"`
+	Erased           []string `json:"erased" yaml:"erased"`
+	RequiredOnInsert []string `json:"required_on_insert" yaml:"required_on_insert"`
+	RequiredOnUpdate []string `json:"required_on_update" yaml:"required_on_update"`
+	RequiredOnDelete []string `json:"required_on_delete" yaml:"required_on_delete"`
+	Searchable       []string `json:"searchable" yaml:"searchable"`
+	Geo              []string `json:"geo" yaml:"geo"`
+}
+
+// Regular expressions of the allowed origins, AllowedOrigins
+// applies to the operations without own list
+type CORSConfig struct {
+	AllowedOrigins       []string `json:"allowed_origins" yaml:"allowed_origins"`
+	GetAllowedOrigins    []string `json:"get_allowed_origins" yaml:"get_allowed_origins"`
+	InsertAllowedOrigins []string `json:"insert_allowed_origins" yaml:"insert_allowed_origins"`
+	UpdateAllowedOrigins []string `json:"update_allowed_origins" yaml:"update_allowed_origins"`
+	DeleteAllowedOrigins []string `json:"delete_allowed_origins" yaml:"delete_allowed_origins"`
+}
@@ -1,20 +1,40 @@
 package go_cake
 
+import "time"
+
 const MAX_URL_LENGTH = 2048
 const MAX_INPUT_PAYLOAD_SIZE = 2097152 // 2MB
-const MAX_REQUEST_TIME_MS = 1000
+const MAX_REQUEST_TIME_MS = 30000      // default timeout of the requests, see TimeoutConfig
 const MAX_INPUT_ITEMS = 1000
 const MAX_OUTPUT_ITEMS = 1000
 const ALLOWED_ACCEPT_HEADER_0 = "*/*"
 const ALLOWED_ACCEPT_HEADER_1 = "application/json"
+const ALLOWED_ACCEPT_HEADER_2 = "text/event-stream"
 const ALLOWED_REQUEST_CONTENT_TYPE = "application/json"
 const RESPONSE_CONTENT_TYPE = "application/json; charset=utf-8"
 const RESPONSE_CACHE_CONTROL = "no-store"
 const OBJECT_ID_FIELD_ERROR_NAME = "ObjectID"
 const HTTP_REQUEST_GET_METHOD = "GET"
+const HTTP_REQUEST_HEAD_METHOD = "HEAD"
 const HTTP_REQUEST_POST_METHOD = "POST"
 const HTTP_REQUEST_PUT_METHOD = "PUT"
 const HTTP_REQUEST_PATCH_METHOD = "PATCH"
 const HTTP_REQUEST_DELETE_METHOD = "DELETE"
 const HTTP_REQUEST_OPTIONS_METHOD = "OPTIONS"
 const FIELD_ANY = "*"
+const SEARCH_SCORE_META_FIELD = "search_score"
+const GEO_DISTANCE_META_FIELD = "geo_distance"
+const VERSION_META_FIELD = "version"
+const VERSION_OPERATION_META_FIELD = "version_operation"
+const VERSION_TIME_META_FIELD = "version_time"
+const LINKS_FIELD = "_links"
+const REVERT_VERSION_FIELD = "_version"
+const ACTION_PREFIX = "_"
+const ACTION_RESTORE = "restore"
+const ACTION_VERSIONS = "versions"
+const ACTION_REVERT = "revert"
+const ACTION_EVENTS = "events"
+const EVENTS_CONTENT_TYPE = "text/event-stream"
+const EVENTS_KEEPALIVE_INTERVAL = 15 * time.Second
+const EVENTS_RESET_TYPE = "reset"
+const REQUEST_TIMEOUT_HEADER = "X-Request-Timeout" // client's timeout in milliseconds
@@ -13,35 +13,90 @@ type DatabaseDriver interface {
 
 	Find(
 		model GoCakeModel,
+		dbPath string,
 		where, sort string,
+		search *SearchQuery,
+		geo *GeoQuery,
+		serverFilter map[string]any,
 		page, perPage int64,
 		ctx context.Context,
 		userData any) ([]GoCakeModel, HTTPError)
 
 	Delete(
 		model GoCakeModel,
+		dbPath string,
 		documents []GoCakeModel,
+		serverFilter map[string]any,
 		ctx context.Context,
 		userData any) HTTPError
 
 	Total(
 		model GoCakeModel,
+		dbPath string,
 		where string,
+		search *SearchQuery,
+		geo *GeoQuery,
+		serverFilter map[string]any,
 		ctx context.Context,
 		userData any) (uint64, HTTPError)
 
 	Insert(
 		model GoCakeModel,
+		dbPath string,
 		documents []GoCakeModel,
 		ctx context.Context,
 		userData any) HTTPError
 
 	Update(
 		model GoCakeModel,
+		dbPath string,
 		documents []GoCakeModel,
+		serverFilter map[string]any,
+		ctx context.Context,
+		userData any) HTTPError
+
+	// Updates only given JSON fields (and the ETag), nil and
+	// zero values are written too
+	UpdateFields(
+		model GoCakeModel,
+		dbPath string,
+		documents []GoCakeModel,
+		jsonFields []string,
+		serverFilter map[string]any,
 		ctx context.Context,
 		userData any) HTTPError
 
 	GetWhereFields(model GoCakeModel, where string) ([]string, HTTPError)
 	GetSortFields(model GoCakeModel, sort string) ([]string, HTTPError)
 }
+
+// Optional, implemented by the drivers able to check the
+// connection, used by the readiness endpoint
+type DatabaseDriverPinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Optional, implemented by the drivers exposing statistics of
+// the connection pool, used by the health endpoints
+type DatabaseDriverStatsProvider interface {
+	Stats() map[string]any
+}
+
+// Optional, implemented by the drivers wrapping other drivers,
+// like the metrics instrumented driver
+type DatabaseDriverWrapper interface {
+	Unwrap() DatabaseDriver
+}
+
+// Returns the innermost driver of the wrappers
+func UnwrapDatabaseDriver(driver DatabaseDriver) DatabaseDriver {
+	for {
+		wrapper, isWrapper := driver.(DatabaseDriverWrapper)
+
+		if !isWrapper {
+			return driver
+		}
+
+		driver = wrapper.Unwrap()
+	}
+}
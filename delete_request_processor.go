@@ -27,13 +27,21 @@ func (drp *DeleteRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoC
 		return nil, httpErr
 	}
 
-	if drp.request.HasWhere() || drp.request.HasSort() || drp.request.HasPage() {
+	if drp.request.HasWhere() ||
+		drp.request.HasSort() ||
+		drp.request.HasSearch() ||
+		drp.request.HasGeo() ||
+		drp.request.HasPage() {
 		return nil, NewModifiersNotAllowedHTTPError(nil)
 	}
 
 	drp.optimizeFields()
 	drp.preRequestJSONActions(drp.request.DecodedJsonSlice)
 
+	if drp.resource.IsSoftDelete() {
+		drp.preRequestSoftDeleteActions(drp.request.DecodedJsonSlice)
+	}
+
 	converted, err := drp.decodedJsonSliceToDBModels()
 
 	if err != nil {
@@ -59,11 +67,29 @@ func (drp *DeleteRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoC
 		ctxDbDriverDelete)
 	defer cancel()
 
-	httpErr = drp.resource.DatabaseDriver.Delete(
-		drp.resource.DbModel,
-		converted,
-		ctx,
-		nil)
+	drp.preChangeActions(converted, ctx)
+
+	if drp.resource.IsSoftDelete() {
+		httpErr = drp.driver.UpdateFields(
+			drp.resource.DbModel,
+			drp.dbPath,
+			converted,
+			[]string{drp.resource.SoftDeleteField},
+			drp.serverFilter,
+			ctx,
+			drp.request.UserData)
+	} else {
+		httpErr = drp.driver.Delete(
+			drp.resource.DbModel,
+			drp.dbPath,
+			converted,
+			drp.serverFilter,
+			ctx,
+			drp.request.UserData)
+	}
+
+	drp.saveDocumentVersions(converted, OPERATION_DELETE, httpErr, ctx)
+	drp.emitResourceEvent(EVENT_DELETED, converted, httpErr)
 
 	httpErr = drp.callDeletedDocumentsHandlers(converted, httpErr)
 
@@ -129,6 +155,20 @@ func (drp *DeleteRequestProcessor) preRequestJSONActions(jsonDocuments []map[str
 	}
 }
 
+// Soft deleted documents are updated with the marker
+// instead of being removed
+func (drp *DeleteRequestProcessor) preRequestSoftDeleteActions(jsonDocuments []map[string]any) {
+	marker := drp.resource.GetSoftDeleteMarker(true)
+
+	for _, jsonObject := range jsonDocuments {
+		if jsonObject["__http_error__"] != nil {
+			continue
+		}
+
+		jsonObject[drp.resource.SoftDeleteField] = marker
+	}
+}
+
 func (drp *DeleteRequestProcessor) preRequestValidateJSON(
 	jsonObjectMap map[string]any) HTTPError {
 	if drp.resource.JSONSchemaConfig == nil ||
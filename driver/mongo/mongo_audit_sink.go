@@ -0,0 +1,62 @@
+package mongo_driver
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const AUDIT_WRITE_TIMEOUT = 10 * time.Second
+
+// go_cake.AuditSink inserting the entries to the collection of
+// the request driver's database, like the tenant's database (the
+// database of the driver passed to the constructor for the other
+// drivers)
+type MongoAuditSink struct {
+	driver         *MongoDriver
+	collectionName string
+}
+
+func NewMongoAuditSink(driver *MongoDriver, collectionName string) *MongoAuditSink {
+	return &MongoAuditSink{
+		driver:         driver,
+		collectionName: collectionName,
+	}
+}
+
+func (mas *MongoAuditSink) WriteAuditEntry(entry *go_cake.AuditEntry, driver go_cake.DatabaseDriver) error {
+	var document bson.M
+
+	// keep JSON field names
+	entryBytes, err := json.Marshal(entry)
+
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(entryBytes, &document); err != nil {
+		return err
+	}
+
+	document["time"] = entry.Time
+
+	ctx, cancel := context.WithTimeout(context.Background(), AUDIT_WRITE_TIMEOUT)
+	defer cancel()
+
+	mongoDriver, ok := go_cake.UnwrapDatabaseDriver(driver).(*MongoDriver)
+
+	if !ok {
+		mongoDriver = mas.driver
+	}
+
+	_, err = mongoDriver.client.
+		Database(mongoDriver.DatabaseName).
+		Collection(mas.collectionName).
+		InsertOne(ctx, document)
+
+	return err
+}
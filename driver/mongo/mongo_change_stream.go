@@ -0,0 +1,239 @@
+package mongo_driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const CHANGE_STREAM_RETRY_INTERVAL = 5 * time.Second
+
+type changeStreamEvent struct {
+	OperationType string   `bson:"operationType"`
+	FullDocument  bson.Raw `bson:"fullDocument"`
+	DocumentKey   bson.Raw `bson:"documentKey"`
+}
+
+// Publishes go_cake.ResourceEvent for the changes of the collection
+// made by any process, like other instances of the API, requires
+// a replica set. Use it instead of adding the listener to the
+// resource, otherwise the changes are published twice
+type MongoChangeStream struct {
+	driver         *MongoDriver
+	resource       *go_cake.Resource
+	dbPath         string
+	tenant         string
+	listener       go_cake.EventListener
+	resumeToken    bson.Raw
+	releaseDriver  func() // of the tenant, by TenantConfig.GetDriver()
+	driverReleased bool
+	cancel         context.CancelFunc
+	stopped        chan struct{}
+	runningMutex   sync.Mutex
+}
+
+func NewMongoChangeStream(
+	driver *MongoDriver,
+	resource *go_cake.Resource,
+	dbPath string,
+	tenant string,
+	listener go_cake.EventListener) *MongoChangeStream {
+	return &MongoChangeStream{
+		driver:   driver,
+		resource: resource,
+		dbPath:   dbPath,
+		tenant:   tenant,
+		listener: listener,
+	}
+}
+
+// Change stream of the tenant's collection, the driver (with the
+// tenant's database) and the database path are resolved by the
+// resource's TenantConfig like for the requests, the driver is
+// held until Stop() so the stream cannot be started again
+func NewMongoTenantChangeStream(
+	resource *go_cake.Resource,
+	tenant string,
+	listener go_cake.EventListener) (*MongoChangeStream, error) {
+	if resource.TenantConfig == nil {
+		return nil, fmt.Errorf("resource %v has no tenant config", resource.ResourceName)
+	}
+
+	driver, dbPath, releaseDriver, err := resource.TenantConfig.GetDriver(resource, tenant)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mongoDriver, ok := go_cake.UnwrapDatabaseDriver(driver).(*MongoDriver)
+
+	if !ok {
+		releaseDriver()
+
+		return nil, fmt.Errorf("driver of tenant %v is %T, not MongoDriver", tenant, driver)
+	}
+
+	changeStream := NewMongoChangeStream(mongoDriver, resource, dbPath, tenant, listener)
+	changeStream.releaseDriver = releaseDriver
+
+	return changeStream, nil
+}
+
+func (mcs *MongoChangeStream) Start() error {
+	mcs.runningMutex.Lock()
+	defer mcs.runningMutex.Unlock()
+
+	if mcs.cancel != nil {
+		return errors.New("change stream already started")
+	}
+
+	if mcs.driverReleased {
+		return errors.New("driver of the tenant change stream released")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mcs.cancel = cancel
+	mcs.stopped = make(chan struct{})
+
+	go mcs.run(ctx, mcs.stopped)
+
+	return nil
+}
+
+func (mcs *MongoChangeStream) Stop() {
+	mcs.runningMutex.Lock()
+	defer mcs.runningMutex.Unlock()
+
+	if mcs.cancel != nil {
+		mcs.cancel()
+		<-mcs.stopped
+
+		mcs.cancel = nil
+		mcs.stopped = nil
+	}
+
+	if mcs.releaseDriver != nil {
+		mcs.releaseDriver()
+
+		mcs.releaseDriver = nil
+		mcs.driverReleased = true
+	}
+}
+
+// Reconnects after errors, resuming after the last seen change
+func (mcs *MongoChangeStream) run(ctx context.Context, stopped chan struct{}) {
+	defer close(stopped)
+
+	for {
+		err := mcs.watch(ctx)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Change stream of %v failed: %v", mcs.dbPath, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(CHANGE_STREAM_RETRY_INTERVAL):
+		}
+	}
+}
+
+func (mcs *MongoChangeStream) watch(ctx context.Context) error {
+	collection := mcs.driver.client.Database(mcs.driver.DatabaseName).Collection(mcs.dbPath)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}},
+		}}},
+	}
+
+	streamOptions := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
+	if mcs.resumeToken != nil {
+		streamOptions.SetResumeAfter(mcs.resumeToken)
+	}
+
+	stream, err := collection.Watch(ctx, pipeline, streamOptions)
+
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change changeStreamEvent
+
+		if err := stream.Decode(&change); err != nil {
+			return err
+		}
+
+		if event := mcs.toResourceEvent(&change); event != nil {
+			mcs.listener.OnResourceEvent(event)
+		}
+
+		mcs.resumeToken = stream.ResumeToken()
+	}
+
+	return stream.Err()
+}
+
+func (mcs *MongoChangeStream) toResourceEvent(change *changeStreamEvent) *go_cake.ResourceEvent {
+	var eventType string
+	var raw bson.Raw
+
+	switch change.OperationType {
+	case "insert":
+		eventType = go_cake.EVENT_INSERTED
+		raw = change.FullDocument
+	case "update", "replace":
+		eventType = go_cake.EVENT_UPDATED
+		raw = change.FullDocument
+	case "delete":
+		eventType = go_cake.EVENT_DELETED
+		raw = change.DocumentKey
+	}
+
+	if raw == nil {
+		// unknown operation or already deleted document
+		return nil
+	}
+
+	modelNewInstance := mcs.resource.DbModel.CreateInstance()
+
+	if err := bson.Unmarshal(raw, modelNewInstance); err != nil {
+		log.Printf("Unable to decode change of %v: %v", mcs.dbPath, err)
+		return nil
+	}
+
+	jsonObject, err := modelNewInstance.ToMap()
+
+	if err != nil {
+		return nil
+	}
+
+	if eventType == go_cake.EVENT_DELETED {
+		// only the key of deleted documents is known
+		idField := mcs.resource.JSONSchemaConfig.IDField
+		jsonObject = map[string]any{idField: jsonObject[idField]}
+	}
+
+	return go_cake.NewResourceEvent(
+		mcs.resource,
+		eventType,
+		mcs.tenant,
+		"",
+		[]map[string]any{jsonObject})
+}
@@ -3,8 +3,10 @@ package mongo_driver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ghetzel/go-stockutil/maputil"
@@ -16,15 +18,22 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 const ENCODABLE_OBJECT_ID = "64177cafe338354a050543f7"
+const SEARCH_SCORE_FIELD = "_go_cake_search_score"
+const GEO_DISTANCE_FIELD = "_go_cake_geo_distance"
+const EARTH_RADIUS_METERS = 6378100.0
 
 type MongoDriver struct {
-	ConnectionString string
-	DatabaseName     string
-	client           *mongo.Client
-	modelJSONTagMap  map[string]ModelSpecs
+	ConnectionString     string
+	DatabaseName         string
+	client               *mongo.Client
+	poolStats            *poolStats
+	modelJSONTagMap      map[string]ModelSpecs
+	textIndexes          map[string]map[string][]string // by model specs key, BSON fields by index name
+	modelJSONTagMapMutex sync.RWMutex                   // also of textIndexes
 }
 
 func NewMongoDriver(connectionString string, databaseName string, ctx context.Context) (*MongoDriver, error) {
@@ -33,11 +42,18 @@ func NewMongoDriver(connectionString string, databaseName string, ctx context.Co
 	driver := MongoDriver{
 		ConnectionString: connectionString,
 		DatabaseName:     databaseName,
+		poolStats:        &poolStats{},
 	}
 
 	driver.modelJSONTagMap = make(map[string]ModelSpecs)
+	driver.textIndexes = make(map[string]map[string][]string)
 
-	driver.client, err = mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	driver.client, err = mongo.Connect(
+		ctx,
+		options.Client().
+			ApplyURI(connectionString).
+			SetMonitor(newTracingCommandMonitor()).
+			SetPoolMonitor(newPoolMonitor(driver.poolStats)))
 
 	if err != nil {
 		return nil, &go_cake.UnableToInitDatabaseDriverError{}
@@ -46,10 +62,37 @@ func NewMongoDriver(connectionString string, databaseName string, ctx context.Co
 	return &driver, nil
 }
 
+// New MongoDriver for another database sharing the same client,
+// useful for per tenant databases, Close() disconnects the
+// shared client
+func (d *MongoDriver) WithDatabase(databaseName string) *MongoDriver {
+	driver := MongoDriver{
+		ConnectionString: d.ConnectionString,
+		DatabaseName:     databaseName,
+		client:           d.client,
+		poolStats:        d.poolStats,
+	}
+
+	driver.modelJSONTagMap = make(map[string]ModelSpecs)
+	driver.textIndexes = make(map[string]map[string][]string)
+
+	return &driver
+}
+
 func (d *MongoDriver) GetUnderlyingDriver() any {
 	return d.client
 }
 
+func (d *MongoDriver) Ping(ctx context.Context) error {
+	return d.client.Ping(ctx, readpref.Primary())
+}
+
+// Connection pool counters, shared by the drivers created
+// by WithDatabase()
+func (d *MongoDriver) Stats() map[string]any {
+	return d.poolStats.toMap()
+}
+
 func (d *MongoDriver) Close() error {
 	if d.client == nil {
 		return nil
@@ -58,11 +101,14 @@ func (d *MongoDriver) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := d.client.Disconnect(ctx); err != nil {
-		panic(err)
+	err := d.client.Disconnect(ctx)
+
+	if errors.Is(err, mongo.ErrClientDisconnected) {
+		// client shared with WithDatabase() drivers
+		return nil
 	}
 
-	return nil
+	return err
 }
 
 func (d *MongoDriver) TestModel(
@@ -70,9 +116,13 @@ func (d *MongoDriver) TestModel(
 	etagField string,
 	model go_cake.GoCakeModel,
 	dbPath string) error {
-	modelType := fmt.Sprintf("%T", model)
+	modelSpecsKey := d.getModelSpecsKey(model, dbPath)
 
-	if _, alreadyTested := d.modelJSONTagMap[modelType]; alreadyTested {
+	d.modelJSONTagMapMutex.RLock()
+	_, alreadyTested := d.modelJSONTagMap[modelSpecsKey]
+	d.modelJSONTagMapMutex.RUnlock()
+
+	if alreadyTested {
 		return nil
 	}
 
@@ -112,7 +162,10 @@ func (d *MongoDriver) TestModel(
 		return err
 	}
 
-	d.modelJSONTagMap[modelType] = ModelSpecs{
+	d.modelJSONTagMapMutex.Lock()
+	defer d.modelJSONTagMapMutex.Unlock()
+
+	d.modelJSONTagMap[modelSpecsKey] = ModelSpecs{
 		model:     model,
 		tagMap:    tagMap,
 		idField:   idField,
@@ -123,6 +176,19 @@ func (d *MongoDriver) TestModel(
 	return nil
 }
 
+// Models are tested per database path, so the same model
+// can be used by many resources or tenants
+func (d *MongoDriver) getModelSpecsKey(model go_cake.GoCakeModel, dbPath string) string {
+	return fmt.Sprintf("%T:%v", model, dbPath)
+}
+
+func (d *MongoDriver) getModelSpecs(model go_cake.GoCakeModel, dbPath string) ModelSpecs {
+	d.modelJSONTagMapMutex.RLock()
+	defer d.modelJSONTagMapMutex.RUnlock()
+
+	return d.modelJSONTagMap[d.getModelSpecsKey(model, dbPath)]
+}
+
 func (d *MongoDriver) testModelID(
 	model go_cake.GoCakeModel,
 	newModelInstance go_cake.GoCakeModel) error {
@@ -279,18 +345,16 @@ func (d *MongoDriver) jsonFieldsToBSONMap(jsonStr string, modelSpecs *ModelSpecs
 					}
 
 					parentValue[specs["bson"]] = utils.StructUtilsInstance.GetFinalValue(modelNewInstance.GetID())
-				} else if modelSpecs.etagField != "" {
-					if key == modelSpecs.tagMap[modelSpecs.etagField]["json"] {
-						valueStr := fmt.Sprintf("%v", value)
-
-						err := modelNewInstance.SetETag(valueStr)
+				} else if modelSpecs.etagField != "" && key == modelSpecs.tagMap[modelSpecs.etagField]["json"] {
+					valueStr := fmt.Sprintf("%v", value)
 
-						if err != nil {
-							return err
-						}
+					err := modelNewInstance.SetETag(valueStr)
 
-						parentValue[specs["bson"]] = utils.StructUtilsInstance.GetFinalValue(modelNewInstance.GetETag())
+					if err != nil {
+						return err
 					}
+
+					parentValue[specs["bson"]] = utils.StructUtilsInstance.GetFinalValue(modelNewInstance.GetETag())
 				} else {
 					parentValue[specs["bson"]] = value
 				}
@@ -321,17 +385,19 @@ func (d *MongoDriver) jsonWhereToFilter(jsonStr string, modelSpecs *ModelSpecs)
 
 func (d *MongoDriver) Find(
 	model go_cake.GoCakeModel,
+	dbPath string,
 	where, sort string,
+	search *go_cake.SearchQuery,
+	geo *go_cake.GeoQuery,
+	serverFilter map[string]any,
 	page, perPage int64,
 	ctx context.Context,
 	userData any) ([]go_cake.GoCakeModel, go_cake.HTTPError) {
 	var filter bson.M
 	var err error
+	var cursor *mongo.Cursor
 
-	resultDocuments := make([]go_cake.GoCakeModel, 0)
-
-	modelType := fmt.Sprintf("%T", model)
-	modelSpec := d.modelJSONTagMap[modelType]
+	modelSpec := d.getModelSpecs(model, dbPath)
 
 	if where != "" {
 		filter, err = d.jsonWhereToFilter(where, &modelSpec)
@@ -341,15 +407,40 @@ func (d *MongoDriver) Find(
 		}
 	}
 
-	options, _, httpErr := d.getFindOptions(sort, page, perPage, &modelSpec)
+	filter, err = d.applyServerFilter(filter, serverFilter, &modelSpec)
 
-	if httpErr != nil {
-		return nil, httpErr
+	if err != nil {
+		return nil, go_cake.NewLowLevelDriverHTTPError(err)
 	}
 
+	filter = d.applySearchToFilter(filter, search)
+	filter = d.applyGeoToFilter(filter, geo, &modelSpec, false)
+
 	collection := d.client.Database(d.DatabaseName).Collection(modelSpec.dbPath)
 
-	cursor, err := collection.Find(ctx, filter, &options)
+	if httpErr := d.checkTextIndexFields(collection, search, &modelSpec, ctx); httpErr != nil {
+		return nil, httpErr
+	}
+
+	if geo != nil && geo.HasNear() {
+		pipeline, httpErr := d.getGeoNearPipeline(filter, sort, search, geo, page, perPage, &modelSpec)
+
+		if httpErr != nil {
+			return nil, httpErr
+		}
+
+		cursor, err = collection.Aggregate(ctx, pipeline)
+	} else {
+		options, _, httpErr := d.getFindOptions(sort, page, perPage, &modelSpec)
+
+		if httpErr != nil {
+			return nil, httpErr
+		}
+
+		d.applySearchToFindOptions(&options, search)
+
+		cursor, err = collection.Find(ctx, filter, &options)
+	}
 
 	if err != nil {
 		httpErr := go_cake.NewLowLevelDriverHTTPError(err)
@@ -358,6 +449,17 @@ func (d *MongoDriver) Find(
 	}
 	defer cursor.Close(ctx)
 
+	return d.decodeCursor(model, cursor, search, geo, ctx)
+}
+
+func (d *MongoDriver) decodeCursor(
+	model go_cake.GoCakeModel,
+	cursor *mongo.Cursor,
+	search *go_cake.SearchQuery,
+	geo *go_cake.GeoQuery,
+	ctx context.Context) ([]go_cake.GoCakeModel, go_cake.HTTPError) {
+	resultDocuments := make([]go_cake.GoCakeModel, 0)
+
 	for cursor.Next(ctx) {
 		modelNewInstance := model.CreateInstance()
 
@@ -366,6 +468,18 @@ func (d *MongoDriver) Find(
 			modelNewInstance.SetHTTPError(httpErr)
 		}
 
+		if search != nil {
+			if score, ok := cursor.Current.Lookup(SEARCH_SCORE_FIELD).DoubleOK(); ok {
+				modelNewInstance.SetMetaField(go_cake.SEARCH_SCORE_META_FIELD, score)
+			}
+		}
+
+		if geo != nil && geo.HasNear() {
+			if distance, ok := cursor.Current.Lookup(GEO_DISTANCE_FIELD).DoubleOK(); ok {
+				modelNewInstance.SetMetaField(go_cake.GEO_DISTANCE_META_FIELD, distance)
+			}
+		}
+
 		resultDocuments = append(resultDocuments, modelNewInstance)
 	}
 
@@ -380,15 +494,18 @@ func (d *MongoDriver) Find(
 
 func (d *MongoDriver) Total(
 	model go_cake.GoCakeModel,
+	dbPath string,
 	where string,
+	search *go_cake.SearchQuery,
+	geo *go_cake.GeoQuery,
+	serverFilter map[string]any,
 	ctx context.Context,
 	userData any) (uint64, go_cake.HTTPError) {
 	var filter bson.M
 	var err error
 	var count int64
 
-	modelType := fmt.Sprintf("%T", model)
-	modelSpec := d.modelJSONTagMap[modelType]
+	modelSpec := d.getModelSpecs(model, dbPath)
 
 	if where != "" {
 		filter, err = d.jsonWhereToFilter(where, &modelSpec)
@@ -398,8 +515,21 @@ func (d *MongoDriver) Total(
 		}
 	}
 
+	filter, err = d.applyServerFilter(filter, serverFilter, &modelSpec)
+
+	if err != nil {
+		return 0, go_cake.NewLowLevelDriverHTTPError(err)
+	}
+
+	filter = d.applySearchToFilter(filter, search)
+	filter = d.applyGeoToFilter(filter, geo, &modelSpec, true)
+
 	collection := d.client.Database(d.DatabaseName).Collection(modelSpec.dbPath)
 
+	if httpErr := d.checkTextIndexFields(collection, search, &modelSpec, ctx); httpErr != nil {
+		return 0, httpErr
+	}
+
 	count, err = collection.CountDocuments(ctx, filter)
 
 	if err != nil {
@@ -411,8 +541,360 @@ func (d *MongoDriver) Total(
 	return uint64(count), nil
 }
 
+// $text requires a text index on the collection, the fields
+// covered by the index are defined at the database level
+func (d *MongoDriver) applySearchToFilter(filter bson.M, search *go_cake.SearchQuery) bson.M {
+	if search == nil || search.Text == "" {
+		return filter
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	text := bson.M{"$search": search.Text}
+
+	if search.Language != "" {
+		text["$language"] = search.Language
+	}
+
+	filter["$text"] = text
+
+	return filter
+}
+
+// $text always searches every field of the collection's text index,
+// so the search is rejected when the index covers a field which is
+// not in search.Fields (not searchable or hidden for the role)
+func (d *MongoDriver) checkTextIndexFields(
+	collection *mongo.Collection,
+	search *go_cake.SearchQuery,
+	modelSpecs *ModelSpecs,
+	ctx context.Context) go_cake.HTTPError {
+	if search == nil || search.Text == "" {
+		return nil
+	}
+
+	textIndexes, httpErr := d.getTextIndexes(collection, modelSpecs, ctx)
+
+	if httpErr != nil {
+		return httpErr
+	}
+
+	searchableBSONFields := make(map[string]bool, len(search.Fields))
+
+	for _, jsonField := range search.Fields {
+		searchableBSONFields[d.jsonFieldToBSONField(jsonField, modelSpecs)] = true
+	}
+
+	for indexName, indexedFields := range textIndexes {
+		for _, indexedField := range indexedFields {
+			rootField, _, _ := strings.Cut(indexedField, ".")
+
+			if indexedField == "$**" || !searchableBSONFields[rootField] {
+				return go_cake.NewSearchNotAllowedHTTPError(
+					fmt.Errorf("text index %v covers fields which are not searchable", indexName))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Fields of the collection's text indexes, listed once per model
+// and database path on the first search, so the text indexes
+// are expected to be created before the driver is used
+func (d *MongoDriver) getTextIndexes(
+	collection *mongo.Collection,
+	modelSpecs *ModelSpecs,
+	ctx context.Context) (map[string][]string, go_cake.HTTPError) {
+	modelSpecsKey := d.getModelSpecsKey(modelSpecs.model, modelSpecs.dbPath)
+
+	d.modelJSONTagMapMutex.RLock()
+	textIndexes, listed := d.textIndexes[modelSpecsKey]
+	d.modelJSONTagMapMutex.RUnlock()
+
+	if listed {
+		return textIndexes, nil
+	}
+
+	cursor, err := collection.Indexes().List(ctx)
+
+	if err != nil {
+		return nil, go_cake.NewLowLevelDriverHTTPError(err)
+	}
+
+	var indexes []bson.M
+
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return nil, go_cake.NewLowLevelDriverHTTPError(err)
+	}
+
+	textIndexes = make(map[string][]string)
+
+	for _, index := range indexes {
+		if indexedFields := d.getTextIndexFields(index); len(indexedFields) > 0 {
+			textIndexes[fmt.Sprint(index["name"])] = indexedFields
+		}
+	}
+
+	d.modelJSONTagMapMutex.Lock()
+	d.textIndexes[modelSpecsKey] = textIndexes
+	d.modelJSONTagMapMutex.Unlock()
+
+	return textIndexes, nil
+}
+
+// Text indexes list their fields in "weights"
+func (d *MongoDriver) getTextIndexFields(index bson.M) []string {
+	fields := make([]string, 0)
+
+	switch weights := index["weights"].(type) {
+	case bson.M:
+		for field := range weights {
+			fields = append(fields, field)
+		}
+	case bson.D:
+		for _, element := range weights {
+			fields = append(fields, element.Key)
+		}
+	}
+
+	return fields
+}
+
+func (d *MongoDriver) applySearchToFindOptions(options *options.FindOptions, search *go_cake.SearchQuery) {
+	if search == nil || search.Text == "" {
+		return
+	}
+
+	scoreMeta := bson.M{"$meta": "textScore"}
+
+	options.SetProjection(bson.M{SEARCH_SCORE_FIELD: scoreMeta})
+
+	if !search.SortByScore {
+		return
+	}
+
+	sort := bson.D{{Key: SEARCH_SCORE_FIELD, Value: scoreMeta}}
+
+	if currentSort, ok := options.Sort.(bson.D); ok {
+		sort = append(sort, currentSort...)
+	}
+
+	options.SetSort(sort)
+}
+
+func (d *MongoDriver) jsonFieldToBSONField(jsonField string, modelSpecs *ModelSpecs) string {
+	for _, specs := range modelSpecs.tagMap {
+		if specs["json"] == jsonField {
+			return specs["bson"]
+		}
+	}
+
+	return jsonField
+}
+
+func (d *MongoDriver) appendAndCondition(filter bson.M, condition bson.M) bson.M {
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	conditions, _ := filter["$and"].([]any)
+	filter["$and"] = append(conditions, condition)
+
+	return filter
+}
+
+// Server filter is a map of JSON fields and values set by the
+// server, it is converted like the "where" and always AND-ed
+func (d *MongoDriver) applyServerFilter(
+	filter bson.M,
+	serverFilter map[string]any,
+	modelSpecs *ModelSpecs) (bson.M, error) {
+	if len(serverFilter) == 0 {
+		return filter, nil
+	}
+
+	equalFilter := make(map[string]any, len(serverFilter))
+
+	for jsonField, value := range serverFilter {
+		if in, isIn := value.(go_cake.InFilter); isIn {
+			condition, err := d.getInCondition(jsonField, in.Values, modelSpecs)
+
+			if err != nil {
+				return nil, err
+			}
+
+			filter = d.appendAndCondition(filter, condition)
+			continue
+		}
+
+		notEqual, isNotEqual := value.(go_cake.NotEqualFilter)
+
+		if !isNotEqual {
+			equalFilter[jsonField] = value
+			continue
+		}
+
+		// $ne matches the documents without the field too
+		filter = d.appendAndCondition(
+			filter,
+			bson.M{d.jsonFieldToBSONField(jsonField, modelSpecs): bson.M{"$ne": notEqual.Value}})
+	}
+
+	if len(equalFilter) == 0 {
+		return filter, nil
+	}
+
+	jsonBytes, err := json.Marshal(equalFilter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	condition, err := d.jsonWhereToFilter(string(jsonBytes), modelSpecs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return d.appendAndCondition(filter, condition), nil
+}
+
+// Values are converted like in where so IDs and ETags
+// become their BSON types
+func (d *MongoDriver) getInCondition(
+	jsonField string,
+	values []any,
+	modelSpecs *ModelSpecs) (bson.M, error) {
+	bsonField := d.jsonFieldToBSONField(jsonField, modelSpecs)
+	bsonValues := make(bson.A, 0, len(values))
+
+	for _, value := range values {
+		jsonBytes, err := json.Marshal(map[string]any{jsonField: value})
+
+		if err != nil {
+			return nil, err
+		}
+
+		condition, err := d.jsonWhereToFilter(string(jsonBytes), modelSpecs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		bsonValues = append(bsonValues, condition[bsonField])
+	}
+
+	return bson.M{bsonField: bson.M{"$in": bsonValues}}, nil
+}
+
+// Adds $geoWithin condition to the filter, "near" is handled by
+// $geoNear aggregation stage in Find, but it cannot be used by
+// CountDocuments so forCount converts it to $centerSphere
+func (d *MongoDriver) applyGeoToFilter(
+	filter bson.M,
+	geo *go_cake.GeoQuery,
+	modelSpecs *ModelSpecs,
+	forCount bool) bson.M {
+	if geo == nil {
+		return filter
+	}
+
+	bsonField := d.jsonFieldToBSONField(geo.Field, modelSpecs)
+
+	if geo.HasWithin() {
+		filter = d.appendAndCondition(filter, bson.M{
+			bsonField: bson.M{
+				"$geoWithin": bson.M{
+					"$geometry": bson.M{
+						"type":        "Polygon",
+						"coordinates": [][][]float64{geo.GetWithinRing()},
+					},
+				},
+			},
+		})
+	}
+
+	if geo.HasNear() && forCount {
+		if geo.MaxDistance > 0 {
+			filter = d.appendAndCondition(filter, bson.M{
+				bsonField: bson.M{
+					"$geoWithin": bson.M{
+						"$centerSphere": bson.A{geo.Near, geo.MaxDistance / EARTH_RADIUS_METERS},
+					},
+				},
+			})
+		} else {
+			filter = d.appendAndCondition(filter, bson.M{
+				bsonField: bson.M{"$exists": true},
+			})
+		}
+	}
+
+	return filter
+}
+
+// $geoNear returns documents sorted by the distance, requires
+// 2dsphere index on the field
+func (d *MongoDriver) getGeoNearPipeline(
+	filter bson.M,
+	sortStr string,
+	search *go_cake.SearchQuery,
+	geo *go_cake.GeoQuery,
+	page int64,
+	perPage int64,
+	modelSpecs *ModelSpecs) (mongo.Pipeline, go_cake.HTTPError) {
+	if search != nil && search.Text != "" {
+		return nil, go_cake.NewMalformedGeoHTTPError(
+			errors.New("near cannot be combined with full-text search"))
+	}
+
+	geoNear := bson.M{
+		"near":          bson.M{"type": go_cake.GEO_JSON_POINT_TYPE, "coordinates": geo.Near},
+		"distanceField": GEO_DISTANCE_FIELD,
+		"key":           d.jsonFieldToBSONField(geo.Field, modelSpecs),
+		"spherical":     true,
+	}
+
+	if geo.MaxDistance > 0 {
+		geoNear["maxDistance"] = geo.MaxDistance
+	}
+
+	if len(filter) > 0 {
+		geoNear["query"] = filter
+	}
+
+	pipeline := mongo.Pipeline{bson.D{{Key: "$geoNear", Value: geoNear}}}
+
+	if sortStr != "" {
+		sort, httpErr := d.getSort(sortStr, modelSpecs)
+
+		if httpErr != nil {
+			return nil, httpErr
+		}
+
+		// keep results sorted by the distance first
+		sort = append(bson.D{{Key: GEO_DISTANCE_FIELD, Value: 1}}, sort...)
+
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: sort}})
+	}
+
+	if page > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: perPage * page}})
+	}
+
+	if perPage > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: perPage}})
+	}
+
+	return pipeline, nil
+}
+
 func (d *MongoDriver) Insert(
 	model go_cake.GoCakeModel,
+	dbPath string,
 	documents []go_cake.GoCakeModel,
 	ctx context.Context,
 	userData any) go_cake.HTTPError {
@@ -420,8 +902,7 @@ func (d *MongoDriver) Insert(
 		return nil
 	}
 
-	modelType := fmt.Sprintf("%T", model)
-	modelSpec := d.modelJSONTagMap[modelType]
+	modelSpec := d.getModelSpecs(model, dbPath)
 
 	collection := d.client.Database(d.DatabaseName).Collection(modelSpec.dbPath)
 
@@ -452,15 +933,22 @@ func (d *MongoDriver) Insert(
 
 func (d *MongoDriver) Delete(
 	model go_cake.GoCakeModel,
+	dbPath string,
 	documents []go_cake.GoCakeModel,
+	serverFilter map[string]any,
 	ctx context.Context,
 	userData any) go_cake.HTTPError {
 	if len(documents) == 0 {
 		return nil
 	}
 
-	modelType := fmt.Sprintf("%T", model)
-	modelSpec := d.modelJSONTagMap[modelType]
+	modelSpec := d.getModelSpecs(model, dbPath)
+
+	serverCondition, err := d.applyServerFilter(nil, serverFilter, &modelSpec)
+
+	if err != nil {
+		return go_cake.NewLowLevelDriverHTTPError(err)
+	}
 
 	collection := d.client.Database(d.DatabaseName).Collection(modelSpec.dbPath)
 
@@ -476,6 +964,10 @@ func (d *MongoDriver) Delete(
 			continue
 		}
 
+		if serverCondition != nil {
+			filter = d.appendAndCondition(filter, serverCondition)
+		}
+
 		result, err := collection.DeleteOne(ctx, filter)
 
 		if err != nil {
@@ -499,15 +991,22 @@ func (d *MongoDriver) Delete(
 
 func (d *MongoDriver) Update(
 	model go_cake.GoCakeModel,
+	dbPath string,
 	documents []go_cake.GoCakeModel,
+	serverFilter map[string]any,
 	ctx context.Context,
 	userData any) go_cake.HTTPError {
 	if len(documents) == 0 {
 		return nil
 	}
 
-	modelType := fmt.Sprintf("%T", model)
-	modelSpec := d.modelJSONTagMap[modelType]
+	modelSpec := d.getModelSpecs(model, dbPath)
+
+	serverCondition, err := d.applyServerFilter(nil, serverFilter, &modelSpec)
+
+	if err != nil {
+		return go_cake.NewLowLevelDriverHTTPError(err)
+	}
 
 	collection := d.client.Database(d.DatabaseName).Collection(modelSpec.dbPath)
 
@@ -523,6 +1022,10 @@ func (d *MongoDriver) Update(
 			continue
 		}
 
+		if serverCondition != nil {
+			filter = d.appendAndCondition(filter, serverCondition)
+		}
+
 		// update etag
 		item.CreateETag()
 
@@ -547,6 +1050,112 @@ func (d *MongoDriver) Update(
 	return nil
 }
 
+func (d *MongoDriver) UpdateFields(
+	model go_cake.GoCakeModel,
+	dbPath string,
+	documents []go_cake.GoCakeModel,
+	jsonFields []string,
+	serverFilter map[string]any,
+	ctx context.Context,
+	userData any) go_cake.HTTPError {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	modelSpec := d.getModelSpecs(model, dbPath)
+
+	serverCondition, err := d.applyServerFilter(nil, serverFilter, &modelSpec)
+
+	if err != nil {
+		return go_cake.NewLowLevelDriverHTTPError(err)
+	}
+
+	collection := d.client.Database(d.DatabaseName).Collection(modelSpec.dbPath)
+
+	for _, item := range documents {
+		if item.GetHTTPError() != nil {
+			continue
+		}
+
+		filter, httpErr := d.documentToFilter2(&modelSpec, item)
+
+		if httpErr != nil {
+			item.SetHTTPError(httpErr)
+			continue
+		}
+
+		if serverCondition != nil {
+			filter = d.appendAndCondition(filter, serverCondition)
+		}
+
+		// update etag
+		item.CreateETag()
+
+		set, err := d.documentToBSONFields(&modelSpec, item, jsonFields)
+
+		if err != nil {
+			item.SetHTTPError(go_cake.NewLowLevelDriverHTTPError(err))
+			continue
+		}
+
+		result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+
+		if err != nil {
+			item.SetHTTPError(go_cake.NewLowLevelDriverHTTPError(err))
+			continue
+		}
+
+		if result.MatchedCount > 1 {
+			item.SetHTTPError(go_cake.NewTooManyAffectedObjectsHTTPError(nil))
+			continue
+		}
+
+		if result.MatchedCount < 1 {
+			item.SetHTTPError(go_cake.NewObjectNotFoundHTTPError(nil))
+			continue
+		}
+	}
+
+	return nil
+}
+
+// Fields omitted by "omitempty" are set to nil so they
+// are written too
+func (d *MongoDriver) documentToBSONFields(
+	modelSpec *ModelSpecs,
+	document go_cake.GoCakeModel,
+	jsonFields []string) (bson.M, error) {
+	var documentMap bson.M
+
+	documentBytes, err := bson.Marshal(document)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = bson.Unmarshal(documentBytes, &documentMap); err != nil {
+		return nil, err
+	}
+
+	bsonFields := make([]string, 0, len(jsonFields)+1)
+
+	for _, jsonField := range jsonFields {
+		bsonFields = append(bsonFields, d.jsonFieldToBSONField(jsonField, modelSpec))
+	}
+
+	if modelSpec.etagField != "" {
+		bsonFields = append(bsonFields, modelSpec.tagMap[modelSpec.etagField]["bson"])
+	}
+
+	set := bson.M{}
+
+	for _, bsonField := range bsonFields {
+		set[bsonField] = documentMap[bsonField]
+	}
+
+	return set, nil
+}
+
 func (d *MongoDriver) documentToFilter2(
 	modelSpec *ModelSpecs,
 	document go_cake.GoCakeModel) (map[string]any, go_cake.HTTPError) {
@@ -0,0 +1,200 @@
+package mongo_driver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const HISTORY_SAVE_ATTEMPTS = 3
+
+type historyDocument struct {
+	DocumentID string         `bson:"document_id"`
+	Version    int64          `bson:"version"`
+	ETag       any            `bson:"etag"`
+	Operation  string         `bson:"operation"`
+	Time       time.Time      `bson:"time"`
+	Document   map[string]any `bson:"document"`
+}
+
+// go_cake.HistoryStore keeping the versions in shadow collections
+// <dbPath>_history of the request driver's database, like the
+// tenant's database (the database of the driver passed to the
+// constructor for the other drivers)
+type MongoHistoryStore struct {
+	driver             *MongoDriver
+	indexedCollections sync.Map
+}
+
+func NewMongoHistoryStore(driver *MongoDriver) *MongoHistoryStore {
+	return &MongoHistoryStore{driver: driver}
+}
+
+func (mhs *MongoHistoryStore) getCollection(
+	driver go_cake.DatabaseDriver,
+	dbPath string,
+	ctx context.Context) (*mongo.Collection, error) {
+	mongoDriver, ok := go_cake.UnwrapDatabaseDriver(driver).(*MongoDriver)
+
+	if !ok {
+		mongoDriver = mhs.driver
+	}
+
+	collection := mongoDriver.client.
+		Database(mongoDriver.DatabaseName).
+		Collection(dbPath + go_cake.HISTORY_DB_PATH_SUFFIX)
+	collectionKey := mongoDriver.DatabaseName + "." + collection.Name()
+
+	if _, indexed := mhs.indexedCollections.Load(collectionKey); indexed {
+		return collection, nil
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "document_id", Value: 1}, {Key: "version", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	mhs.indexedCollections.Store(collectionKey, true)
+
+	return collection, nil
+}
+
+// Version numbers are unique per document so concurrent saves
+// are retried with the next number
+func (mhs *MongoHistoryStore) SaveVersion(
+	resource *go_cake.Resource,
+	driver go_cake.DatabaseDriver,
+	dbPath string,
+	version *go_cake.DocumentVersion,
+	ctx context.Context) error {
+	collection, err := mhs.getCollection(driver, dbPath, ctx)
+
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < HISTORY_SAVE_ATTEMPTS; attempt++ {
+		var last historyDocument
+
+		err = collection.FindOne(
+			ctx,
+			bson.M{"document_id": version.DocumentID},
+			options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})).Decode(&last)
+
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+
+		_, err = collection.InsertOne(ctx, historyDocument{
+			DocumentID: version.DocumentID,
+			Version:    last.Version + 1,
+			ETag:       version.ETag,
+			Operation:  version.Operation,
+			Time:       version.Time,
+			Document:   version.Document,
+		})
+
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		version.Version = last.Version + 1
+
+		return nil
+	}
+
+	return err
+}
+
+func (mhs *MongoHistoryStore) GetVersions(
+	resource *go_cake.Resource,
+	driver go_cake.DatabaseDriver,
+	dbPath string,
+	documentID string,
+	ctx context.Context) ([]*go_cake.DocumentVersion, error) {
+	collection, err := mhs.getCollection(driver, dbPath, ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(
+		ctx,
+		bson.M{"document_id": documentID},
+		options.Find().SetSort(bson.D{{Key: "version", Value: -1}}))
+
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	versions := make([]*go_cake.DocumentVersion, 0)
+
+	for cursor.Next(ctx) {
+		var iDocument historyDocument
+
+		if err = cursor.Decode(&iDocument); err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, mhs.toDocumentVersion(&iDocument))
+	}
+
+	return versions, cursor.Err()
+}
+
+func (mhs *MongoHistoryStore) GetVersion(
+	resource *go_cake.Resource,
+	driver go_cake.DatabaseDriver,
+	dbPath string,
+	documentID string,
+	version int64,
+	ctx context.Context) (*go_cake.DocumentVersion, error) {
+	var document historyDocument
+
+	collection, err := mhs.getCollection(driver, dbPath, ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = collection.FindOne(
+		ctx,
+		bson.M{"document_id": documentID, "version": version}).Decode(&document)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mhs.toDocumentVersion(&document), nil
+}
+
+func (mhs *MongoHistoryStore) toDocumentVersion(document *historyDocument) *go_cake.DocumentVersion {
+	return &go_cake.DocumentVersion{
+		DocumentID: document.DocumentID,
+		Version:    document.Version,
+		ETag:       document.ETag,
+		Operation:  document.Operation,
+		Time:       document.Time,
+		Document:   document.Document,
+	}
+}
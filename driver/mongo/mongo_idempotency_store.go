@@ -0,0 +1,167 @@
+package mongo_driver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const IDEMPOTENCY_TIMEOUT = 10 * time.Second
+const IDEMPOTENCY_RESERVE_ATTEMPTS = 3
+
+type idempotencyDocument struct {
+	Key         string    `bson:"_id"`
+	RequestHash string    `bson:"request_hash"`
+	Completed   bool      `bson:"completed"`
+	StatusCode  int       `bson:"status_code"`
+	Body        []byte    `bson:"body"`
+	ExpireTime  time.Time `bson:"expire_time"`
+}
+
+// go_cake.IdempotencyStore keeping the records in the collection
+// of the driver's database, expired records are removed by
+// the TTL index
+type MongoIdempotencyStore struct {
+	driver         *MongoDriver
+	collectionName string
+	indexed        bool
+	indexMutex     sync.Mutex
+}
+
+func NewMongoIdempotencyStore(driver *MongoDriver, collectionName string) *MongoIdempotencyStore {
+	return &MongoIdempotencyStore{
+		driver:         driver,
+		collectionName: collectionName,
+	}
+}
+
+func (mis *MongoIdempotencyStore) getCollection(ctx context.Context) (*mongo.Collection, error) {
+	mis.indexMutex.Lock()
+	defer mis.indexMutex.Unlock()
+
+	collection := mis.driver.client.
+		Database(mis.driver.DatabaseName).
+		Collection(mis.collectionName)
+
+	if mis.indexed {
+		return collection, nil
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expire_time", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	mis.indexed = true
+
+	return collection, nil
+}
+
+// The TTL index removes the records with a delay so expired
+// records are taken over by the upsert, the upsert fails with
+// duplicate key error if the key is still in use
+func (mis *MongoIdempotencyStore) Reserve(record *go_cake.IdempotencyRecord) (*go_cake.IdempotencyRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), IDEMPOTENCY_TIMEOUT)
+	defer cancel()
+
+	collection, err := mis.getCollection(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < IDEMPOTENCY_RESERVE_ATTEMPTS; attempt++ {
+		var existing idempotencyDocument
+
+		_, err = collection.ReplaceOne(
+			ctx,
+			bson.M{"_id": record.Key, "expire_time": bson.M{"$lte": time.Now()}},
+			mis.toDocument(record),
+			options.Replace().SetUpsert(true))
+
+		if err == nil {
+			return nil, nil
+		}
+
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+
+		err = collection.FindOne(ctx, bson.M{"_id": record.Key}).Decode(&existing)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// removed in the meantime
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &go_cake.IdempotencyRecord{
+			Key:         existing.Key,
+			RequestHash: existing.RequestHash,
+			Completed:   existing.Completed,
+			StatusCode:  existing.StatusCode,
+			Body:        existing.Body,
+			ExpireTime:  existing.ExpireTime,
+		}, nil
+	}
+
+	return nil, err
+}
+
+func (mis *MongoIdempotencyStore) Complete(record *go_cake.IdempotencyRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), IDEMPOTENCY_TIMEOUT)
+	defer cancel()
+
+	collection, err := mis.getCollection(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.ReplaceOne(
+		ctx,
+		bson.M{"_id": record.Key, "request_hash": record.RequestHash},
+		mis.toDocument(record))
+
+	return err
+}
+
+func (mis *MongoIdempotencyStore) Remove(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), IDEMPOTENCY_TIMEOUT)
+	defer cancel()
+
+	collection, err := mis.getCollection(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.DeleteOne(ctx, bson.M{"_id": key})
+
+	return err
+}
+
+func (mis *MongoIdempotencyStore) toDocument(record *go_cake.IdempotencyRecord) *idempotencyDocument {
+	return &idempotencyDocument{
+		Key:         record.Key,
+		RequestHash: record.RequestHash,
+		Completed:   record.Completed,
+		StatusCode:  record.StatusCode,
+		Body:        record.Body,
+		ExpireTime:  record.ExpireTime,
+	}
+}
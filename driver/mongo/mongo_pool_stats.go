@@ -0,0 +1,46 @@
+package mongo_driver
+
+import (
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// Connection pool counters of all the servers of the client,
+// updated by the pool monitor
+type poolStats struct {
+	openConnections  atomic.Int64
+	inUseConnections atomic.Int64
+	checkOutFailures atomic.Int64
+}
+
+func newPoolMonitor(stats *poolStats) *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(poolEvent *event.PoolEvent) {
+			switch poolEvent.Type {
+			case event.ConnectionCreated:
+				stats.openConnections.Add(1)
+			case event.ConnectionClosed:
+				stats.openConnections.Add(-1)
+			case event.GetSucceeded:
+				stats.inUseConnections.Add(1)
+			case event.ConnectionReturned:
+				stats.inUseConnections.Add(-1)
+			case event.GetFailed:
+				stats.checkOutFailures.Add(1)
+			}
+		},
+	}
+}
+
+func (ps *poolStats) toMap() map[string]any {
+	openConnections := ps.openConnections.Load()
+	inUseConnections := ps.inUseConnections.Load()
+
+	return map[string]any{
+		"open_connections":   openConnections,
+		"in_use":             inUseConnections,
+		"idle":               max(openConnections-inUseConnections, 0),
+		"check_out_failures": ps.checkOutFailures.Load(),
+	}
+}
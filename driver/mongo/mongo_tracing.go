@@ -0,0 +1,150 @@
+package mongo_driver
+
+import (
+	"context"
+	"sync"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+	"github.com/thoas/go-funk"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TRACING_DB_SYSTEM = "mongodb"
+const TRACING_MAX_STATEMENT_LENGTH = 4096
+
+// command fields not included in db.statement, documents and
+// updates are too big even without the values
+var tracingOmittedCommandFields = []string{"documents", "updates", "lsid", "$clusterTime", "$db"}
+
+// Creates a client span for each command, spans are kept only
+// when recorded
+type tracingMonitor struct {
+	spans sync.Map
+}
+
+func newTracingCommandMonitor() *event.CommandMonitor {
+	monitor := tracingMonitor{}
+
+	return &event.CommandMonitor{
+		Started:   monitor.started,
+		Succeeded: monitor.succeeded,
+		Failed:    monitor.failed,
+	}
+}
+
+func (tm *tracingMonitor) started(ctx context.Context, startedEvent *event.CommandStartedEvent) {
+	attributes := []attribute.KeyValue{
+		attribute.String("db.system", TRACING_DB_SYSTEM),
+		attribute.String("db.name", startedEvent.DatabaseName),
+		attribute.String("db.operation.name", startedEvent.CommandName),
+		attribute.String("db.statement", tm.getStatement(startedEvent.Command)),
+	}
+
+	if collection, ok := startedEvent.Command.Index(0).Value().StringValueOK(); ok {
+		attributes = append(attributes, attribute.String("db.collection.name", collection))
+	}
+
+	_, span := otel.Tracer(go_cake.TRACER_NAME).Start(
+		ctx,
+		"mongo."+startedEvent.CommandName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...))
+
+	if !span.IsRecording() {
+		return
+	}
+
+	tm.spans.Store(startedEvent.RequestID, span)
+}
+
+func (tm *tracingMonitor) succeeded(ctx context.Context, succeededEvent *event.CommandSucceededEvent) {
+	if span, ok := tm.spans.LoadAndDelete(succeededEvent.RequestID); ok {
+		span.(trace.Span).End()
+	}
+}
+
+func (tm *tracingMonitor) failed(ctx context.Context, failedEvent *event.CommandFailedEvent) {
+	if span, ok := tm.spans.LoadAndDelete(failedEvent.RequestID); ok {
+		span.(trace.Span).SetStatus(codes.Error, failedEvent.Failure)
+		span.(trace.Span).End()
+	}
+}
+
+// Values of the command are replaced by ?, only the command
+// name (collection), keys and operators are kept
+func (tm *tracingMonitor) getStatement(command bson.Raw) string {
+	elements, err := command.Elements()
+
+	if err != nil {
+		return ""
+	}
+
+	statement := bson.D{}
+
+	for i, element := range elements {
+		if funk.ContainsString(tracingOmittedCommandFields, element.Key()) {
+			continue
+		}
+
+		if i == 0 {
+			statement = append(statement, bson.E{Key: element.Key(), Value: element.Value()})
+			continue
+		}
+
+		statement = append(statement, bson.E{Key: element.Key(), Value: tm.sanitizeValue(element.Value())})
+	}
+
+	statementJSON, err := bson.MarshalExtJSON(statement, false, false)
+
+	if err != nil {
+		return ""
+	}
+
+	if len(statementJSON) > TRACING_MAX_STATEMENT_LENGTH {
+		statementJSON = statementJSON[:TRACING_MAX_STATEMENT_LENGTH]
+	}
+
+	return string(statementJSON)
+}
+
+func (tm *tracingMonitor) sanitizeValue(value bson.RawValue) any {
+	switch value.Type {
+	case bsontype.EmbeddedDocument:
+		elements, err := value.Document().Elements()
+
+		if err != nil {
+			return "?"
+		}
+
+		document := bson.D{}
+
+		for _, element := range elements {
+			document = append(document, bson.E{Key: element.Key(), Value: tm.sanitizeValue(element.Value())})
+		}
+
+		return document
+	case bsontype.Array:
+		values, err := value.Array().Values()
+
+		if err != nil {
+			return "?"
+		}
+
+		array := bson.A{}
+
+		for _, arrayValue := range values {
+			array = append(array, tm.sanitizeValue(arrayValue))
+		}
+
+		return array
+	default:
+		return "?"
+	}
+}
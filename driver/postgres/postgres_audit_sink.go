@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+	"github.com/uptrace/bun"
+)
+
+const AUDIT_WRITE_TIMEOUT = 10 * time.Second
+
+// go_cake.AuditSink inserting the entries to the table of the
+// request driver's database (the database of the driver passed
+// to the constructor for the other drivers), created on first use
+type PostgresAuditSink struct {
+	driver        *PostgresDriver
+	tableName     string
+	createdTables sync.Map // by database
+}
+
+func NewPostgresAuditSink(driver *PostgresDriver, tableName string) *PostgresAuditSink {
+	return &PostgresAuditSink{
+		driver:    driver,
+		tableName: tableName,
+	}
+}
+
+func (pas *PostgresAuditSink) getDB(driver go_cake.DatabaseDriver) *bun.DB {
+	if postgresDriver, ok := go_cake.UnwrapDatabaseDriver(driver).(*PostgresDriver); ok {
+		return postgresDriver.db
+	}
+
+	return pas.driver.db
+}
+
+func (pas *PostgresAuditSink) createTable(db *bun.DB, ctx context.Context) error {
+	if _, created := pas.createdTables.Load(db); created {
+		return nil
+	}
+
+	_, err := db.NewRaw(
+		`CREATE TABLE IF NOT EXISTS ? (
+			id BIGSERIAL PRIMARY KEY,
+			time TIMESTAMPTZ NOT NULL,
+			request_unique_id TEXT NOT NULL,
+			resource TEXT NOT NULL,
+			operation TEXT NOT NULL,
+			subject TEXT,
+			tenant TEXT,
+			status_code INTEGER NOT NULL,
+			entry JSONB NOT NULL)`,
+		bun.Ident(pas.tableName)).Exec(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	pas.createdTables.Store(db, true)
+
+	return nil
+}
+
+func (pas *PostgresAuditSink) WriteAuditEntry(entry *go_cake.AuditEntry, driver go_cake.DatabaseDriver) error {
+	ctx, cancel := context.WithTimeout(context.Background(), AUDIT_WRITE_TIMEOUT)
+	defer cancel()
+
+	db := pas.getDB(driver)
+
+	if err := pas.createTable(db, ctx); err != nil {
+		return err
+	}
+
+	entryBytes, err := json.Marshal(entry)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = db.NewRaw(
+		`INSERT INTO ? (time, request_unique_id, resource, operation, subject, tenant, status_code, entry)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?::jsonb)`,
+		bun.Ident(pas.tableName),
+		entry.Time,
+		entry.RequestUniqueID,
+		entry.Resource,
+		entry.Operation,
+		entry.Subject,
+		entry.Tenant,
+		entry.StatusCode,
+		string(entryBytes)).Exec(ctx)
+
+	return err
+}
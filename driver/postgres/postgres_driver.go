@@ -3,8 +3,12 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/auxten/postgresql-parser/pkg/sql/parser"
 	"github.com/auxten/postgresql-parser/pkg/sql/sem/tree"
@@ -16,9 +20,13 @@ import (
 	"github.com/uptrace/bun/driver/pgdriver"
 )
 
+const META_ID_COLUMN = "_go_cake_id"
+const META_COLUMN_PREFIX = "_go_cake_"
+
 type PostgresDriver struct {
-	modelJSONTagMap map[string]ModelSpecs
-	db              *bun.DB
+	modelJSONTagMap      map[string]ModelSpecs
+	modelJSONTagMapMutex sync.RWMutex
+	db                   *bun.DB
 }
 
 // New PostgresDriver using github.com/uptrace/bun/driver/pgdriver driver
@@ -29,6 +37,7 @@ func NewPostgresDriver(connectionString string, ctx context.Context) (*PostgresD
 
 	sqldb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(connectionString)))
 	driver.db = bun.NewDB(sqldb, pgdialect.New())
+	driver.db.AddQueryHook(&tracingQueryHook{})
 
 	driver.modelJSONTagMap = make(map[string]ModelSpecs)
 
@@ -39,6 +48,23 @@ func (pd *PostgresDriver) GetUnderlyingDriver() any {
 	return pd.db
 }
 
+func (pd *PostgresDriver) Ping(ctx context.Context) error {
+	return pd.db.PingContext(ctx)
+}
+
+func (pd *PostgresDriver) Stats() map[string]any {
+	stats := pd.db.Stats()
+
+	return map[string]any{
+		"max_open_connections": stats.MaxOpenConnections,
+		"open_connections":     stats.OpenConnections,
+		"in_use":               stats.InUse,
+		"idle":                 stats.Idle,
+		"wait_count":           stats.WaitCount,
+		"wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+	}
+}
+
 func (pd *PostgresDriver) Close() error {
 	if pd.db == nil {
 		return nil
@@ -55,9 +81,13 @@ func (pd *PostgresDriver) TestModel(
 	etagField string,
 	model go_cake.GoCakeModel,
 	dbPath string) error {
-	modelType := fmt.Sprintf("%T", model)
+	modelSpecsKey := pd.getModelSpecsKey(model, dbPath)
+
+	pd.modelJSONTagMapMutex.RLock()
+	_, alreadyTested := pd.modelJSONTagMap[modelSpecsKey]
+	pd.modelJSONTagMapMutex.RUnlock()
 
-	if _, alreadyTested := pd.modelJSONTagMap[modelType]; alreadyTested {
+	if alreadyTested {
 		return nil
 	}
 
@@ -99,7 +129,10 @@ func (pd *PostgresDriver) TestModel(
 
 	// log.Println("tagMap", tagMap)
 
-	pd.modelJSONTagMap[modelType] = ModelSpecs{
+	pd.modelJSONTagMapMutex.Lock()
+	defer pd.modelJSONTagMapMutex.Unlock()
+
+	pd.modelJSONTagMap[modelSpecsKey] = ModelSpecs{
 		model:     model,
 		tagMap:    tagMap,
 		idField:   idField,
@@ -110,6 +143,35 @@ func (pd *PostgresDriver) TestModel(
 	return nil
 }
 
+// Models are tested per database path (schema.table), so the
+// same model can be used by many resources or tenants
+func (pd *PostgresDriver) getModelSpecsKey(model go_cake.GoCakeModel, dbPath string) string {
+	return fmt.Sprintf("%T:%v", model, dbPath)
+}
+
+func (pd *PostgresDriver) getModelSpecs(model go_cake.GoCakeModel, dbPath string) ModelSpecs {
+	pd.modelJSONTagMapMutex.RLock()
+	defer pd.modelJSONTagMapMutex.RUnlock()
+
+	return pd.modelJSONTagMap[pd.getModelSpecsKey(model, dbPath)]
+}
+
+// Field names do not depend on the database path
+func (pd *PostgresDriver) getModelSpecsByType(model go_cake.GoCakeModel) ModelSpecs {
+	pd.modelJSONTagMapMutex.RLock()
+	defer pd.modelJSONTagMapMutex.RUnlock()
+
+	modelType := fmt.Sprintf("%T", model)
+
+	for _, modelSpecs := range pd.modelJSONTagMap {
+		if fmt.Sprintf("%T", modelSpecs.model) == modelType {
+			return modelSpecs
+		}
+	}
+
+	return ModelSpecs{}
+}
+
 func (pd *PostgresDriver) testModelID(
 	model go_cake.GoCakeModel,
 	newModelInstance go_cake.GoCakeModel) error {
@@ -200,7 +262,9 @@ func (pd *PostgresDriver) modelSpecsJSONToBUNField(
 
 func (pd *PostgresDriver) selectQueryJSONFieldsToBun(
 	query *bun.SelectQuery,
-	modelSpecs *ModelSpecs) (*bun.SelectQuery, go_cake.HTTPError) {
+	modelSpecs *ModelSpecs,
+	search *go_cake.SearchQuery,
+	geo *go_cake.GeoQuery) (*bun.SelectQuery, go_cake.HTTPError) {
 	statements, err := parser.Parse(query.String())
 
 	if err != nil {
@@ -307,6 +371,20 @@ func (pd *PostgresDriver) selectQueryJSONFieldsToBun(
 		translatedQuery.Where(newWhere)
 	}
 
+	if geo != nil {
+		pd.applyGeo(translatedQuery, modelSpecs, geo)
+	}
+
+	if search != nil && search.Text != "" {
+		vectorExpr, queryExpr, args := pd.buildSearchExpressions(modelSpecs, search)
+
+		translatedQuery.Where(vectorExpr+" @@ "+queryExpr, args...)
+
+		if search.SortByScore {
+			translatedQuery.OrderExpr("ts_rank("+vectorExpr+", "+queryExpr+") DESC", args...)
+		}
+	}
+
 	if newOrderBy != "" {
 		translatedQuery.OrderExpr(newOrderBy)
 	}
@@ -322,6 +400,238 @@ func (pd *PostgresDriver) selectQueryJSONFieldsToBun(
 	return translatedQuery, nil
 }
 
+// Build to_tsvector(...) and plainto_tsquery(...) expressions
+// for the searchable fields, args are shared by both
+func (pd *PostgresDriver) buildSearchExpressions(
+	modelSpecs *ModelSpecs,
+	search *go_cake.SearchQuery) (string, string, []any) {
+	vectorArgs := make([]any, 0)
+	queryArgs := make([]any, 0)
+	placeholders := make([]string, 0)
+
+	for _, jsonField := range search.Fields {
+		bunName := pd.modelSpecsJSONToBUNField(jsonField, modelSpecs)
+
+		if bunName == "" {
+			continue
+		}
+
+		placeholders = append(placeholders, "?")
+		vectorArgs = append(vectorArgs, bun.Ident(bunName))
+	}
+
+	document := fmt.Sprintf("concat_ws(' ', %v)", strings.Join(placeholders, ", "))
+
+	vectorExpr := fmt.Sprintf("to_tsvector(%v)", document)
+	queryExpr := "plainto_tsquery(?)"
+
+	if search.Language != "" {
+		vectorExpr = fmt.Sprintf("to_tsvector(?::regconfig, %v)", document)
+		queryExpr = "plainto_tsquery(?::regconfig, ?)"
+
+		vectorArgs = append([]any{search.Language}, vectorArgs...)
+		queryArgs = append(queryArgs, search.Language)
+	}
+
+	queryArgs = append(queryArgs, search.Text)
+
+	return vectorExpr, queryExpr, append(vectorArgs, queryArgs...)
+}
+
+// Server filter is a map of JSON fields and values set by the
+// server, applied after the translation so the values are
+// passed as query arguments
+func (pd *PostgresDriver) applyServerFilter(
+	query bun.QueryBuilder,
+	modelSpecs *ModelSpecs,
+	serverFilter map[string]any) go_cake.HTTPError {
+	jsonFields := make([]string, 0, len(serverFilter))
+
+	for jsonField := range serverFilter {
+		jsonFields = append(jsonFields, jsonField)
+	}
+
+	sort.Strings(jsonFields)
+
+	for _, jsonField := range jsonFields {
+		bunName := pd.modelSpecsJSONToBUNField(jsonField, modelSpecs)
+
+		if bunName == "" {
+			return go_cake.NewLowLevelDriverHTTPError(
+				fmt.Errorf("unknown server filter field %v", jsonField))
+		}
+
+		value := serverFilter[jsonField]
+
+		if notEqual, isNotEqual := value.(go_cake.NotEqualFilter); isNotEqual {
+			// NULL is distinct from any value
+			query.Where("? IS DISTINCT FROM ?", bun.Ident(bunName), notEqual.Value)
+		} else if in, isIn := value.(go_cake.InFilter); isIn {
+			if len(in.Values) == 0 {
+				// IN () is not valid SQL
+				query.Where("FALSE")
+			} else {
+				query.Where("? IN (?)", bun.Ident(bunName), bun.In(in.Values))
+			}
+		} else if value == nil {
+			query.Where("? IS NULL", bun.Ident(bunName))
+		} else {
+			query.Where("? = ?", bun.Ident(bunName), value)
+		}
+	}
+
+	return nil
+}
+
+// Geo functions require PostGIS, the field is expected
+// to be geometry(Point, 4326) column
+func (pd *PostgresDriver) applyGeo(
+	query *bun.SelectQuery,
+	modelSpecs *ModelSpecs,
+	geo *go_cake.GeoQuery) {
+	column := bun.Ident(pd.modelSpecsJSONToBUNField(geo.Field, modelSpecs))
+
+	if geo.HasWithin() {
+		polygon, _ := json.Marshal(map[string]any{
+			"type":        "Polygon",
+			"coordinates": [][][]float64{geo.GetWithinRing()},
+		})
+
+		query.Where(
+			"ST_Within(?, ST_SetSRID(ST_GeomFromGeoJSON(?), ?))",
+			column,
+			string(polygon),
+			go_cake.GEO_SRID_WGS84)
+	}
+
+	if geo.HasNear() {
+		distanceExpr, args := pd.buildGeoDistanceExpression(modelSpecs, geo)
+
+		if geo.MaxDistance > 0 {
+			// ST_DWithin can use spatial index
+			query.Where(
+				"ST_DWithin(?::geography, ST_SetSRID(ST_MakePoint(?, ?), ?)::geography, ?)",
+				column,
+				geo.Near[0],
+				geo.Near[1],
+				go_cake.GEO_SRID_WGS84,
+				geo.MaxDistance)
+		}
+
+		query.OrderExpr(distanceExpr+" ASC", args...)
+	}
+}
+
+func (pd *PostgresDriver) buildGeoDistanceExpression(
+	modelSpecs *ModelSpecs,
+	geo *go_cake.GeoQuery) (string, []any) {
+	column := bun.Ident(pd.modelSpecsJSONToBUNField(geo.Field, modelSpecs))
+
+	return "ST_Distance(?::geography, ST_SetSRID(ST_MakePoint(?, ?), ?)::geography)",
+		[]any{column, geo.Near[0], geo.Near[1], go_cake.GEO_SRID_WGS84}
+}
+
+type metaColumn struct {
+	metaField string
+	expr      string
+	args      []any
+}
+
+func (pd *PostgresDriver) buildMetaColumns(
+	modelSpecs *ModelSpecs,
+	search *go_cake.SearchQuery,
+	geo *go_cake.GeoQuery) []metaColumn {
+	columns := make([]metaColumn, 0)
+
+	if search != nil && search.Text != "" {
+		vectorExpr, queryExpr, args := pd.buildSearchExpressions(modelSpecs, search)
+
+		columns = append(columns, metaColumn{
+			metaField: go_cake.SEARCH_SCORE_META_FIELD,
+			expr:      "ts_rank(" + vectorExpr + ", " + queryExpr + ")",
+			args:      args,
+		})
+	}
+
+	if geo != nil && geo.HasNear() {
+		distanceExpr, args := pd.buildGeoDistanceExpression(modelSpecs, geo)
+
+		columns = append(columns, metaColumn{
+			metaField: go_cake.GEO_DISTANCE_META_FIELD,
+			expr:      distanceExpr,
+			args:      args,
+		})
+	}
+
+	return columns
+}
+
+// Fetch computed values (like ts_rank() or ST_Distance()) of the found
+// documents and put them into the documents meta fields
+func (pd *PostgresDriver) fetchMetaFields(
+	modelSpecs *ModelSpecs,
+	columns []metaColumn,
+	documents []go_cake.GoCakeModel,
+	ctx context.Context) go_cake.HTTPError {
+	ids := make([]any, 0)
+
+	for _, item := range documents {
+		if item.GetID() == nil {
+			continue
+		}
+
+		ids = append(ids, utils.StructUtilsInstance.GetFinalValue(item.GetID()))
+	}
+
+	if len(ids) == 0 || len(columns) == 0 {
+		return nil
+	}
+
+	idColumn := modelSpecs.tagMap[modelSpecs.idField]["bun"]
+
+	query := pd.db.NewSelect().
+		Table(modelSpecs.dbPath).
+		ColumnExpr("? AS ?", bun.Ident(idColumn), bun.Ident(META_ID_COLUMN)).
+		Where("? IN (?)", bun.Ident(idColumn), bun.In(ids))
+
+	for _, column := range columns {
+		query.ColumnExpr(
+			column.expr+" AS ?",
+			append(column.args, bun.Ident(META_COLUMN_PREFIX+column.metaField))...)
+	}
+
+	rows := make([]map[string]any, 0)
+
+	if err := query.Scan(ctx, &rows); err != nil {
+		return go_cake.NewLowLevelDriverHTTPError(err)
+	}
+
+	rowsByID := make(map[string]map[string]any)
+
+	for _, row := range rows {
+		rowsByID[fmt.Sprint(row[META_ID_COLUMN])] = row
+	}
+
+	for _, item := range documents {
+		if item.GetID() == nil {
+			continue
+		}
+
+		id := fmt.Sprint(utils.StructUtilsInstance.GetFinalValue(item.GetID()))
+		row, ok := rowsByID[id]
+
+		if !ok {
+			continue
+		}
+
+		for _, column := range columns {
+			item.SetMetaField(column.metaField, row[META_COLUMN_PREFIX+column.metaField])
+		}
+	}
+
+	return nil
+}
+
 func (pd *PostgresDriver) selectQueryGetJSONFields(
 	query *bun.SelectQuery,
 	modelSpecs *ModelSpecs,
@@ -401,49 +711,69 @@ func (pd *PostgresDriver) buildSelectQuery(
 
 func (pd *PostgresDriver) Find(
 	model go_cake.GoCakeModel,
+	dbPath string,
 	where, sort string,
+	search *go_cake.SearchQuery,
+	geo *go_cake.GeoQuery,
+	serverFilter map[string]any,
 	page, perPage int64,
 	ctx context.Context,
 	userData any) ([]go_cake.GoCakeModel, go_cake.HTTPError) {
-	modelType := fmt.Sprintf("%T", model)
-	modelSpec := pd.modelJSONTagMap[modelType]
+	modelSpec := pd.getModelSpecs(model, dbPath)
 
 	resultDocuments := pd.prepareResultDocuments(model, int(perPage))
 
 	query := pd.buildSelectQuery(&modelSpec, where, sort, &page, &perPage)
 
-	translatedQuery, httpErr := pd.selectQueryJSONFieldsToBun(query, &modelSpec)
+	translatedQuery, httpErr := pd.selectQueryJSONFieldsToBun(query, &modelSpec, search, geo)
 
 	if httpErr != nil {
 		return nil, httpErr
 	}
 
+	if httpErr = pd.applyServerFilter(translatedQuery.QueryBuilder(), &modelSpec, serverFilter); httpErr != nil {
+		return nil, httpErr
+	}
+
 	err := translatedQuery.Scan(ctx, &resultDocuments)
 
 	if err != nil {
 		return nil, go_cake.NewLowLevelDriverHTTPError(err)
 	}
 
+	metaColumns := pd.buildMetaColumns(&modelSpec, search, geo)
+
+	if httpErr = pd.fetchMetaFields(&modelSpec, metaColumns, resultDocuments, ctx); httpErr != nil {
+		return nil, httpErr
+	}
+
 	return resultDocuments, nil
 }
 
 func (pd *PostgresDriver) Total(
 	model go_cake.GoCakeModel,
+	dbPath string,
 	where string,
+	search *go_cake.SearchQuery,
+	geo *go_cake.GeoQuery,
+	serverFilter map[string]any,
 	ctx context.Context,
 	userData any) (uint64, go_cake.HTTPError) {
 
-	modelType := fmt.Sprintf("%T", model)
-	modelSpec := pd.modelJSONTagMap[modelType]
+	modelSpec := pd.getModelSpecs(model, dbPath)
 
 	query := pd.buildSelectQuery(&modelSpec, where, "", nil, nil)
 
-	translatedQuery, httpErr := pd.selectQueryJSONFieldsToBun(query, &modelSpec)
+	translatedQuery, httpErr := pd.selectQueryJSONFieldsToBun(query, &modelSpec, search, geo)
 
 	if httpErr != nil {
 		return 0, httpErr
 	}
 
+	if httpErr = pd.applyServerFilter(translatedQuery.QueryBuilder(), &modelSpec, serverFilter); httpErr != nil {
+		return 0, httpErr
+	}
+
 	count, err := translatedQuery.Count(ctx)
 
 	if err != nil {
@@ -455,6 +785,7 @@ func (pd *PostgresDriver) Total(
 
 func (pd *PostgresDriver) Insert(
 	model go_cake.GoCakeModel,
+	dbPath string,
 	documents []go_cake.GoCakeModel,
 	ctx context.Context,
 	userData any) go_cake.HTTPError {
@@ -470,7 +801,10 @@ func (pd *PostgresDriver) Insert(
 		// update etag
 		item.CreateETag()
 
-		result, err := pd.db.NewInsert().Model(item).Exec(ctx)
+		result, err := pd.db.NewInsert().
+			Model(item).
+			ModelTableExpr("?", bun.Ident(dbPath)).
+			Exec(ctx)
 
 		if err != nil {
 			item.SetHTTPError(go_cake.NewLowLevelDriverHTTPError(err))
@@ -500,15 +834,16 @@ func (pd *PostgresDriver) Insert(
 
 func (pd *PostgresDriver) Delete(
 	model go_cake.GoCakeModel,
+	dbPath string,
 	documents []go_cake.GoCakeModel,
+	serverFilter map[string]any,
 	ctx context.Context,
 	userData any) go_cake.HTTPError {
 	if len(documents) == 0 {
 		return nil
 	}
 
-	modelType := fmt.Sprintf("%T", model)
-	modelSpec := pd.modelJSONTagMap[modelType]
+	modelSpec := pd.getModelSpecs(model, dbPath)
 
 	for _, item := range documents {
 		if item.GetHTTPError() != nil {
@@ -517,6 +852,11 @@ func (pd *PostgresDriver) Delete(
 
 		query := pd.buildDeleteQuery(&modelSpec, item)
 
+		if httpErr := pd.applyServerFilter(query.QueryBuilder(), &modelSpec, serverFilter); httpErr != nil {
+			item.SetHTTPError(httpErr)
+			continue
+		}
+
 		result, err := query.Exec(ctx)
 
 		if err != nil {
@@ -538,7 +878,7 @@ func (pd *PostgresDriver) Delete(
 func (pd *PostgresDriver) buildDeleteQuery(
 	modelSpec *ModelSpecs,
 	item go_cake.GoCakeModel) *bun.DeleteQuery {
-	query := pd.db.NewDelete().Model(item)
+	query := pd.db.NewDelete().Model(item).ModelTableExpr("?", bun.Ident(modelSpec.dbPath))
 
 	where := ""
 
@@ -565,7 +905,7 @@ func (pd *PostgresDriver) buildUpdateQuery(
 	modelSpec *ModelSpecs,
 	oldEtagValue any,
 	item go_cake.GoCakeModel) *bun.UpdateQuery {
-	query := pd.db.NewUpdate().Model(item)
+	query := pd.db.NewUpdate().Model(item).ModelTableExpr("?", bun.Ident(modelSpec.dbPath))
 
 	where := ""
 
@@ -590,15 +930,16 @@ func (pd *PostgresDriver) buildUpdateQuery(
 
 func (pd *PostgresDriver) Update(
 	model go_cake.GoCakeModel,
+	dbPath string,
 	documents []go_cake.GoCakeModel,
+	serverFilter map[string]any,
 	ctx context.Context,
 	userData any) go_cake.HTTPError {
 	if len(documents) == 0 {
 		return nil
 	}
 
-	modelType := fmt.Sprintf("%T", model)
-	modelSpec := pd.modelJSONTagMap[modelType]
+	modelSpec := pd.getModelSpecs(model, dbPath)
 
 	for _, item := range documents {
 		if item.GetHTTPError() != nil {
@@ -612,6 +953,77 @@ func (pd *PostgresDriver) Update(
 
 		query := pd.buildUpdateQuery(&modelSpec, oldEtagValue, item)
 
+		if httpErr := pd.applyServerFilter(query.QueryBuilder(), &modelSpec, serverFilter); httpErr != nil {
+			item.SetHTTPError(httpErr)
+			continue
+		}
+
+		result, err := query.Exec(ctx)
+
+		if err != nil {
+			item.SetHTTPError(go_cake.NewLowLevelDriverHTTPError(err))
+			continue
+		}
+
+		affectedRows, _ := result.RowsAffected()
+
+		if affectedRows <= 0 {
+			item.SetHTTPError(go_cake.NewObjectNotFoundHTTPError(nil))
+			continue
+		}
+	}
+
+	return nil
+}
+
+func (pd *PostgresDriver) UpdateFields(
+	model go_cake.GoCakeModel,
+	dbPath string,
+	documents []go_cake.GoCakeModel,
+	jsonFields []string,
+	serverFilter map[string]any,
+	ctx context.Context,
+	userData any) go_cake.HTTPError {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	modelSpec := pd.getModelSpecs(model, dbPath)
+
+	columns := make([]string, 0, len(jsonFields)+1)
+
+	for _, jsonField := range jsonFields {
+		bunName := pd.modelSpecsJSONToBUNField(jsonField, &modelSpec)
+
+		if bunName == "" {
+			return go_cake.NewLowLevelDriverHTTPError(
+				fmt.Errorf("unknown field %v", jsonField))
+		}
+
+		columns = append(columns, bunName)
+	}
+
+	if modelSpec.etagField != "" {
+		columns = append(columns, modelSpec.tagMap[modelSpec.etagField]["bun"])
+	}
+
+	for _, item := range documents {
+		if item.GetHTTPError() != nil {
+			continue
+		}
+
+		oldEtagValue := item.GetETag()
+
+		// update etag
+		item.CreateETag()
+
+		query := pd.buildUpdateQuery(&modelSpec, oldEtagValue, item).Column(columns...)
+
+		if httpErr := pd.applyServerFilter(query.QueryBuilder(), &modelSpec, serverFilter); httpErr != nil {
+			item.SetHTTPError(httpErr)
+			continue
+		}
+
 		result, err := query.Exec(ctx)
 
 		if err != nil {
@@ -633,8 +1045,7 @@ func (pd *PostgresDriver) Update(
 func (pd *PostgresDriver) GetWhereFields(
 	model go_cake.GoCakeModel,
 	where string) ([]string, go_cake.HTTPError) {
-	modelType := fmt.Sprintf("%T", model)
-	modelSpec := pd.modelJSONTagMap[modelType]
+	modelSpec := pd.getModelSpecsByType(model)
 
 	query := pd.buildSelectQuery(&modelSpec, where, "", nil, nil)
 
@@ -650,8 +1061,7 @@ func (pd *PostgresDriver) GetWhereFields(
 func (pd *PostgresDriver) GetSortFields(
 	model go_cake.GoCakeModel,
 	sort string) ([]string, go_cake.HTTPError) {
-	modelType := fmt.Sprintf("%T", model)
-	modelSpec := pd.modelJSONTagMap[modelType]
+	modelSpec := pd.getModelSpecsByType(model)
 
 	query := pd.buildSelectQuery(&modelSpec, "", sort, nil, nil)
 
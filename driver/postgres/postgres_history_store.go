@@ -0,0 +1,233 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+const HISTORY_SAVE_ATTEMPTS = 3
+
+type historyRow struct {
+	DocumentID string    `bun:"document_id"`
+	Version    int64     `bun:"version"`
+	ETag       []byte    `bun:"etag"`
+	Operation  string    `bun:"operation"`
+	Time       time.Time `bun:"time"`
+	Document   []byte    `bun:"document"`
+}
+
+// go_cake.HistoryStore keeping the versions in shadow tables
+// <dbPath>_history, created on first use, in the database of
+// the request's driver (the driver passed to the constructor
+// for the other drivers)
+type PostgresHistoryStore struct {
+	driver        *PostgresDriver
+	createdTables sync.Map
+}
+
+func NewPostgresHistoryStore(driver *PostgresDriver) *PostgresHistoryStore {
+	return &PostgresHistoryStore{driver: driver}
+}
+
+func (phs *PostgresHistoryStore) getDB(driver go_cake.DatabaseDriver) *bun.DB {
+	if postgresDriver, ok := go_cake.UnwrapDatabaseDriver(driver).(*PostgresDriver); ok {
+		return postgresDriver.db
+	}
+
+	return phs.driver.db
+}
+
+func (phs *PostgresHistoryStore) getTable(
+	driver go_cake.DatabaseDriver,
+	dbPath string,
+	ctx context.Context) (*bun.DB, string, error) {
+	db := phs.getDB(driver)
+	table := dbPath + go_cake.HISTORY_DB_PATH_SUFFIX
+	tableKey := fmt.Sprintf("%p/%v", db, table)
+
+	if _, created := phs.createdTables.Load(tableKey); created {
+		return db, table, nil
+	}
+
+	_, err := db.NewRaw(
+		`CREATE TABLE IF NOT EXISTS ? (
+			document_id TEXT NOT NULL,
+			version BIGINT NOT NULL,
+			etag JSONB,
+			operation TEXT NOT NULL,
+			time TIMESTAMPTZ NOT NULL,
+			document JSONB NOT NULL,
+			PRIMARY KEY (document_id, version))`,
+		bun.Ident(table)).Exec(ctx)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	phs.createdTables.Store(tableKey, true)
+
+	return db, table, nil
+}
+
+// Version numbers are unique per document so concurrent saves
+// are retried with the next number
+func (phs *PostgresHistoryStore) SaveVersion(
+	resource *go_cake.Resource,
+	driver go_cake.DatabaseDriver,
+	dbPath string,
+	version *go_cake.DocumentVersion,
+	ctx context.Context) error {
+	db, table, err := phs.getTable(driver, dbPath, ctx)
+
+	if err != nil {
+		return err
+	}
+
+	etag, err := json.Marshal(version.ETag)
+
+	if err != nil {
+		return err
+	}
+
+	document, err := json.Marshal(version.Document)
+
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < HISTORY_SAVE_ATTEMPTS; attempt++ {
+		var versionNumber int64
+
+		err = db.NewRaw(
+			`INSERT INTO ? (document_id, version, etag, operation, time, document)
+			SELECT ?, COALESCE(MAX(version), 0) + 1, ?::jsonb, ?, ?, ?::jsonb
+			FROM ? WHERE document_id = ?
+			RETURNING version`,
+			bun.Ident(table),
+			version.DocumentID,
+			string(etag),
+			version.Operation,
+			version.Time,
+			string(document),
+			bun.Ident(table),
+			version.DocumentID).Scan(ctx, &versionNumber)
+
+		var pgErr pgdriver.Error
+
+		if errors.As(err, &pgErr) && pgErr.IntegrityViolation() {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		version.Version = versionNumber
+
+		return nil
+	}
+
+	return err
+}
+
+func (phs *PostgresHistoryStore) GetVersions(
+	resource *go_cake.Resource,
+	driver go_cake.DatabaseDriver,
+	dbPath string,
+	documentID string,
+	ctx context.Context) ([]*go_cake.DocumentVersion, error) {
+	var rows []historyRow
+
+	db, table, err := phs.getTable(driver, dbPath, ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.NewRaw(
+		`SELECT document_id, version, etag, operation, time, document
+		FROM ? WHERE document_id = ? ORDER BY version DESC`,
+		bun.Ident(table),
+		documentID).Scan(ctx, &rows)
+
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*go_cake.DocumentVersion, 0, len(rows))
+
+	for i := range rows {
+		version, err := phs.toDocumentVersion(&rows[i])
+
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+func (phs *PostgresHistoryStore) GetVersion(
+	resource *go_cake.Resource,
+	driver go_cake.DatabaseDriver,
+	dbPath string,
+	documentID string,
+	version int64,
+	ctx context.Context) (*go_cake.DocumentVersion, error) {
+	var row historyRow
+
+	db, table, err := phs.getTable(driver, dbPath, ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.NewRaw(
+		`SELECT document_id, version, etag, operation, time, document
+		FROM ? WHERE document_id = ? AND version = ?`,
+		bun.Ident(table),
+		documentID,
+		version).Scan(ctx, &row)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return phs.toDocumentVersion(&row)
+}
+
+func (phs *PostgresHistoryStore) toDocumentVersion(row *historyRow) (*go_cake.DocumentVersion, error) {
+	version := go_cake.DocumentVersion{
+		DocumentID: row.DocumentID,
+		Version:    row.Version,
+		Operation:  row.Operation,
+		Time:       row.Time,
+	}
+
+	if len(row.ETag) > 0 {
+		if err := json.Unmarshal(row.ETag, &version.ETag); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := json.Unmarshal(row.Document, &version.Document); err != nil {
+		return nil, err
+	}
+
+	return &version, nil
+}
@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+	"github.com/uptrace/bun"
+)
+
+const IDEMPOTENCY_TIMEOUT = 10 * time.Second
+const IDEMPOTENCY_RESERVE_ATTEMPTS = 3
+
+type idempotencyRow struct {
+	Key         string    `bun:"key"`
+	RequestHash string    `bun:"request_hash"`
+	Completed   bool      `bun:"completed"`
+	StatusCode  int       `bun:"status_code"`
+	Body        []byte    `bun:"body"`
+	ExpireTime  time.Time `bun:"expire_time"`
+}
+
+// go_cake.IdempotencyStore keeping the records in the table,
+// created on first use, see DeleteExpired()
+type PostgresIdempotencyStore struct {
+	driver       *PostgresDriver
+	tableName    string
+	tableCreated bool
+	tableMutex   sync.Mutex
+}
+
+func NewPostgresIdempotencyStore(driver *PostgresDriver, tableName string) *PostgresIdempotencyStore {
+	return &PostgresIdempotencyStore{
+		driver:    driver,
+		tableName: tableName,
+	}
+}
+
+func (pis *PostgresIdempotencyStore) createTable(ctx context.Context) error {
+	pis.tableMutex.Lock()
+	defer pis.tableMutex.Unlock()
+
+	if pis.tableCreated {
+		return nil
+	}
+
+	_, err := pis.driver.db.NewRaw(
+		`CREATE TABLE IF NOT EXISTS ? (
+			key TEXT PRIMARY KEY,
+			request_hash TEXT NOT NULL,
+			completed BOOLEAN NOT NULL,
+			status_code INTEGER NOT NULL,
+			body BYTEA,
+			expire_time TIMESTAMPTZ NOT NULL)`,
+		bun.Ident(pis.tableName)).Exec(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	pis.tableCreated = true
+
+	return nil
+}
+
+// Expired records are taken over in the same statement, the
+// existing record is read only if the key is still in use
+func (pis *PostgresIdempotencyStore) Reserve(record *go_cake.IdempotencyRecord) (*go_cake.IdempotencyRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), IDEMPOTENCY_TIMEOUT)
+	defer cancel()
+
+	if err := pis.createTable(ctx); err != nil {
+		return nil, err
+	}
+
+	var err error
+
+	for attempt := 0; attempt < IDEMPOTENCY_RESERVE_ATTEMPTS; attempt++ {
+		var key string
+		var row idempotencyRow
+
+		err = pis.driver.db.NewRaw(
+			`INSERT INTO ? AS t (key, request_hash, completed, status_code, body, expire_time)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (key) DO UPDATE SET
+				request_hash = EXCLUDED.request_hash,
+				completed = EXCLUDED.completed,
+				status_code = EXCLUDED.status_code,
+				body = EXCLUDED.body,
+				expire_time = EXCLUDED.expire_time
+			WHERE t.expire_time <= ?
+			RETURNING key`,
+			bun.Ident(pis.tableName),
+			record.Key,
+			record.RequestHash,
+			record.Completed,
+			record.StatusCode,
+			record.Body,
+			record.ExpireTime,
+			time.Now()).Scan(ctx, &key)
+
+		if err == nil {
+			return nil, nil
+		}
+
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		err = pis.driver.db.NewRaw(
+			`SELECT key, request_hash, completed, status_code, body, expire_time
+			FROM ? WHERE key = ?`,
+			bun.Ident(pis.tableName),
+			record.Key).Scan(ctx, &row)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			// removed in the meantime
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &go_cake.IdempotencyRecord{
+			Key:         row.Key,
+			RequestHash: row.RequestHash,
+			Completed:   row.Completed,
+			StatusCode:  row.StatusCode,
+			Body:        row.Body,
+			ExpireTime:  row.ExpireTime,
+		}, nil
+	}
+
+	return nil, err
+}
+
+func (pis *PostgresIdempotencyStore) Complete(record *go_cake.IdempotencyRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), IDEMPOTENCY_TIMEOUT)
+	defer cancel()
+
+	if err := pis.createTable(ctx); err != nil {
+		return err
+	}
+
+	_, err := pis.driver.db.NewRaw(
+		`UPDATE ? SET completed = ?, status_code = ?, body = ?, expire_time = ?
+		WHERE key = ? AND request_hash = ?`,
+		bun.Ident(pis.tableName),
+		record.Completed,
+		record.StatusCode,
+		record.Body,
+		record.ExpireTime,
+		record.Key,
+		record.RequestHash).Exec(ctx)
+
+	return err
+}
+
+func (pis *PostgresIdempotencyStore) Remove(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), IDEMPOTENCY_TIMEOUT)
+	defer cancel()
+
+	if err := pis.createTable(ctx); err != nil {
+		return err
+	}
+
+	_, err := pis.driver.db.NewRaw(
+		`DELETE FROM ? WHERE key = ?`,
+		bun.Ident(pis.tableName),
+		key).Exec(ctx)
+
+	return err
+}
+
+// Expired records are reused by Reserve() but never removed,
+// should be called periodically by the app
+func (pis *PostgresIdempotencyStore) DeleteExpired(ctx context.Context) (int64, error) {
+	if err := pis.createTable(ctx); err != nil {
+		return 0, err
+	}
+
+	result, err := pis.driver.db.NewRaw(
+		`DELETE FROM ? WHERE expire_time <= ?`,
+		bun.Ident(pis.tableName),
+		time.Now()).Exec(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+	"github.com/uptrace/bun"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TRACING_DB_SYSTEM = "postgresql"
+const TRACING_MAX_STATEMENT_LENGTH = 4096
+
+// quoted identifiers are matched first so they are kept
+var tracingStatementTokenPattern = regexp.MustCompile(
+	`"(?:[^"]|"")*"|'(?:[^']|'')*'|\b\d+(?:\.\d+)?(?:[Ee][+-]?\d+)?\b`)
+
+type tracingSpanKey struct{}
+
+// bun.QueryHook creating a client span for each query, bun
+// interpolates the values so literals of the statement are
+// replaced by ? to keep the document values out of the traces
+type tracingQueryHook struct{}
+
+func (tqh *tracingQueryHook) BeforeQuery(ctx context.Context, event *bun.QueryEvent) context.Context {
+	operation := event.Operation()
+
+	statement := tqh.getStatement(event.Query)
+
+	if len(statement) > TRACING_MAX_STATEMENT_LENGTH {
+		statement = statement[:TRACING_MAX_STATEMENT_LENGTH]
+	}
+
+	attributes := []attribute.KeyValue{
+		attribute.String("db.system", TRACING_DB_SYSTEM),
+		attribute.String("db.operation.name", operation),
+		attribute.String("db.statement", statement),
+	}
+
+	ctx, span := otel.Tracer(go_cake.TRACER_NAME).Start(
+		ctx,
+		"postgres."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...))
+
+	if event.Stash == nil {
+		event.Stash = make(map[any]any)
+	}
+
+	event.Stash[tracingSpanKey{}] = span
+
+	return ctx
+}
+
+func (tqh *tracingQueryHook) AfterQuery(ctx context.Context, event *bun.QueryEvent) {
+	span, ok := event.Stash[tracingSpanKey{}].(trace.Span)
+
+	if !ok {
+		return
+	}
+
+	if event.Err != nil && !errors.Is(event.Err, sql.ErrNoRows) {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Err.Error())
+	}
+
+	span.End()
+}
+
+func (tqh *tracingQueryHook) getStatement(query string) string {
+	return tracingStatementTokenPattern.ReplaceAllStringFunc(query, func(token string) string {
+		if strings.HasPrefix(token, `"`) {
+			return token
+		}
+
+		return "?"
+	})
+}
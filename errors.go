@@ -19,6 +19,8 @@ type UnableToInitDatabaseDriverError struct{ BaseError }
 type NoSchemaConfigError struct{ BaseError }
 type NoSchemaConfigIDError struct{ BaseError }
 type SchemaConfigUnknownFieldError struct{ BaseError }
+type HandlerAlreadyStartedError struct{ BaseError }
+type HandlerShutDownError struct{ BaseError }
 
 func NewNoResourceDatabaseDriverSetError(resource *Resource, internalError error) error {
 	e := NoResourceDatabaseDriverSetError{}
@@ -174,4 +176,20 @@ func NewUnableToTestModelError(
 	return e
 }
 
+func NewHandlerAlreadyStartedError(internalError error) error {
+	e := HandlerAlreadyStartedError{}
+
+	e.Message = e.FormatStatusMessage("Handler already started", e, internalError)
+
+	return e
+}
+
+func NewHandlerShutDownError(internalError error) error {
+	e := HandlerShutDownError{}
+
+	e.Message = e.FormatStatusMessage("Handler already shut down", e, internalError)
+
+	return e
+}
+
 // TODO add messages to each error
@@ -0,0 +1,107 @@
+package go_cake
+
+import "sync"
+
+const EVENT_BROKER_DEFAULT_HISTORY_SIZE = 1000
+const EVENT_BROKER_SUBSCRIBER_BUFFER = 64
+
+// Fans out ResourceEvent to the subscribers of <collection>/_events
+// and keeps the last events for Last-Event-ID resume. Events
+// are published by adding the broker to Resource.AddEventListener()
+// or by an external source like a change stream
+type EventBroker struct {
+	historySize int
+	history     []*ResourceEvent
+	subscribers map[chan *ResourceEvent]struct{}
+	closed      bool
+	mutex       sync.Mutex
+}
+
+func NewEventBroker(historySize int) *EventBroker {
+	if historySize <= 0 {
+		historySize = EVENT_BROKER_DEFAULT_HISTORY_SIZE
+	}
+
+	return &EventBroker{
+		historySize: historySize,
+		subscribers: make(map[chan *ResourceEvent]struct{}),
+	}
+}
+
+// Slow subscribers are dropped instead of blocking the request,
+// the channel is closed so they can reconnect with Last-Event-ID
+func (eb *EventBroker) OnResourceEvent(event *ResourceEvent) {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+
+	eb.history = append(eb.history, event)
+
+	if len(eb.history) > eb.historySize {
+		eb.history = eb.history[len(eb.history)-eb.historySize:]
+	}
+
+	for subscriber := range eb.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			delete(eb.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// Returns the events published after lastEventID and the channel
+// of the next events, found is false if lastEventID is no longer
+// in the history
+func (eb *EventBroker) Subscribe(lastEventID string) (missed []*ResourceEvent, events chan *ResourceEvent, found bool) {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+
+	found = lastEventID == ""
+
+	if !found {
+		for i := len(eb.history) - 1; i >= 0; i-- {
+			if eb.history[i].ID == lastEventID {
+				missed = append(missed, eb.history[i+1:]...)
+				found = true
+				break
+			}
+		}
+	}
+
+	events = make(chan *ResourceEvent, EVENT_BROKER_SUBSCRIBER_BUFFER)
+
+	if eb.closed {
+		close(events)
+
+		return missed, events, found
+	}
+
+	eb.subscribers[events] = struct{}{}
+
+	return missed, events, found
+}
+
+func (eb *EventBroker) Unsubscribe(events chan *ResourceEvent) {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+
+	if _, ok := eb.subscribers[events]; ok {
+		delete(eb.subscribers, events)
+		close(events)
+	}
+}
+
+// Ends the streams of all the subscribers, new subscribers
+// get closed channels, the events are still kept in the history
+func (eb *EventBroker) Close() {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+
+	eb.closed = true
+
+	for subscriber := range eb.subscribers {
+		delete(eb.subscribers, subscriber)
+		close(subscriber)
+	}
+}
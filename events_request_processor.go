@@ -0,0 +1,308 @@
+package go_cake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"time"
+)
+
+// Streams ResourceEvent of the collection as Server-Sent Events,
+// GET <collection>/_events, events are filtered by where and
+// the server filter, where is not applied to hard deleted
+// documents, resumed from Last-Event-ID header
+type EventsRequestProcessor struct {
+	BaseRequestProcessor
+}
+
+func NewEventsRequestProcessor(request *Request, resource *Resource) *EventsRequestProcessor {
+	var eventsRequestProcessor EventsRequestProcessor
+
+	eventsRequestProcessor.request = request
+	eventsRequestProcessor.resource = resource
+	eventsRequestProcessor.subRequestProcessor = &eventsRequestProcessor
+
+	return &eventsRequestProcessor
+}
+
+func (erp *EventsRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoCakeModel, HTTPError) {
+	if !erp.request.IsGet || erp.request.IsHead || !erp.resource.GetAllowed {
+		return nil, NewMethodNotAllowedHTTPError(nil)
+	}
+
+	if erp.request.HasSort() ||
+		erp.request.HasSearch() ||
+		erp.request.HasGeo() ||
+		erp.request.HasPage() ||
+		erp.request.DocumentVersion > 0 {
+		return nil, NewModifiersNotAllowedHTTPError(nil)
+	}
+
+	if erp.request.Request == nil || erp.request.ResponseWriter == nil {
+		return nil, NewInternalServerErrorHTTPError(errors.New("no HTTP request to stream to"))
+	}
+
+	lastEventID := erp.request.Request.Header.Get("Last-Event-ID")
+
+	missed, events, found := erp.resource.EventBroker.Subscribe(lastEventID)
+	defer erp.resource.EventBroker.Unsubscribe(events)
+
+	writer := erp.request.ResponseWriter
+	controller := http.NewResponseController(writer)
+
+	// the stream outlives the server write timeout
+	controller.SetWriteDeadline(time.Time{})
+
+	writer.Header().Set("X-GO-KATE-REQUEST-UNIQUE-ID", erp.request.UniqueID)
+	writer.Header().Set("Content-Type", EVENTS_CONTENT_TYPE)
+	writer.Header().Set("Cache-Control", RESPONSE_CACHE_CONTROL)
+	writer.Header().Set("X-Accel-Buffering", "no")
+	writer.WriteHeader(http.StatusOK)
+
+	// the response is already sent
+	erp.request.ResponseWriter = nil
+
+	if !found {
+		// events since lastEventID are lost, the client
+		// should reload the collection
+		fmt.Fprintf(writer, "event: %v\ndata: {}\n\n", EVENTS_RESET_TYPE)
+	}
+
+	if err := controller.Flush(); err != nil {
+		return nil, nil
+	}
+
+	for _, event := range missed {
+		if err := erp.sendEvent(writer, controller, event, response); err != nil {
+			return nil, nil
+		}
+	}
+
+	keepAlive := time.NewTicker(EVENTS_KEEPALIVE_INTERVAL)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-erp.request.Request.Context().Done():
+			return nil, nil
+		case event, ok := <-events:
+			if !ok {
+				// too slow, the client will reconnect
+				return nil, nil
+			}
+
+			if err := erp.sendEvent(writer, controller, event, response); err != nil {
+				return nil, nil
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(writer, ": keepalive\n\n"); err != nil {
+				return nil, nil
+			}
+
+			if err := controller.Flush(); err != nil {
+				return nil, nil
+			}
+		}
+	}
+}
+
+func (erp *EventsRequestProcessor) sendEvent(
+	writer http.ResponseWriter,
+	controller *http.ResponseController,
+	event *ResourceEvent,
+	response *ResponseJSON) error {
+	filtered, httpErr := erp.filterEvent(event, response)
+
+	if httpErr != nil {
+		return httpErr
+	}
+
+	if filtered == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(filtered)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprintf(writer, "id: %v\nevent: %v\ndata: %s\n\n", filtered.ID, filtered.Type, data); err != nil {
+		return err
+	}
+
+	return controller.Flush()
+}
+
+// Copy of the event with the documents visible for the request
+// and the projection applied, nil if none
+func (erp *EventsRequestProcessor) filterEvent(event *ResourceEvent, response *ResponseJSON) (*ResourceEvent, HTTPError) {
+	if event.Tenant != erp.request.Tenant {
+		return nil, nil
+	}
+
+	visible, httpErr := erp.getVisibleDocuments(event, response)
+
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	eventResponse := NewResponseJSON()
+
+	for i, document := range event.Documents {
+		if visible[i] {
+			eventResponse.Items = append(eventResponse.Items, maps.Clone(document))
+		}
+	}
+
+	if len(eventResponse.Items) == 0 {
+		return nil, nil
+	}
+
+	erp.postRequestResponseActions(eventResponse)
+
+	filtered := *event
+	filtered.Documents = eventResponse.Items
+
+	return &filtered, nil
+}
+
+// Without where the server filter is checked on the documents
+// of the event (with their hidden fields), documents without
+// its fields and all of them with where are checked by the
+// driver in one query so where has the same meaning as for GET,
+// hard deleted documents no longer exist so where is not applied
+// to them, the streams with where get every hard deleted document
+// matching the server filter, the ones without its fields (like
+// the events of other nodes with hidden fields) are not sent
+func (erp *EventsRequestProcessor) getVisibleDocuments(
+	event *ResourceEvent,
+	response *ResponseJSON) ([]bool, HTTPError) {
+	idField := erp.resource.JSONSchemaConfig.IDField
+	serverFilter := maps.Clone(erp.serverFilter)
+	documents := event.Documents
+	hardDeleted := event.Type == EVENT_DELETED && !erp.resource.IsSoftDelete()
+
+	if serverFilter == nil {
+		serverFilter = make(map[string]any)
+	}
+
+	if event.Type == EVENT_DELETED {
+		delete(serverFilter, erp.resource.SoftDeleteField)
+	}
+
+	visible := make([]bool, len(documents))
+	uncheckedIDs := make([]any, 0)
+
+	for i, document := range documents {
+		if document[idField] == nil {
+			continue
+		}
+
+		filterDocument := event.getServerFilterDocument(i)
+
+		if hardDeleted || (!erp.request.HasWhere() && erp.hasServerFilterFields(filterDocument, serverFilter)) {
+			visible[i] = erp.matchesServerFilter(filterDocument, serverFilter)
+			continue
+		}
+
+		uncheckedIDs = append(uncheckedIDs, document[idField])
+	}
+
+	if len(uncheckedIDs) == 0 {
+		return visible, nil
+	}
+
+	foundIDs, httpErr := erp.findDocumentIDs(uncheckedIDs, serverFilter, response)
+
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	for i, document := range documents {
+		if document[idField] != nil && foundIDs[fmt.Sprint(document[idField])] {
+			visible[i] = true
+		}
+	}
+
+	return visible, nil
+}
+
+// Fields of NotEqualFilter can be missing, like in the database
+func (erp *EventsRequestProcessor) hasServerFilterFields(
+	document map[string]any,
+	serverFilter map[string]any) bool {
+	for iJsonField, value := range serverFilter {
+		if _, isNotEqual := value.(NotEqualFilter); isNotEqual {
+			continue
+		}
+
+		if _, exists := document[iJsonField]; !exists {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (erp *EventsRequestProcessor) matchesServerFilter(
+	document map[string]any,
+	serverFilter map[string]any) bool {
+	for iJsonField, value := range serverFilter {
+		if !erp.serverFilterValueMatches(document[iJsonField], value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (erp *EventsRequestProcessor) findDocumentIDs(
+	ids []any,
+	serverFilter map[string]any,
+	response *ResponseJSON) (map[string]bool, HTTPError) {
+	idField := erp.resource.JSONSchemaConfig.IDField
+
+	serverFilter = maps.Clone(serverFilter)
+	serverFilter[idField] = InFilter{Values: ids}
+
+	ctx, cancel := erp.resource.ResourceCallback.CreateContext(
+		erp.resource,
+		erp.request,
+		response,
+		ctxDbDriverFind)
+	defer cancel()
+
+	documents, httpErr := erp.driver.Find(
+		erp.resource.DbModel,
+		erp.dbPath,
+		erp.request.Where,
+		"",
+		nil,
+		nil,
+		serverFilter,
+		0,
+		int64(len(ids)),
+		ctx,
+		erp.request.UserData)
+
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	foundIDs := make(map[string]bool, len(documents))
+
+	for _, document := range documents {
+		jsonObject, err := document.ToMap()
+
+		if err != nil {
+			return nil, NewLowLevelDriverHTTPError(err)
+		}
+
+		foundIDs[fmt.Sprint(jsonObject[idField])] = true
+	}
+
+	return foundIDs, nil
+}
@@ -0,0 +1,68 @@
+package go_cake
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestEventsHardDeletedDocumentsMatchHiddenServerFilterFields(t *testing.T) {
+	_, resource := newTestHandler(t, newTestDriver())
+
+	resource.JSONSchemaConfig.HiddenFields = []string{"name"}
+
+	event := NewResourceEvent(resource, EVENT_DELETED, "", "", []map[string]any{
+		{"id": "1", "name": "mine"},
+		{"id": "2", "name": "other"},
+	})
+
+	if _, exists := event.Documents[0]["name"]; exists {
+		t.Fatal("hidden field sent in the event")
+	}
+
+	// the hidden fields are not sent to the other nodes
+	data, _ := json.Marshal(event)
+	var remoteEvent ResourceEvent
+	json.Unmarshal(data, &remoteEvent)
+
+	tests := []struct {
+		name  string
+		where string
+		event *ResourceEvent
+		want  []bool
+	}{
+		{
+			name:  "server filter",
+			event: event,
+			want:  []bool{true, false},
+		},
+		{
+			name:  "where not applied",
+			where: `{"id":"2"}`,
+			event: event,
+			want:  []bool{true, false},
+		},
+		{
+			name:  "without hidden fields",
+			event: &remoteEvent,
+			want:  []bool{false, false},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			erp := NewEventsRequestProcessor(&Request{Where: test.where}, resource)
+			erp.serverFilter = map[string]any{"name": "mine"}
+
+			visible, httpErr := erp.getVisibleDocuments(test.event, nil)
+
+			if httpErr != nil {
+				t.Fatal(httpErr)
+			}
+
+			if !slices.Equal(visible, test.want) {
+				t.Errorf("visible = %v, want %v", visible, test.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	go_cake "github.com/skazanyNaGlany/go-cake"
 	driver "github.com/skazanyNaGlany/go-cake/driver/mongo"
 	models "github.com/skazanyNaGlany/go-cake/examples/mongo/models"
+	middleware "github.com/skazanyNaGlany/go-cake/middleware"
 )
 
 func checkAuth(
@@ -162,6 +163,7 @@ func main() {
 	defer dbDriver.Close()
 
 	restHandler := go_cake.NewHandler()
+	restHandler.Use(middleware.CompressionMiddleware)
 
 	ordersValidator, err := go_cake.NewDefaultJSONValidator("orders.json", `{
 		"$schema": "http://json-schema.org/draft-04/schema#",
@@ -413,6 +415,7 @@ func main() {
 	devicesResource.JSONSchemaConfig.DeleteValidator = devicesValidator
 	devicesResource.JSONSchemaConfig.InsertValidator = devicesValidator
 	devicesResource.JSONSchemaConfig.UpdateValidator = devicesValidator
+	devicesResource.JSONSchemaConfig.GeoFields = []string{"location"}
 	devicesResource.ResourceCallback.PreRequestCallback = preRequest
 	devicesResource.ResourceCallback.PostRequestCallback = postRequest
 	devicesResource.ResourceCallback.FetchedDocuments = fetchedDocuments
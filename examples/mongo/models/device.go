@@ -8,9 +8,10 @@ import (
 type Device struct {
 	go_cake.BaseGoCakeModel `json:"-" bson:"-"`
 
-	ID          *primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	Email       *string             `json:"email,omitempty" bson:"email,omitempty"`
-	MaxContacts *uint64             `json:"max_contacts,omitempty" bson:"max_contacts,omitempty"`
+	ID          *primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	Email       *string               `json:"email,omitempty" bson:"email,omitempty"`
+	MaxContacts *uint64               `json:"max_contacts,omitempty" bson:"max_contacts,omitempty"`
+	Location    *go_cake.GeoJSONPoint `json:"location,omitempty" bson:"location,omitempty"`
 }
 
 func (d *Device) CreateInstance() go_cake.GoCakeModel {
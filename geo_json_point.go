@@ -0,0 +1,97 @@
+package go_cake
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const GEO_JSON_POINT_TYPE = "Point"
+const GEO_SRID_WGS84 = 4326
+
+// GeoJSON point usable as a model field, stored as GeoJSON
+// object by MongoDB and as PostGIS geometry(Point, 4326)
+// by PostgreSQL
+type GeoJSONPoint struct {
+	Type        string    `json:"type" bson:"type"`
+	Coordinates []float64 `json:"coordinates" bson:"coordinates"`
+}
+
+func NewGeoJSONPoint(longitude, latitude float64) *GeoJSONPoint {
+	return &GeoJSONPoint{
+		Type:        GEO_JSON_POINT_TYPE,
+		Coordinates: []float64{longitude, latitude},
+	}
+}
+
+// Returns EWKT which is accepted by PostGIS geometry input
+func (p GeoJSONPoint) Value() (driver.Value, error) {
+	if len(p.Coordinates) != 2 {
+		return nil, errors.New("GeoJSONPoint: coordinates must be [longitude, latitude]")
+	}
+
+	return fmt.Sprintf(
+		"SRID=%d;POINT(%v %v)",
+		GEO_SRID_WGS84,
+		p.Coordinates[0],
+		p.Coordinates[1]), nil
+}
+
+// Decodes (E)WKB hex string returned by PostGIS
+func (p *GeoJSONPoint) Scan(src any) error {
+	var encoded string
+
+	switch value := src.(type) {
+	case nil:
+		return nil
+	case string:
+		encoded = value
+	case []byte:
+		encoded = string(value)
+	default:
+		return fmt.Errorf("GeoJSONPoint: unsupported source type %T", src)
+	}
+
+	wkb, err := hex.DecodeString(encoded)
+
+	if err != nil {
+		return err
+	}
+
+	if len(wkb) < 5 {
+		return errors.New("GeoJSONPoint: WKB too short")
+	}
+
+	var byteOrder binary.ByteOrder = binary.BigEndian
+
+	if wkb[0] == 1 {
+		byteOrder = binary.LittleEndian
+	}
+
+	geometryType := byteOrder.Uint32(wkb[1:5])
+	offset := 5
+
+	if geometryType&0x20000000 != 0 {
+		// SRID present
+		offset += 4
+	}
+
+	if geometryType&0xFFFF != 1 {
+		return fmt.Errorf("GeoJSONPoint: geometry type %d is not a point", geometryType&0xFFFF)
+	}
+
+	if len(wkb) < offset+16 {
+		return errors.New("GeoJSONPoint: WKB too short")
+	}
+
+	p.Type = GEO_JSON_POINT_TYPE
+	p.Coordinates = []float64{
+		math.Float64frombits(byteOrder.Uint64(wkb[offset : offset+8])),
+		math.Float64frombits(byteOrder.Uint64(wkb[offset+8 : offset+16])),
+	}
+
+	return nil
+}
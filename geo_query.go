@@ -0,0 +1,145 @@
+package go_cake
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Geospatial filter passed to the DatabaseDriver, parsed from
+// the "geo" URL parameter, for example:
+// {"field": "location", "near": [18.64, 54.35], "max_distance": 1000}
+// {"field": "location", "within": {"box": [[18.5, 54.3], [18.7, 54.4]]}}
+// {"field": "location", "within": {"polygon": [[18.5, 54.3], [18.7, 54.3], [18.6, 54.4]]}}
+// Coordinates are [longitude, latitude], distances are in meters
+type GeoQuery struct {
+	Field       string     `json:"field"`
+	Near        []float64  `json:"near"`
+	MaxDistance float64    `json:"max_distance"`
+	Within      *GeoWithin `json:"within"`
+}
+
+type GeoWithin struct {
+	Box     [][]float64 `json:"box"`
+	Polygon [][]float64 `json:"polygon"`
+}
+
+func (gq *GeoQuery) HasNear() bool {
+	return len(gq.Near) > 0
+}
+
+func (gq *GeoQuery) HasWithin() bool {
+	return gq.Within != nil
+}
+
+func (gq *GeoQuery) Validate() error {
+	if gq.Field == "" {
+		return errors.New("no field set")
+	}
+
+	if !gq.HasNear() && !gq.HasWithin() {
+		return errors.New("near or within must be set")
+	}
+
+	if gq.MaxDistance < 0 {
+		return errors.New("max_distance cannot be negative")
+	}
+
+	if gq.MaxDistance > 0 && !gq.HasNear() {
+		return errors.New("max_distance requires near")
+	}
+
+	if gq.HasNear() {
+		if err := gq.validatePosition(gq.Near); err != nil {
+			return fmt.Errorf("near: %v", err)
+		}
+	}
+
+	if gq.HasWithin() {
+		return gq.validateWithin()
+	}
+
+	return nil
+}
+
+func (gq *GeoQuery) validateWithin() error {
+	hasBox := len(gq.Within.Box) > 0
+	hasPolygon := len(gq.Within.Polygon) > 0
+
+	if hasBox == hasPolygon {
+		return errors.New("within requires exactly one of box or polygon")
+	}
+
+	if hasBox {
+		if len(gq.Within.Box) != 2 {
+			return errors.New("box requires two positions (bottom left and upper right)")
+		}
+
+		for _, position := range gq.Within.Box {
+			if err := gq.validatePosition(position); err != nil {
+				return fmt.Errorf("box: %v", err)
+			}
+		}
+
+		return nil
+	}
+
+	if len(gq.Within.Polygon) < 3 {
+		return errors.New("polygon requires at least three positions")
+	}
+
+	for _, position := range gq.Within.Polygon {
+		if err := gq.validatePosition(position); err != nil {
+			return fmt.Errorf("polygon: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (gq *GeoQuery) validatePosition(position []float64) error {
+	if len(position) != 2 {
+		return fmt.Errorf("position %v must be [longitude, latitude]", position)
+	}
+
+	if position[0] < -180 || position[0] > 180 {
+		return fmt.Errorf("longitude %v out of range", position[0])
+	}
+
+	if position[1] < -90 || position[1] > 90 {
+		return fmt.Errorf("latitude %v out of range", position[1])
+	}
+
+	return nil
+}
+
+// Returns closed polygon ring for "within" filter, the box
+// is converted to the polygon as well
+func (gq *GeoQuery) GetWithinRing() [][]float64 {
+	if !gq.HasWithin() {
+		return nil
+	}
+
+	if len(gq.Within.Box) == 2 {
+		minLng, minLat := gq.Within.Box[0][0], gq.Within.Box[0][1]
+		maxLng, maxLat := gq.Within.Box[1][0], gq.Within.Box[1][1]
+
+		return [][]float64{
+			{minLng, minLat},
+			{maxLng, minLat},
+			{maxLng, maxLat},
+			{minLng, maxLat},
+			{minLng, minLat},
+		}
+	}
+
+	ring := append([][]float64{}, gq.Within.Polygon...)
+
+	first := ring[0]
+	last := ring[len(ring)-1]
+
+	if first[0] != last[0] || first[1] != last[1] {
+		ring = append(ring, first)
+	}
+
+	return ring
+}
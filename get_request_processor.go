@@ -1,5 +1,7 @@
 package go_cake
 
+import "context"
+
 type GetRequestProcessor struct {
 	BaseRequestProcessor
 }
@@ -24,6 +26,11 @@ func (grp *GetRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoCake
 
 	grp.initPagination(response)
 
+	if grp.request.DocumentVersion > 0 &&
+		(!grp.request.HasItemID() || grp.resource.HistoryStore == nil) {
+		return nil, NewModifiersNotAllowedHTTPError(nil)
+	}
+
 	if httpErr = grp.checkRanges(); httpErr != nil {
 		return nil, httpErr
 	}
@@ -36,6 +43,17 @@ func (grp *GetRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoCake
 		return nil, nil
 	}
 
+	if grp.request.IsHead && !grp.request.HasItemID() {
+		// only totals and headers for HEAD
+		return nil, nil
+	}
+
+	serverFilter, httpErr := grp.createServerFilter()
+
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
 	ctx, cancel := grp.resource.ResourceCallback.CreateContext(
 		grp.resource,
 		grp.request,
@@ -43,14 +61,18 @@ func (grp *GetRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoCake
 		ctxDbDriverFind)
 	defer cancel()
 
-	documents, httpErr = grp.resource.DatabaseDriver.Find(
+	documents, httpErr = grp.driver.Find(
 		grp.resource.DbModel,
+		grp.dbPath,
 		grp.request.Where,
 		grp.request.Sort,
+		grp.createSearchQuery(),
+		grp.request.Geo,
+		serverFilter,
 		grp.request.Page,
 		grp.request.PerPage,
 		ctx,
-		nil)
+		grp.request.UserData)
 
 	httpErr = grp.callFetchedDocumentsHandlers(documents, httpErr)
 
@@ -58,9 +80,38 @@ func (grp *GetRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoCake
 		return nil, httpErr
 	}
 
+	if grp.request.HasItemID() && len(documents) == 0 {
+		return nil, NewObjectNotFoundHTTPError(nil)
+	}
+
+	if grp.request.DocumentVersion > 0 {
+		return grp.findDocumentVersion(ctx)
+	}
+
 	return documents, nil
 }
 
+// Point-in-time read of the item, ?version=N
+func (grp *GetRequestProcessor) findDocumentVersion(ctx context.Context) ([]GoCakeModel, HTTPError) {
+	version, err := grp.resource.HistoryStore.GetVersion(
+		grp.resource,
+		grp.driver,
+		grp.dbPath,
+		grp.request.ItemID,
+		grp.request.DocumentVersion,
+		ctx)
+
+	if err != nil {
+		return nil, NewLowLevelDriverHTTPError(err)
+	}
+
+	if version == nil {
+		return nil, NewObjectNotFoundHTTPError(nil)
+	}
+
+	return []GoCakeModel{grp.versionToDocument(version)}, nil
+}
+
 func (grp *GetRequestProcessor) preRequestModelActions() HTTPError {
 	// no actions for get
 	return nil
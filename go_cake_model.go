@@ -10,4 +10,6 @@ type GoCakeModel interface {
 	GetETag() any
 	SetHTTPError(httpError HTTPError)
 	GetHTTPError() HTTPError
+	SetMetaField(name string, value any)
+	GetMetaFields() map[string]any
 }
@@ -1,19 +1,41 @@
 package go_cake
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var knownActions = []string{ACTION_RESTORE, ACTION_VERSIONS, ACTION_REVERT, ACTION_EVENTS}
+
 type Handler struct {
-	NotFoundHandler http.Handler
-	resources       map[string]*Resource
-	middlewares     []MiddlewareCallback
+	NotFoundHandler   http.Handler
+	HealthCheckConfig *HealthCheckConfig // serves liveness and readiness endpoints
+	resources         map[string]*Resource
+	middlewares       []MiddlewareCallback
+	observers         []RequestObserver
+	services          []HandlerService
+	started           bool
+	shuttingDown      bool
+	lifecycleMutex    sync.RWMutex
+	baseContext       context.Context // parent of the driver contexts
+	cancelBaseContext context.CancelFunc
 }
 
 func NewHandler() *Handler {
 	handler := Handler{}
 	handler.resources = make(map[string]*Resource)
+	handler.baseContext, handler.cancelBaseContext = context.WithCancel(context.Background())
 
 	return &handler
 }
@@ -22,11 +44,40 @@ func (rh *Handler) Use(mwf ...MiddlewareCallback) {
 	rh.middlewares = append(rh.middlewares, mwf...)
 }
 
+func (rh *Handler) AddRequestObserver(observer RequestObserver) {
+	rh.observers = append(rh.observers, observer)
+}
+
+func (rh *Handler) observeRequest(
+	request *Request,
+	resource *Resource,
+	response *ResponseJSON,
+	timeStart time.Time) {
+	if len(rh.observers) == 0 {
+		return
+	}
+
+	stats := RequestStats{
+		Resource:    resource,
+		Request:     request,
+		StatusCode:  response.Meta.StatusCode,
+		Duration:    time.Since(timeStart),
+		InputItems:  len(request.DecodedJsonSlice),
+		OutputItems: len(response.Items),
+	}
+
+	for _, observer := range rh.observers {
+		observer.ObserveRequest(&stats)
+	}
+}
+
 func (rh *Handler) processRequest(
 	request *Request,
 	resource *Resource,
 	response *ResponseJSON) {
-	if request.IsGet {
+	if request.HasAction() {
+		rh.processActionRequest(request, resource, response)
+	} else if request.IsGet {
 		processor := NewGetRequestProcessor(request, resource)
 
 		processor.BaseRequestProcessor.ProcessRequest(response)
@@ -54,24 +105,88 @@ func (rh *Handler) processRequest(
 	}
 }
 
-func (rh *Handler) writeResponse(response *ResponseJSON, httpWriter http.ResponseWriter) {
+func (rh *Handler) processActionRequest(
+	request *Request,
+	resource *Resource,
+	response *ResponseJSON) {
+	if request.IsCORS {
+		processor := NewCORSRequestProcessor(request, resource)
+
+		processor.BaseRequestProcessor.ProcessRequest(response)
+	} else if request.Action == ACTION_RESTORE && resource.SoftDeleteField != "" {
+		processor := NewRestoreRequestProcessor(request, resource)
+
+		processor.BaseRequestProcessor.ProcessRequest(response)
+	} else if request.Action == ACTION_VERSIONS && resource.HistoryStore != nil && request.HasItemID() {
+		processor := NewVersionsRequestProcessor(request, resource)
+
+		processor.BaseRequestProcessor.ProcessRequest(response)
+	} else if request.Action == ACTION_REVERT && resource.HistoryStore != nil {
+		processor := NewRevertRequestProcessor(request, resource)
+
+		processor.BaseRequestProcessor.ProcessRequest(response)
+	} else if request.Action == ACTION_EVENTS && resource.EventBroker != nil && !request.HasItemID() {
+		processor := NewEventsRequestProcessor(request, resource)
+
+		processor.BaseRequestProcessor.ProcessRequest(response)
+	} else {
+		httpErr := NewURLNotFoundHTTPError(nil)
+
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+	}
+}
+
+func (rh *Handler) writeResponse(
+	response *ResponseJSON,
+	httpWriter http.ResponseWriter,
+	withBody bool) {
 	jsonText, _ := json.Marshal(response)
 
 	httpWriter.Header().Set("X-GO-KATE-REQUEST-UNIQUE-ID", response.Meta.RequestUniqueID)
 	httpWriter.Header().Set("X-GO-KATE-VERSION", response.Meta.Version)
 	httpWriter.Header().Set("Content-Type", RESPONSE_CONTENT_TYPE)
-	httpWriter.Header().Set("Cache-Control", RESPONSE_CACHE_CONTROL)
+
+	if httpWriter.Header().Get("Cache-Control") == "" {
+		// not set by the CacheControl of the resource
+		httpWriter.Header().Set("Cache-Control", RESPONSE_CACHE_CONTROL)
+	}
 
 	httpWriter.WriteHeader(int(response.Meta.StatusCode))
-	httpWriter.Write(jsonText)
+
+	if withBody {
+		httpWriter.Write(jsonText)
+	}
+}
+
+func (rh *Handler) writePaginationHeaders(
+	request *Request,
+	response *ResponseJSON,
+	httpWriter http.ResponseWriter) {
+	if !request.IsGet || request.HasItemID() || response.Meta.StatusCode != http.StatusOK {
+		return
+	}
+
+	httpWriter.Header().Set("X-Total-Count", strconv.FormatUint(response.Meta.Total, 10))
+
+	links := newResponsePaginationLinks(request, response)
+
+	if links == nil {
+		return
+	}
+
+	if linkHeader := links.ToLinkHeader(); linkHeader != "" {
+		httpWriter.Header().Set("Link", linkHeader)
+	}
 }
 
 func (rh *Handler) mainResourceHandler(httpWriter http.ResponseWriter, httpRequest *http.Request) {
 	var httpErr HTTPError
 
+	timeStart := time.Now()
 	response := NewResponseJSON()
 
-	resource := rh.FindMatchedResource(httpRequest)
+	resource, collectionPath, itemID, action := rh.findMatchedResourceAndItem(httpRequest.URL.Path)
 
 	if resource == nil {
 		if rh.NotFoundHandler != nil {
@@ -85,30 +200,127 @@ func (rh *Handler) mainResourceHandler(httpWriter http.ResponseWriter, httpReque
 		response.Meta.StatusMessage = httpErr.GetStatusMessage()
 		response.Meta.StatusCode = httpErr.GetStatusCode()
 
-		rh.writeResponse(response, httpWriter)
+		rh.writeResponse(response, httpWriter, httpRequest.Method != HTTP_REQUEST_HEAD_METHOD)
 		return
 	}
 
+	if !rh.beginRequest(resource) {
+		httpErr = NewServiceUnavailableHTTPError(nil)
+
+		response.Meta.StatusMessage = httpErr.GetStatusMessage()
+		response.Meta.StatusCode = httpErr.GetStatusCode()
+
+		httpWriter.Header().Set("Connection", "close")
+
+		rh.writeResponse(response, httpWriter, httpRequest.Method != HTTP_REQUEST_HEAD_METHOD)
+		return
+	}
+
+	defer rh.endRequest(resource)
+
 	request := Request{
 		ResourcePattern: resource.CompiledPattern,
 		Resource:        resource.ResourceName,
+		CollectionPath:  collectionPath,
+		ItemID:          itemID,
+		Action:          action,
 		Method:          httpRequest.Method,
 		Request:         httpRequest,
 		ResponseWriter:  httpWriter,
 	}
 
-	if httpErr = request.Parse(httpRequest); httpErr != nil {
+	if httpErr = rh.parseRequest(&request, httpRequest); httpErr != nil {
 		response.Meta.StatusMessage = httpErr.GetStatusMessage()
 		response.Meta.StatusCode = httpErr.GetStatusCode()
 
-		rh.writeResponse(response, request.ResponseWriter)
+		rh.traceResponse(&request, response)
+		rh.observeRequest(&request, resource, response, timeStart)
+		rh.writeResponse(response, request.ResponseWriter, !request.IsHead)
 		return
 	}
 
+	cancelDeadline := rh.applyDeadline(&request, resource, timeStart)
+	defer cancelDeadline()
+
 	rh.processRequest(&request, resource, response)
+	rh.checkDeadline(&request, response)
+	rh.traceResponse(&request, response)
+	rh.observeRequest(&request, resource, response, timeStart)
 
 	if request.ResponseWriter != nil {
-		rh.writeResponse(response, request.ResponseWriter)
+		rh.writePaginationHeaders(&request, response, request.ResponseWriter)
+		rh.writeResponse(response, request.ResponseWriter, !request.IsHead)
+	}
+}
+
+// Limits the request context by the timeout of the operation,
+// the client can shorten it by REQUEST_TIMEOUT_HEADER, event
+// streams are not limited
+func (rh *Handler) applyDeadline(request *Request, resource *Resource, timeStart time.Time) context.CancelFunc {
+	if request.Action == ACTION_EVENTS {
+		return func() {}
+	}
+
+	timeout := resource.GetTimeout(request.Operation())
+
+	if request.ClientTimeout > 0 && request.ClientTimeout < timeout {
+		timeout = request.ClientTimeout
+	}
+
+	request.Deadline = timeStart.Add(timeout)
+
+	ctx, cancel := context.WithDeadline(request.TraceContext(), request.Deadline)
+
+	request.traceContext = ctx
+
+	return cancel
+}
+
+// Server errors caused by the exceeded deadline are reported
+// as 504, successful changes are kept as they are
+func (rh *Handler) checkDeadline(request *Request, response *ResponseJSON) {
+	if request.Deadline.IsZero() ||
+		response.Meta.StatusCode < http.StatusInternalServerError ||
+		time.Now().Before(request.Deadline) {
+		return
+	}
+
+	httpErr := NewGatewayTimeoutHTTPError(nil)
+
+	response.Meta.StatusMessage = httpErr.GetStatusMessage()
+	response.Meta.StatusCode = httpErr.GetStatusCode()
+}
+
+func (rh *Handler) parseRequest(request *Request, httpRequest *http.Request) HTTPError {
+	ctx, span := startSpan(httpRequest.Context(), "Request.Parse")
+
+	httpErr := request.Parse(httpRequest)
+
+	serverSpan := trace.SpanFromContext(httpRequest.Context())
+
+	// the trace and the request are searchable by each other
+	request.TraceID = serverSpan.SpanContext().TraceID().String()
+	request.traceContext = httpRequest.Context()
+
+	serverSpan.SetAttributes(
+		attribute.String(SPAN_ATTRIBUTE_PREFIX+"request_unique_id", request.UniqueID),
+		attribute.String(SPAN_ATTRIBUTE_PREFIX+"resource", request.Resource))
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String(SPAN_ATTRIBUTE_PREFIX+"operation", request.Operation()))
+
+	endSpan(span, httpErr)
+
+	return httpErr
+}
+
+func (rh *Handler) traceResponse(request *Request, response *ResponseJSON) {
+	serverSpan := trace.SpanFromContext(request.TraceContext())
+
+	serverSpan.SetAttributes(attribute.Int("http.response.status_code", response.Meta.StatusCode))
+
+	if response.Meta.StatusCode >= 500 {
+		serverSpan.SetStatus(codes.Error, response.Meta.StatusMessage)
 	}
 }
 
@@ -118,8 +330,30 @@ func (rh *Handler) targetHandler(next http.Handler) http.Handler {
 	})
 }
 
+// Continues the trace of W3C traceparent header if present
 func (rh *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	handlers := rh.middlewares
+	if rh.serveHealthCheck(w, r) {
+		// probes are not traced
+		return
+	}
+
+	ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+	ctx, span := getTracer().Start(
+		ctx,
+		SPAN_ATTRIBUTE_PREFIX+"Handler.ServeHTTP",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path)))
+	defer span.End()
+
+	r = r.WithContext(ctx)
+
+	// the queue is consumed while executing so each request
+	// needs its own copy of the middlewares
+	handlers := make([]MiddlewareCallback, 0, len(rh.middlewares)+1)
+	handlers = append(handlers, rh.middlewares...)
 	handlers = append(handlers, rh.targetHandler)
 
 	queue := MiddlewareQueue{
@@ -132,8 +366,14 @@ func (rh *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (rh *Handler) FindMatchedResource(r *http.Request) *Resource {
+	resource, _, _, _ := rh.findMatchedResourceAndItem(r.URL.Path)
+
+	return resource
+}
+
+func (rh *Handler) findResourceByPath(path string) *Resource {
 	for _, iresource := range rh.resources {
-		if iresource.MatchPattern(r.URL.Path) {
+		if iresource.MatchPattern(path) {
 			return iresource
 		}
 	}
@@ -141,12 +381,102 @@ func (rh *Handler) FindMatchedResource(r *http.Request) *Resource {
 	return nil
 }
 
+// Returns matched resource, its collection path, item ID and
+// action, item URLs are made of the collection path and the ID
+// like /v1/api/items/1, actions are prefixed with ACTION_PREFIX
+// like /v1/api/items/_restore or /v1/api/items/1/_restore
+func (rh *Handler) findMatchedResourceAndItem(path string) (*Resource, string, string, string) {
+	if resource := rh.findResourceByPath(path); resource != nil {
+		return resource, path, "", ""
+	}
+
+	parentPath, lastSegment, found := rh.splitLastSegment(path)
+
+	if !found {
+		return nil, "", "", ""
+	}
+
+	if !rh.isActionSegment(lastSegment) {
+		resource, collectionPath := rh.findCollection(parentPath)
+
+		if resource == nil {
+			return nil, "", "", ""
+		}
+
+		return resource, collectionPath, lastSegment, ""
+	}
+
+	action := strings.TrimPrefix(lastSegment, ACTION_PREFIX)
+
+	if resource, collectionPath := rh.findCollection(parentPath); resource != nil {
+		return resource, collectionPath, "", action
+	}
+
+	collectionPath, itemID, found := rh.splitLastSegment(parentPath)
+
+	if !found {
+		return nil, "", "", ""
+	}
+
+	resource, collectionPath := rh.findCollection(collectionPath)
+
+	if resource == nil {
+		return nil, "", "", ""
+	}
+
+	return resource, collectionPath, itemID, action
+}
+
+// Only the known actions, other segments starting with
+// ACTION_PREFIX are item IDs
+func (rh *Handler) isActionSegment(segment string) bool {
+	action, isPrefixed := strings.CutPrefix(segment, ACTION_PREFIX)
+
+	return isPrefixed && slices.Contains(knownActions, action)
+}
+
+func (rh *Handler) findCollection(collectionPath string) (*Resource, string) {
+	for _, iCollectionPath := range []string{collectionPath, collectionPath + "/"} {
+		if resource := rh.findResourceByPath(iCollectionPath); resource != nil {
+			return resource, iCollectionPath
+		}
+	}
+
+	return nil, ""
+}
+
+func (rh *Handler) splitLastSegment(path string) (string, string, bool) {
+	trimmedPath := strings.TrimSuffix(path, "/")
+	index := strings.LastIndex(trimmedPath, "/")
+
+	if index <= 0 || index == len(trimmedPath)-1 {
+		return "", "", false
+	}
+
+	return trimmedPath[:index], trimmedPath[index+1:], true
+}
+
 func (rh *Handler) AddResource(resource *Resource) error {
 	if _, exists := rh.resources[resource.Pattern]; exists {
 		return &RestHandlerPatternExistsError{}
 	}
 
 	rh.resources[resource.Pattern] = resource
+	resource.baseContext = rh.baseContext
 
 	return nil
 }
+
+func (rh *Handler) getSortedResources() []*Resource {
+	resources := make([]*Resource, 0, len(rh.resources))
+
+	for _, resource := range rh.resources {
+		resources = append(resources, resource)
+	}
+
+	slices.SortFunc(resources, func(a, b *Resource) int {
+		return strings.Compare(a.ResourceName, b.ResourceName)
+	})
+
+	return resources
+}
@@ -0,0 +1,226 @@
+package go_cake
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+)
+
+// Started by Handler.Start() and stopped by Handler.Shutdown()
+// after the requests in progress, like webhook.Dispatcher or
+// mongo_driver.MongoChangeStream
+type HandlerService interface {
+	Start() error
+	Stop()
+}
+
+func (rh *Handler) AddService(service HandlerService) {
+	rh.services = append(rh.services, service)
+}
+
+// Starts the services added by AddService() and the event
+// listeners and audit sinks of the resources implementing
+// HandlerService, the resources are served also without Start()
+func (rh *Handler) Start() error {
+	rh.lifecycleMutex.Lock()
+	defer rh.lifecycleMutex.Unlock()
+
+	if rh.shuttingDown {
+		return NewHandlerShutDownError(nil)
+	}
+
+	if rh.started {
+		return NewHandlerAlreadyStartedError(nil)
+	}
+
+	services := rh.getServices()
+
+	for i, service := range services {
+		if err := service.Start(); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				services[j].Stop()
+			}
+
+			return err
+		}
+	}
+
+	rh.started = true
+
+	return nil
+}
+
+// New requests are rejected with 503, the event streams are ended
+// and the requests in progress are awaited until ctx is done, then
+// the driver contexts are canceled, the services are stopped and
+// distinct sinks and drivers implementing io.Closer are closed once
+func (rh *Handler) Shutdown(ctx context.Context) error {
+	rh.lifecycleMutex.Lock()
+
+	if rh.shuttingDown {
+		rh.lifecycleMutex.Unlock()
+
+		return NewHandlerShutDownError(nil)
+	}
+
+	rh.shuttingDown = true
+
+	rh.lifecycleMutex.Unlock()
+
+	resources := rh.getSortedResources()
+	errs := make([]error, 0)
+
+	for _, resource := range resources {
+		if err := resource.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := rh.waitForRequests(resources, ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	// the remaining requests are aborted
+	rh.cancelBaseContext()
+
+	services := rh.getServices()
+
+	for i := len(services) - 1; i >= 0; i-- {
+		services[i].Stop()
+	}
+
+	for _, closer := range rh.getClosers(resources) {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (rh *Handler) IsShuttingDown() bool {
+	rh.lifecycleMutex.RLock()
+	defer rh.lifecycleMutex.RUnlock()
+
+	return rh.shuttingDown
+}
+
+// Returns false if the Handler is shutting down, otherwise
+// the request must be ended by endRequest()
+func (rh *Handler) beginRequest(resource *Resource) bool {
+	rh.lifecycleMutex.RLock()
+	defer rh.lifecycleMutex.RUnlock()
+
+	if rh.shuttingDown {
+		return false
+	}
+
+	resource.inFlightRequests.Add(1)
+	resource.activeRequests.Add(1)
+
+	return true
+}
+
+func (rh *Handler) endRequest(resource *Resource) {
+	resource.activeRequests.Add(-1)
+	resource.inFlightRequests.Done()
+}
+
+// Waits for the requests in progress, the waiting goroutine is
+// left running when ctx is done and ends with the last request
+func (rh *Handler) waitForRequests(resources []*Resource, ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		for _, resource := range resources {
+			resource.inFlightRequests.Wait()
+		}
+
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (rh *Handler) getServices() []HandlerService {
+	services := slices.Clone(rh.services)
+
+	addService := func(candidate any) {
+		service, ok := candidate.(HandlerService)
+
+		if ok && !containsComparable(services, service) {
+			services = append(services, service)
+		}
+	}
+
+	for _, resource := range rh.getSortedResources() {
+		for _, listener := range resource.EventListeners {
+			addService(listener)
+		}
+
+		addService(resource.AuditSink)
+	}
+
+	return services
+}
+
+// Sinks are closed before the drivers, they may use them, the
+// drivers are unwrapped so each one is closed once and the cached
+// drivers of the tenants are closed too
+func (rh *Handler) getClosers(resources []*Resource) []io.Closer {
+	closers := make([]io.Closer, 0)
+	drivers := make([]io.Closer, 0)
+
+	addCloser := func(closers []io.Closer, candidate any) []io.Closer {
+		closer, ok := candidate.(io.Closer)
+
+		if ok && !containsComparable(closers, closer) {
+			closers = append(closers, closer)
+		}
+
+		return closers
+	}
+
+	for _, resource := range resources {
+		closers = addCloser(closers, resource.AuditSink)
+		closers = addCloser(closers, resource.HistoryStore)
+
+		if resource.DatabaseDriver != nil {
+			drivers = addCloser(drivers, UnwrapDatabaseDriver(resource.DatabaseDriver))
+		}
+
+		if resource.TenantConfig == nil {
+			continue
+		}
+
+		for _, driver := range resource.TenantConfig.getDrivers() {
+			drivers = addCloser(drivers, UnwrapDatabaseDriver(driver))
+		}
+	}
+
+	return append(closers, drivers...)
+}
+
+// slices.Contains() panics on the interface values of not
+// comparable types, like a map based sink, such values are
+// never treated as duplicates
+func containsComparable[T any](values []T, candidate T) bool {
+	if !reflect.ValueOf(candidate).Comparable() {
+		return false
+	}
+
+	for _, value := range values {
+		if reflect.ValueOf(value).Comparable() && any(value) == any(candidate) {
+			return true
+		}
+	}
+
+	return false
+}
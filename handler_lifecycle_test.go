@@ -0,0 +1,72 @@
+package go_cake
+
+import (
+	"context"
+	"testing"
+)
+
+// AuditSink of a not comparable type, started, stopped and
+// closed like the sinks of the apps
+type testMapAuditSink map[string]int
+
+func (tmas testMapAuditSink) WriteAuditEntry(entry *AuditEntry, driver DatabaseDriver) error {
+	return nil
+}
+
+func (tmas testMapAuditSink) Start() error {
+	tmas["start"]++
+
+	return nil
+}
+
+func (tmas testMapAuditSink) Stop() {
+	tmas["stop"]++
+}
+
+func (tmas testMapAuditSink) Close() error {
+	tmas["close"]++
+
+	return nil
+}
+
+func TestHandlerLifecycleWithNotComparableSinks(t *testing.T) {
+	handler, resource := newTestHandler(t, newTestDriver())
+	sink := testMapAuditSink{}
+
+	resource.AuditSink = sink
+
+	if err := handler.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handler.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if sink["start"] != 1 || sink["stop"] != 1 || sink["close"] != 1 {
+		t.Errorf("sink calls = %v, want one start, stop and close", sink)
+	}
+}
+
+func TestContainsComparable(t *testing.T) {
+	sink := testMapAuditSink{}
+	values := []any{1, "a", sink, []int{1}}
+
+	tests := []struct {
+		candidate any
+		want      bool
+	}{
+		{1, true},
+		{"a", true},
+		{2, false},
+		{sink, false},
+		{[]int{1}, false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		if got := containsComparable(values, test.candidate); got != test.want {
+			t.Errorf("containsComparable(%v) = %v, want %v", test.candidate, got, test.want)
+		}
+	}
+}
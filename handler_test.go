@@ -0,0 +1,342 @@
+package go_cake
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const TEST_RESOURCE_PATTERN = `^(?P<version>\/\w+)(?P<url>\/api\/items\/?)$`
+
+var testETagCounter int
+
+type testItem struct {
+	BaseGoCakeModel `json:"-"`
+
+	ID   *string `json:"id,omitempty"`
+	ETag *string `json:"_etag,omitempty"`
+	Name *string `json:"name,omitempty"`
+}
+
+func (ti *testItem) CreateInstance() GoCakeModel {
+	item := &testItem{}
+	item.SetSubModel(item)
+
+	return item
+}
+
+func (ti *testItem) GetID() any {
+	return ti.ID
+}
+
+func (ti *testItem) SetID(id string) error {
+	ti.ID = &id
+
+	return nil
+}
+
+// Changed on each write, unlike the test names
+func (ti *testItem) CreateETag() any {
+	testETagCounter++
+
+	etag := "etag-" + strconv.Itoa(testETagCounter)
+
+	ti.ETag = &etag
+
+	return ti.ETag
+}
+
+func (ti *testItem) GetETag() any {
+	return ti.ETag
+}
+
+func (ti *testItem) SetETag(etag string) error {
+	ti.ETag = &etag
+
+	return nil
+}
+
+// DatabaseDriver keeping the items in memory, where, sort and
+// the server filter other than InFilter of the IDs are ignored,
+// counts the calls so the tests can tell which requests reached
+// the driver
+type testDriver struct {
+	items      map[string]*testItem
+	nextID     int
+	findCalls  int
+	writeCalls int
+	mutex      sync.Mutex
+}
+
+func newTestDriver(names ...string) *testDriver {
+	driver := &testDriver{items: make(map[string]*testItem)}
+
+	for _, name := range names {
+		driver.add(name)
+	}
+
+	return driver
+}
+
+func (td *testDriver) add(name string) *testItem {
+	td.nextID++
+
+	id := strconv.Itoa(td.nextID)
+	item := &testItem{ID: &id, Name: &name}
+	item.SetSubModel(item)
+	item.CreateETag()
+
+	td.items[id] = item
+
+	return item
+}
+
+func (td *testDriver) getSortedIDs() []string {
+	ids := make([]string, 0, len(td.items))
+
+	for id := range td.items {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return len(ids[i]) < len(ids[j]) || len(ids[i]) == len(ids[j]) && ids[i] < ids[j]
+	})
+
+	return ids
+}
+
+func (td *testDriver) GetUnderlyingDriver() any {
+	return nil
+}
+
+func (td *testDriver) TestModel(idField string, etagField string, model GoCakeModel, dbPath string) error {
+	return nil
+}
+
+func (td *testDriver) Find(
+	model GoCakeModel,
+	dbPath string,
+	where, sort string,
+	search *SearchQuery,
+	geo *GeoQuery,
+	serverFilter map[string]any,
+	page, perPage int64,
+	ctx context.Context,
+	userData any) ([]GoCakeModel, HTTPError) {
+	td.mutex.Lock()
+	defer td.mutex.Unlock()
+
+	td.findCalls++
+
+	documents := make([]GoCakeModel, 0)
+	ids := td.getSortedIDs()
+
+	if in, isIn := serverFilter["id"].(InFilter); isIn {
+		ids = slices.DeleteFunc(ids, func(id string) bool {
+			return !slices.Contains(in.Values, any(id))
+		})
+	}
+
+	for i, id := range ids {
+		if int64(i) >= page*perPage && int64(i) < (page+1)*perPage {
+			item := *td.items[id]
+			item.SetSubModel(&item)
+
+			documents = append(documents, &item)
+		}
+	}
+
+	return documents, nil
+}
+
+func (td *testDriver) Total(
+	model GoCakeModel,
+	dbPath string,
+	where string,
+	search *SearchQuery,
+	geo *GeoQuery,
+	serverFilter map[string]any,
+	ctx context.Context,
+	userData any) (uint64, HTTPError) {
+	td.mutex.Lock()
+	defer td.mutex.Unlock()
+
+	return uint64(len(td.items)), nil
+}
+
+func (td *testDriver) Insert(
+	model GoCakeModel,
+	dbPath string,
+	documents []GoCakeModel,
+	ctx context.Context,
+	userData any) HTTPError {
+	td.mutex.Lock()
+	defer td.mutex.Unlock()
+
+	td.writeCalls++
+
+	for _, document := range documents {
+		if document.GetHTTPError() != nil {
+			continue
+		}
+
+		item := document.(*testItem)
+		added := td.add(*item.Name)
+
+		item.ID = added.ID
+		item.ETag = added.ETag
+	}
+
+	return nil
+}
+
+func (td *testDriver) Update(
+	model GoCakeModel,
+	dbPath string,
+	documents []GoCakeModel,
+	serverFilter map[string]any,
+	ctx context.Context,
+	userData any) HTTPError {
+	return td.UpdateFields(model, dbPath, documents, []string{"name"}, serverFilter, ctx, userData)
+}
+
+func (td *testDriver) UpdateFields(
+	model GoCakeModel,
+	dbPath string,
+	documents []GoCakeModel,
+	jsonFields []string,
+	serverFilter map[string]any,
+	ctx context.Context,
+	userData any) HTTPError {
+	td.mutex.Lock()
+	defer td.mutex.Unlock()
+
+	td.writeCalls++
+
+	for _, document := range documents {
+		if document.GetHTTPError() != nil {
+			continue
+		}
+
+		item := document.(*testItem)
+		current, exists := td.items[*item.ID]
+
+		if !exists || *current.ETag != *item.ETag {
+			document.SetHTTPError(NewObjectNotFoundHTTPError(nil))
+			continue
+		}
+
+		current.Name = item.Name
+		item.ETag, _ = current.CreateETag().(*string)
+	}
+
+	return nil
+}
+
+func (td *testDriver) Delete(
+	model GoCakeModel,
+	dbPath string,
+	documents []GoCakeModel,
+	serverFilter map[string]any,
+	ctx context.Context,
+	userData any) HTTPError {
+	td.mutex.Lock()
+	defer td.mutex.Unlock()
+
+	td.writeCalls++
+
+	for _, document := range documents {
+		item := document.(*testItem)
+
+		if _, exists := td.items[*item.ID]; !exists {
+			document.SetHTTPError(NewObjectNotFoundHTTPError(nil))
+			continue
+		}
+
+		delete(td.items, *item.ID)
+	}
+
+	return nil
+}
+
+func (td *testDriver) GetWhereFields(model GoCakeModel, where string) ([]string, HTTPError) {
+	return nil, nil
+}
+
+func (td *testDriver) GetSortFields(model GoCakeModel, sort string) ([]string, HTTPError) {
+	return nil, nil
+}
+
+// Handler with the items resource at /v1/api/items served
+// from the driver, without auth
+func newTestHandler(t *testing.T, driver *testDriver) (*Handler, *Resource) {
+	t.Helper()
+
+	handler := NewHandler()
+
+	resource, err := NewResource(
+		TEST_RESOURCE_PATTERN,
+		"items",
+		"items",
+		driver,
+		&testItem{},
+		"ID",
+		"id",
+		"ETag",
+		"_etag",
+		[]string{"v1"},
+		nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = handler.AddResource(resource); err != nil {
+		t.Fatal(err)
+	}
+
+	return handler, resource
+}
+
+// Headers are given as name, value pairs
+func serveTestRequest(
+	handler http.Handler,
+	method string,
+	url string,
+	body string,
+	headers ...string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, url, strings.NewReader(body))
+	request.Header.Set("Accept", "application/json")
+
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func decodeTestResponse(t *testing.T, recorder *httptest.ResponseRecorder) *ResponseJSON {
+	t.Helper()
+
+	var response ResponseJSON
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("unable to decode %q: %v", recorder.Body.String(), err)
+	}
+
+	return &response
+}
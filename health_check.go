@@ -0,0 +1,263 @@
+package go_cake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/thoas/go-funk"
+)
+
+const HEALTH_CHECK_DEFAULT_LIVENESS_PATH = "/healthz"
+const HEALTH_CHECK_DEFAULT_READINESS_PATH = "/readyz"
+const HEALTH_CHECK_DEFAULT_TIMEOUT = 5 * time.Second
+
+const HEALTH_STATUS_OK = "ok"
+const HEALTH_STATUS_UNAVAILABLE = "unavailable"
+
+const DRIVER_STATUS_UP = "up"
+const DRIVER_STATUS_DOWN = "down"
+const DRIVER_STATUS_UNKNOWN = "unknown" // driver without Ping()
+
+// Liveness and readiness endpoints served by the Handler before
+// the middlewares, liveness does not ping the drivers so database
+// outages do not restart the instances
+type HealthCheckConfig struct {
+	LivenessPath  string        // HEALTH_CHECK_DEFAULT_LIVENESS_PATH if empty
+	ReadinessPath string        // HEALTH_CHECK_DEFAULT_READINESS_PATH if empty
+	Timeout       time.Duration // of the pings, HEALTH_CHECK_DEFAULT_TIMEOUT if 0
+}
+
+func NewHealthCheckConfig() *HealthCheckConfig {
+	return &HealthCheckConfig{
+		LivenessPath:  HEALTH_CHECK_DEFAULT_LIVENESS_PATH,
+		ReadinessPath: HEALTH_CHECK_DEFAULT_READINESS_PATH,
+		Timeout:       HEALTH_CHECK_DEFAULT_TIMEOUT,
+	}
+}
+
+func (hcc *HealthCheckConfig) GetLivenessPath() string {
+	if hcc.LivenessPath == "" {
+		return HEALTH_CHECK_DEFAULT_LIVENESS_PATH
+	}
+
+	return hcc.LivenessPath
+}
+
+func (hcc *HealthCheckConfig) GetReadinessPath() string {
+	if hcc.ReadinessPath == "" {
+		return HEALTH_CHECK_DEFAULT_READINESS_PATH
+	}
+
+	return hcc.ReadinessPath
+}
+
+func (hcc *HealthCheckConfig) GetTimeout() time.Duration {
+	if hcc.Timeout <= 0 {
+		return HEALTH_CHECK_DEFAULT_TIMEOUT
+	}
+
+	return hcc.Timeout
+}
+
+type DriverHealth struct {
+	Driver    string         `json:"driver"`
+	Tenant    string         `json:"tenant,omitempty"`
+	Resources []string       `json:"resources"`
+	Status    string         `json:"status"`
+	Error     string         `json:"error,omitempty"`
+	LatencyMs float64        `json:"latency_ms,omitempty"`
+	Stats     map[string]any `json:"stats,omitempty"`
+	driver    DatabaseDriver
+}
+
+type HealthReport struct {
+	Status  string          `json:"status"`
+	Drivers []*DriverHealth `json:"drivers"`
+}
+
+// Handler of the liveness endpoint, reports the drivers with
+// their statistics without pinging them
+func (rh *Handler) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := HealthReport{
+			Status:  HEALTH_STATUS_OK,
+			Drivers: rh.createDriversHealth(),
+		}
+
+		for _, driverHealth := range report.Drivers {
+			driverHealth.Status = DRIVER_STATUS_UNKNOWN
+		}
+
+		rh.writeHealthReport(&report, w, r)
+	})
+}
+
+// Handler of the readiness endpoint, pings every distinct driver
+// of the resources, unavailable if any of the pings fails or the
+// Handler is shutting down
+func (rh *Handler) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := HEALTH_CHECK_DEFAULT_TIMEOUT
+
+		if rh.HealthCheckConfig != nil {
+			timeout = rh.HealthCheckConfig.GetTimeout()
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		report := HealthReport{
+			Status:  HEALTH_STATUS_OK,
+			Drivers: rh.createDriversHealth(),
+		}
+
+		if rh.IsShuttingDown() {
+			report.Status = HEALTH_STATUS_UNAVAILABLE
+		}
+
+		rh.pingDrivers(report.Drivers, ctx)
+
+		for _, driverHealth := range report.Drivers {
+			if driverHealth.Status == DRIVER_STATUS_DOWN {
+				report.Status = HEALTH_STATUS_UNAVAILABLE
+			}
+		}
+
+		rh.writeHealthReport(&report, w, r)
+	})
+}
+
+func (rh *Handler) serveHealthCheck(w http.ResponseWriter, r *http.Request) bool {
+	if rh.HealthCheckConfig == nil ||
+		(r.Method != HTTP_REQUEST_GET_METHOD && r.Method != HTTP_REQUEST_HEAD_METHOD) {
+		return false
+	}
+
+	switch r.URL.Path {
+	case rh.HealthCheckConfig.GetLivenessPath():
+		rh.LivenessHandler().ServeHTTP(w, r)
+	case rh.HealthCheckConfig.GetReadinessPath():
+		rh.ReadinessHandler().ServeHTTP(w, r)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// One entry per distinct driver, ordered by the resource names,
+// the cached drivers of the tenants follow the resource's driver,
+// wrappers like the instrumented driver are looked through
+func (rh *Handler) createDriversHealth() []*DriverHealth {
+	drivers := make(map[DatabaseDriver]*DriverHealth)
+	driversHealth := make([]*DriverHealth, 0)
+
+	for _, resource := range rh.getSortedResources() {
+		if resource.DatabaseDriver != nil {
+			driversHealth = rh.addDriverHealth(drivers, driversHealth, resource, "", resource.DatabaseDriver)
+		}
+
+		if resource.TenantConfig == nil {
+			continue
+		}
+
+		tenantDrivers := resource.TenantConfig.getDrivers()
+		tenants := funk.Keys(tenantDrivers).([]string)
+
+		sort.Strings(tenants)
+
+		for _, tenant := range tenants {
+			driversHealth = rh.addDriverHealth(drivers, driversHealth, resource, tenant, tenantDrivers[tenant])
+		}
+	}
+
+	return driversHealth
+}
+
+func (rh *Handler) addDriverHealth(
+	drivers map[DatabaseDriver]*DriverHealth,
+	driversHealth []*DriverHealth,
+	resource *Resource,
+	tenant string,
+	driver DatabaseDriver) []*DriverHealth {
+	driver = UnwrapDatabaseDriver(driver)
+	driverHealth, ok := drivers[driver]
+
+	if !ok {
+		driverHealth = &DriverHealth{
+			Driver: fmt.Sprintf("%T", driver),
+			Tenant: tenant,
+			driver: driver,
+		}
+
+		if statsProvider, ok := driver.(DatabaseDriverStatsProvider); ok {
+			driverHealth.Stats = statsProvider.Stats()
+		}
+
+		drivers[driver] = driverHealth
+		driversHealth = append(driversHealth, driverHealth)
+	}
+
+	if !funk.ContainsString(driverHealth.Resources, resource.ResourceName) {
+		driverHealth.Resources = append(driverHealth.Resources, resource.ResourceName)
+	}
+
+	return driversHealth
+}
+
+// Pings the drivers concurrently, ctx limits all the pings
+func (rh *Handler) pingDrivers(driversHealth []*DriverHealth, ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, driverHealth := range driversHealth {
+		pinger, ok := driverHealth.driver.(DatabaseDriverPinger)
+
+		if !ok {
+			driverHealth.Status = DRIVER_STATUS_UNKNOWN
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(driverHealth *DriverHealth, pinger DatabaseDriverPinger) {
+			defer wg.Done()
+
+			timeStart := time.Now()
+			err := pinger.Ping(ctx)
+
+			driverHealth.LatencyMs = time.Since(timeStart).Seconds() * 1000
+
+			if err != nil {
+				driverHealth.Status = DRIVER_STATUS_DOWN
+				driverHealth.Error = err.Error()
+			} else {
+				driverHealth.Status = DRIVER_STATUS_UP
+			}
+		}(driverHealth, pinger)
+	}
+
+	wg.Wait()
+}
+
+func (rh *Handler) writeHealthReport(report *HealthReport, w http.ResponseWriter, r *http.Request) {
+	statusCode := http.StatusOK
+
+	if report.Status != HEALTH_STATUS_OK {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	jsonText, _ := json.Marshal(report)
+
+	w.Header().Set("Content-Type", RESPONSE_CONTENT_TYPE)
+	w.Header().Set("Cache-Control", RESPONSE_CACHE_CONTROL)
+	w.WriteHeader(statusCode)
+
+	if r.Method != HTTP_REQUEST_HEAD_METHOD {
+		w.Write(jsonText)
+	}
+}
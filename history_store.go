@@ -0,0 +1,48 @@
+package go_cake
+
+import (
+	"context"
+	"time"
+)
+
+const HISTORY_DB_PATH_SUFFIX = "_history"
+
+// Previous version of a document, saved before the change
+type DocumentVersion struct {
+	DocumentID string
+	Version    int64 // assigned by HistoryStore.SaveVersion(), starts from 1
+	ETag       any
+	Operation  string // operation which replaced the version
+	Time       time.Time
+	Document   map[string]any // JSON fields of the document
+}
+
+// Keeps previous versions of the documents, like a shadow
+// collection or table named <dbPath>HISTORY_DB_PATH_SUFFIX,
+// driver is the one resolved for the request (like the tenant's
+// database) so the versions are kept next to the documents
+type HistoryStore interface {
+	SaveVersion(
+		resource *Resource,
+		driver DatabaseDriver,
+		dbPath string,
+		version *DocumentVersion,
+		ctx context.Context) error
+
+	// newest first
+	GetVersions(
+		resource *Resource,
+		driver DatabaseDriver,
+		dbPath string,
+		documentID string,
+		ctx context.Context) ([]*DocumentVersion, error)
+
+	// nil if the version does not exist
+	GetVersion(
+		resource *Resource,
+		driver DatabaseDriver,
+		dbPath string,
+		documentID string,
+		version int64,
+		ctx context.Context) (*DocumentVersion, error)
+}
@@ -0,0 +1,90 @@
+package go_cake
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// HistoryStore keeping the saved versions in memory
+type testHistoryStore struct {
+	versions []*DocumentVersion
+	mutex    sync.Mutex
+}
+
+func (ths *testHistoryStore) SaveVersion(
+	resource *Resource,
+	driver DatabaseDriver,
+	dbPath string,
+	version *DocumentVersion,
+	ctx context.Context) error {
+	ths.mutex.Lock()
+	defer ths.mutex.Unlock()
+
+	version.Version = int64(len(ths.versions) + 1)
+	ths.versions = append(ths.versions, version)
+
+	return nil
+}
+
+func (ths *testHistoryStore) GetVersions(
+	resource *Resource,
+	driver DatabaseDriver,
+	dbPath string,
+	documentID string,
+	ctx context.Context) ([]*DocumentVersion, error) {
+	return nil, nil
+}
+
+func (ths *testHistoryStore) GetVersion(
+	resource *Resource,
+	driver DatabaseDriver,
+	dbPath string,
+	documentID string,
+	version int64,
+	ctx context.Context) (*DocumentVersion, error) {
+	return nil, nil
+}
+
+func TestHistoryStoreSavesVersionsOfChangedDocuments(t *testing.T) {
+	driver := newTestDriver("first", "second", "third")
+	handler, resource := newTestHandler(t, driver)
+	historyStore := &testHistoryStore{}
+
+	resource.HistoryStore = historyStore
+
+	firstETag := *driver.items["1"].ETag
+	secondETag := *driver.items["2"].ETag
+	findCalls := driver.findCalls
+
+	// the second one has a stale ETag so its update fails
+	body := fmt.Sprintf(
+		`[{"id":"1","_etag":%q,"name":"first v2"},{"id":"2","_etag":"stale","name":"second v2"}]`,
+		firstETag)
+	recorder := serveTestRequest(handler, http.MethodPatch, "/v1/api/items", body)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %v, want %v: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	if calls := driver.findCalls - findCalls; calls != 1 {
+		t.Errorf("%v Find() calls for the current documents, want 1", calls)
+	}
+
+	if len(historyStore.versions) != 1 {
+		t.Fatalf("%v versions saved, want only the one of the updated document", len(historyStore.versions))
+	}
+
+	version := historyStore.versions[0]
+
+	if version.DocumentID != "1" || version.ETag != firstETag ||
+		version.Document["name"] != "first" || version.Operation != OPERATION_UPDATE {
+		t.Errorf("saved version = %+v, want the previous state of document 1", version)
+	}
+
+	if *driver.items["2"].ETag != secondETag {
+		t.Error("document 2 updated with a stale ETag")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type HTTPError interface {
@@ -44,6 +45,19 @@ type ObjectNotFoundHTTPError struct{ BaseHTTPError }
 type ObjectNotAffectedHTTPError struct{ BaseHTTPError }
 type TooManyAffectedObjectsHTTPError struct{ BaseHTTPError }
 type UnsupportedVersionHTTPError struct{ BaseHTTPError }
+type SearchNotAllowedHTTPError struct{ BaseHTTPError }
+type MalformedGeoHTTPError struct{ BaseHTTPError }
+type FieldNotGeoFilterableHTTPError struct{ BaseHTTPError }
+type ForbiddenHTTPError struct{ BaseHTTPError }
+type FieldAccessDeniedHTTPError struct{ BaseHTTPError }
+type InvalidTenantHTTPError struct{ BaseHTTPError }
+type TooManyRequestsHTTPError struct{ BaseHTTPError }
+type InvalidIdempotencyKeyHTTPError struct{ BaseHTTPError }
+type IdempotencyKeyInUseHTTPError struct{ BaseHTTPError }
+type IdempotencyKeyReusedHTTPError struct{ BaseHTTPError }
+type ServiceUnavailableHTTPError struct{ BaseHTTPError }
+type InvalidRequestTimeoutHTTPError struct{ BaseHTTPError }
+type GatewayTimeoutHTTPError struct{ BaseHTTPError }
 
 func NewMethodNotAllowedHTTPError(internalError error) HTTPError {
 	e := MethodNotAllowedHTTPError{}
@@ -66,6 +80,15 @@ func NewUnauthorizedHTTPError(internalError error) HTTPError {
 	return e
 }
 
+func NewForbiddenHTTPError(internalError error) HTTPError {
+	e := ForbiddenHTTPError{}
+
+	e.StatusCode = http.StatusForbidden
+	e.StatusMessage = e.FormatStatusMessage("", e, internalError)
+
+	return e
+}
+
 func NewURLNotFoundHTTPError(internalError error) HTTPError {
 	e := URLNotFoundHTTPError{}
 
@@ -375,3 +398,128 @@ func NewModifiersNotAllowedHTTPError(internalError error) HTTPError {
 
 	return e
 }
+
+func NewSearchNotAllowedHTTPError(internalError error) HTTPError {
+	e := SearchNotAllowedHTTPError{}
+
+	e.StatusCode = http.StatusBadRequest
+	e.StatusMessage = e.FormatStatusMessage("Full-text search is not enabled for this resource", e, internalError)
+
+	return e
+}
+
+func NewMalformedGeoHTTPError(internalError error) HTTPError {
+	e := MalformedGeoHTTPError{}
+
+	e.StatusCode = http.StatusBadRequest
+	e.StatusMessage = e.FormatStatusMessage("", e, internalError)
+
+	return e
+}
+
+func NewFieldNotGeoFilterableHTTPError(field string, internalError error) HTTPError {
+	e := FieldNotGeoFilterableHTTPError{}
+
+	message := fmt.Sprintf("Field '%v' is not geo filterable", field)
+
+	e.StatusCode = http.StatusBadRequest
+	e.StatusMessage = e.FormatStatusMessage(message, e, internalError)
+
+	return e
+}
+
+func NewFieldAccessDeniedHTTPError(field string, internalError error) HTTPError {
+	e := FieldAccessDeniedHTTPError{}
+
+	e.StatusCode = http.StatusForbidden
+	e.StatusMessage = e.FormatStatusMessage(
+		fmt.Sprintf("Field %v access denied", field),
+		e,
+		internalError)
+
+	return e
+}
+
+func NewInvalidTenantHTTPError(internalError error) HTTPError {
+	e := InvalidTenantHTTPError{}
+
+	e.StatusCode = http.StatusBadRequest
+	e.StatusMessage = e.FormatStatusMessage("Invalid or missing tenant", e, internalError)
+
+	return e
+}
+
+func NewTooManyRequestsHTTPError(retryAfter time.Duration, internalError error) HTTPError {
+	e := TooManyRequestsHTTPError{}
+
+	message := fmt.Sprintf("Too many requests, retry after %v seconds", durationToSeconds(retryAfter))
+
+	e.StatusCode = http.StatusTooManyRequests
+	e.StatusMessage = e.FormatStatusMessage(message, e, internalError)
+
+	return e
+}
+
+func NewInvalidIdempotencyKeyHTTPError(internalError error) HTTPError {
+	e := InvalidIdempotencyKeyHTTPError{}
+
+	message := fmt.Sprintf("Invalid idempotency key, max. length is %v", IDEMPOTENCY_MAX_KEY_LENGTH)
+
+	e.StatusCode = http.StatusBadRequest
+	e.StatusMessage = e.FormatStatusMessage(message, e, internalError)
+
+	return e
+}
+
+func NewIdempotencyKeyInUseHTTPError(internalError error) HTTPError {
+	e := IdempotencyKeyInUseHTTPError{}
+
+	e.StatusCode = http.StatusConflict
+	e.StatusMessage = e.FormatStatusMessage(
+		"Request with the same idempotency key is in progress",
+		e,
+		internalError)
+
+	return e
+}
+
+func NewIdempotencyKeyReusedHTTPError(internalError error) HTTPError {
+	e := IdempotencyKeyReusedHTTPError{}
+
+	e.StatusCode = http.StatusUnprocessableEntity
+	e.StatusMessage = e.FormatStatusMessage(
+		"Idempotency key was already used with a different request",
+		e,
+		internalError)
+
+	return e
+}
+
+func NewServiceUnavailableHTTPError(internalError error) HTTPError {
+	e := ServiceUnavailableHTTPError{}
+
+	e.StatusCode = http.StatusServiceUnavailable
+	e.StatusMessage = e.FormatStatusMessage("Server is shutting down", e, internalError)
+
+	return e
+}
+
+func NewInvalidRequestTimeoutHTTPError(internalError error) HTTPError {
+	e := InvalidRequestTimeoutHTTPError{}
+
+	message := fmt.Sprintf("Invalid %v header, expected positive number of milliseconds", REQUEST_TIMEOUT_HEADER)
+
+	e.StatusCode = http.StatusBadRequest
+	e.StatusMessage = e.FormatStatusMessage(message, e, internalError)
+
+	return e
+}
+
+func NewGatewayTimeoutHTTPError(internalError error) HTTPError {
+	e := GatewayTimeoutHTTPError{}
+
+	e.StatusCode = http.StatusGatewayTimeout
+	e.StatusMessage = e.FormatStatusMessage("Request deadline exceeded", e, internalError)
+
+	return e
+}
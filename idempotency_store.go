@@ -0,0 +1,89 @@
+package go_cake
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const IDEMPOTENCY_KEY_HEADER = "Idempotency-Key"
+const IDEMPOTENCY_REPLAYED_HEADER = "Idempotent-Replayed"
+const IDEMPOTENCY_MAX_KEY_LENGTH = 255
+const IDEMPOTENCY_DEFAULT_TTL = 24 * time.Hour
+const IDEMPOTENCY_DEFAULT_PENDING_TIMEOUT = time.Minute
+
+// First response of the request with the Idempotency-Key,
+// Key is hashed with the resource, tenant and identity
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	Completed   bool
+	StatusCode  int
+	Body        []byte // JSON response
+	ExpireTime  time.Time
+}
+
+// Storage of the responses, implementations must be safe for
+// concurrent use and by many instances of the API
+type IdempotencyStore interface {
+	// Saves the record if the key is not used or expired,
+	// returns the existing record otherwise
+	Reserve(record *IdempotencyRecord) (*IdempotencyRecord, error)
+
+	Complete(record *IdempotencyRecord) error
+	Remove(key string) error
+}
+
+// Inserts and updates with Idempotency-Key header are processed
+// once, retries get the stored response
+type IdempotencyConfig struct {
+	Store          IdempotencyStore // MemoryIdempotencyStore if nil
+	TTL            time.Duration    // of the stored responses, IDEMPOTENCY_DEFAULT_TTL if 0
+	PendingTimeout time.Duration    // of the requests in progress, IDEMPOTENCY_DEFAULT_PENDING_TIMEOUT if 0
+	storeOnce      sync.Once
+}
+
+func NewIdempotencyConfig(store IdempotencyStore) *IdempotencyConfig {
+	if store == nil {
+		store = NewMemoryIdempotencyStore()
+	}
+
+	return &IdempotencyConfig{
+		Store:          store,
+		TTL:            IDEMPOTENCY_DEFAULT_TTL,
+		PendingTimeout: IDEMPOTENCY_DEFAULT_PENDING_TIMEOUT,
+	}
+}
+
+func (ic *IdempotencyConfig) GetStore() IdempotencyStore {
+	ic.storeOnce.Do(func() {
+		if ic.Store == nil {
+			ic.Store = NewMemoryIdempotencyStore()
+		}
+	})
+
+	return ic.Store
+}
+
+func (ic *IdempotencyConfig) GetTTL() time.Duration {
+	if ic.TTL <= 0 {
+		return IDEMPOTENCY_DEFAULT_TTL
+	}
+
+	return ic.TTL
+}
+
+func (ic *IdempotencyConfig) GetPendingTimeout() time.Duration {
+	if ic.PendingTimeout <= 0 {
+		return IDEMPOTENCY_DEFAULT_PENDING_TIMEOUT
+	}
+
+	return ic.PendingTimeout
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
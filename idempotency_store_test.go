@@ -0,0 +1,96 @@
+package go_cake
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIdempotencyKeyReplaysFirstResponse(t *testing.T) {
+	driver := newTestDriver()
+	handler, resource := newTestHandler(t, driver)
+
+	resource.IdempotencyConfig = NewIdempotencyConfig(nil)
+
+	first := serveTestRequest(handler, http.MethodPost, "/v1/api/items", `[{"name":"a"}]`, "Idempotency-Key", "order-1")
+
+	if first.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v", first.Code, http.StatusOK)
+	}
+
+	if replayed := first.Header().Get(IDEMPOTENCY_REPLAYED_HEADER); replayed != "" {
+		t.Errorf("%v = %q in the first response, want none", IDEMPOTENCY_REPLAYED_HEADER, replayed)
+	}
+
+	retry := serveTestRequest(handler, http.MethodPost, "/v1/api/items", `[{"name":"a"}]`, "Idempotency-Key", "order-1")
+
+	if retry.Code != first.Code {
+		t.Errorf("status of the retry = %v, want %v", retry.Code, first.Code)
+	}
+
+	if replayed := retry.Header().Get(IDEMPOTENCY_REPLAYED_HEADER); replayed != "true" {
+		t.Errorf("%v = %q, want true", IDEMPOTENCY_REPLAYED_HEADER, replayed)
+	}
+
+	firstItems := decodeTestResponse(t, first).Items
+	retryItems := decodeTestResponse(t, retry).Items
+
+	if len(retryItems) != 1 || retryItems[0]["id"] != firstItems[0]["id"] || retryItems[0]["_etag"] != firstItems[0]["_etag"] {
+		t.Errorf("items of the retry = %v, want the stored %v", retryItems, firstItems)
+	}
+
+	if driver.writeCalls != 1 || len(driver.items) != 1 {
+		t.Fatalf("%v inserts of %v items, want the retry not to reach the driver", driver.writeCalls, len(driver.items))
+	}
+
+	reused := serveTestRequest(handler, http.MethodPost, "/v1/api/items", `[{"name":"b"}]`, "Idempotency-Key", "order-1")
+
+	if reused.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status of the key reused with another body = %v, want %v", reused.Code, http.StatusUnprocessableEntity)
+	}
+
+	other := serveTestRequest(handler, http.MethodPost, "/v1/api/items", `[{"name":"a"}]`, "Idempotency-Key", "order-2")
+
+	if other.Code != http.StatusOK || len(driver.items) != 2 {
+		t.Errorf("status of another key = %v with %v items, want a new insert", other.Code, len(driver.items))
+	}
+
+	if plain := serveTestRequest(handler, http.MethodPost, "/v1/api/items", `[{"name":"a"}]`); plain.Code != http.StatusOK || len(driver.items) != 3 {
+		t.Errorf("status without the key = %v with %v items, want a new insert", plain.Code, len(driver.items))
+	}
+}
+
+func TestIdempotencyKeyInProgress(t *testing.T) {
+	driver := newTestDriver()
+	handler, resource := newTestHandler(t, driver)
+	retryStatusCode := 0
+
+	resource.IdempotencyConfig = NewIdempotencyConfig(nil)
+
+	// the retry arrives while the first request is inserting
+	resource.ResourceCallback.InsertingDocuments = func(
+		resource *Resource,
+		request *Request,
+		documents []GoCakeModel,
+		currentHttpErr HTTPError) HTTPError {
+		if retryStatusCode == 0 {
+			retry := serveTestRequest(handler, http.MethodPost, "/v1/api/items", `[{"name":"a"}]`, "Idempotency-Key", "order-1")
+			retryStatusCode = retry.Code
+		}
+
+		return currentHttpErr
+	}
+
+	first := serveTestRequest(handler, http.MethodPost, "/v1/api/items", `[{"name":"a"}]`, "Idempotency-Key", "order-1")
+
+	if first.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v", first.Code, http.StatusOK)
+	}
+
+	if retryStatusCode != http.StatusConflict {
+		t.Errorf("status of the retry in progress = %v, want %v", retryStatusCode, http.StatusConflict)
+	}
+
+	if len(driver.items) != 1 {
+		t.Errorf("%v items inserted, want 1", len(driver.items))
+	}
+}
@@ -1,6 +1,8 @@
 package go_cake
 
 import (
+	"slices"
+
 	"github.com/thoas/go-funk"
 )
 
@@ -29,7 +31,11 @@ func (irp *InsertRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoC
 		return nil, httpErr
 	}
 
-	if irp.request.HasWhere() || irp.request.HasSort() || irp.request.HasPage() {
+	if irp.request.HasWhere() ||
+		irp.request.HasSort() ||
+		irp.request.HasSearch() ||
+		irp.request.HasGeo() ||
+		irp.request.HasPage() {
 		return nil, NewModifiersNotAllowedHTTPError(nil)
 	}
 
@@ -61,11 +67,14 @@ func (irp *InsertRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoC
 		ctxDbDriverInsert)
 	defer cancel()
 
-	httpErr = irp.resource.DatabaseDriver.Insert(
+	httpErr = irp.driver.Insert(
 		irp.resource.DbModel,
+		irp.dbPath,
 		converted,
 		ctx,
-		nil)
+		irp.request.UserData)
+
+	irp.emitResourceEvent(EVENT_INSERTED, converted, httpErr)
 
 	httpErr = irp.callInsertedDocumentsHandlers(converted, httpErr)
 
@@ -105,7 +114,15 @@ func (irp *InsertRequestProcessor) preRequestJSONActions() {
 		insertableFields = irp.resource.DbModelJSONFieldsNoReserved
 	}
 
+	// populated by the server
+	insertableFields = append(slices.Clone(insertableFields), irp.getServerFilterFields()...)
+
 	for _, jsonObject := range irp.request.DecodedJsonSlice {
+		if httpErr = irp.preRequestServerFilterActions(jsonObject); httpErr != nil {
+			jsonObject["__http_error__"] = httpErr
+			continue
+		}
+
 		if httpErr = irp.preRequestRequireOnInsertChecks(
 			jsonObject,
 			requireOnInsertFields); httpErr != nil {
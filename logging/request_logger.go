@@ -0,0 +1,265 @@
+package logging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strings"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+	"github.com/thoas/go-funk"
+)
+
+const REDACTED_VALUE = "***"
+const OPERATION_CORS = "cors"
+const OPERATION_UNKNOWN = "unknown"
+const MAX_LOGGED_PAYLOAD_ITEMS = 10
+
+type RequestLoggerConfig struct {
+	Logger         *slog.Logger // slog.Default() by default
+	Level          slog.Level   // of successful requests, 4xx are logged as warnings, 5xx as errors
+	SampleRate     float64      // fraction of successful requests logged, all if 0
+	Sampler        func(stats *go_cake.RequestStats) bool
+	TrustProxy     bool     // client IP from X-Forwarded-For and X-Real-IP headers
+	TrustedProxies int      // proxies appending to X-Forwarded-For, 1 if 0
+	LogQuery       bool     // where and sort
+	LogPayload     bool     // up to MAX_LOGGED_PAYLOAD_ITEMS decoded payload items
+	RedactedFields []string // redacted in addition to JSONSchemaConfig.HiddenFields and ErasedFields
+}
+
+// Structured request log, add it to the handler by
+// go_cake.Handler.AddRequestObserver(), failed requests are
+// always logged, successful ones can be sampled
+type RequestLogger struct {
+	config RequestLoggerConfig
+}
+
+func NewRequestLogger(config RequestLoggerConfig) *RequestLogger {
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	return &RequestLogger{config: config}
+}
+
+// go_cake.RequestObserver
+func (rl *RequestLogger) ObserveRequest(stats *go_cake.RequestStats) {
+	if stats.StatusCode < http.StatusBadRequest && !rl.isSampled(stats) {
+		return
+	}
+
+	level := rl.getLevel(stats.StatusCode)
+
+	request := stats.Request
+	ctx := request.TraceContext()
+
+	if !rl.config.Logger.Enabled(ctx, level) {
+		return
+	}
+
+	attributes := []slog.Attr{
+		slog.String("request_id", request.UniqueID),
+		slog.String("method", request.Method),
+		slog.String("resource", stats.Resource.ResourceName),
+		slog.String("operation", rl.getOperation(request)),
+		slog.String("version", request.Version),
+		slog.Int("status", stats.StatusCode),
+		slog.Float64("duration_ms", float64(stats.Duration.Microseconds())/1000),
+		slog.Int("items_in", stats.InputItems),
+		slog.Int("items_out", stats.OutputItems),
+	}
+
+	if request.TraceID != "" && strings.Trim(request.TraceID, "0") != "" {
+		attributes = append(attributes, slog.String("trace_id", request.TraceID))
+	}
+
+	if request.Request != nil {
+		attributes = append(attributes,
+			slog.String("path", request.Request.URL.Path),
+			slog.String("client_ip", rl.getClientIP(request.Request)))
+	}
+
+	if request.Identity != nil {
+		attributes = append(attributes,
+			slog.String("subject", request.Identity.Subject),
+			slog.String("auth_provider", request.Identity.Provider))
+	}
+
+	if request.Tenant != "" {
+		attributes = append(attributes, slog.String("tenant", request.Tenant))
+	}
+
+	redactedFields := rl.getRedactedFields(stats.Resource)
+
+	if rl.config.LogQuery {
+		if request.Where != "" {
+			attributes = append(attributes, slog.String("where", rl.redactQuery(request.Where, redactedFields)))
+		}
+
+		if request.Sort != "" {
+			attributes = append(attributes, slog.String("sort", request.Sort))
+		}
+	}
+
+	if rl.config.LogPayload && len(request.DecodedJsonSlice) > 0 {
+		attributes = append(attributes, slog.Any("payload", rl.redactPayload(request.DecodedJsonSlice, redactedFields)))
+	}
+
+	rl.config.Logger.LogAttrs(ctx, level, "request", attributes...)
+}
+
+func (rl *RequestLogger) getLevel(statusCode int) slog.Level {
+	if statusCode >= http.StatusInternalServerError {
+		return slog.LevelError
+	}
+
+	if statusCode >= http.StatusBadRequest {
+		return slog.LevelWarn
+	}
+
+	return rl.config.Level
+}
+
+func (rl *RequestLogger) isSampled(stats *go_cake.RequestStats) bool {
+	if rl.config.Sampler != nil {
+		return rl.config.Sampler(stats)
+	}
+
+	if rl.config.SampleRate <= 0 || rl.config.SampleRate >= 1 {
+		return true
+	}
+
+	return rand.Float64() < rl.config.SampleRate
+}
+
+func (rl *RequestLogger) getOperation(request *go_cake.Request) string {
+	if request.IsCORS {
+		return OPERATION_CORS
+	}
+
+	if operation := request.Operation(); operation != "" {
+		return operation
+	}
+
+	// not parsed request
+	return OPERATION_UNKNOWN
+}
+
+// Proxy headers can be spoofed so they are used only with
+// TrustProxy
+func (rl *RequestLogger) getClientIP(httpRequest *http.Request) string {
+	if !rl.config.TrustProxy {
+		return go_cake.GetClientIP(httpRequest, 0)
+	}
+
+	if httpRequest.Header.Get("X-Forwarded-For") == "" {
+		if realIP := strings.TrimSpace(httpRequest.Header.Get("X-Real-IP")); realIP != "" {
+			return realIP
+		}
+	}
+
+	return go_cake.GetClientIP(httpRequest, max(rl.config.TrustedProxies, 1))
+}
+
+func (rl *RequestLogger) getRedactedFields(resource *go_cake.Resource) []string {
+	redactedFields := append([]string{}, rl.config.RedactedFields...)
+
+	if resource.JSONSchemaConfig == nil {
+		return redactedFields
+	}
+
+	hiddenFields := resource.JSONSchemaConfig.HiddenFields
+	erasedFields := resource.JSONSchemaConfig.ErasedFields
+
+	if funk.ContainsString(hiddenFields, go_cake.FIELD_ANY) {
+		hiddenFields = resource.DbModelJSONFieldsNoReserved
+	}
+
+	if funk.ContainsString(erasedFields, go_cake.FIELD_ANY) {
+		erasedFields = resource.DbModelJSONFieldsNoReserved
+	}
+
+	redactedFields = append(redactedFields, hiddenFields...)
+
+	return append(redactedFields, erasedFields...)
+}
+
+func (rl *RequestLogger) redactPayload(items []map[string]any, redactedFields []string) []map[string]any {
+	if len(items) > MAX_LOGGED_PAYLOAD_ITEMS {
+		items = items[:MAX_LOGGED_PAYLOAD_ITEMS]
+	}
+
+	redacted := make([]map[string]any, 0, len(items))
+
+	for _, item := range items {
+		redacted = append(redacted, rl.redactMap(item, redactedFields))
+	}
+
+	return redacted
+}
+
+// Nested maps and arrays are redacted too, like conditions
+// of the JSON where
+func (rl *RequestLogger) redactValue(value any, redactedFields []string) any {
+	switch typedValue := value.(type) {
+	case map[string]any:
+		return rl.redactMap(typedValue, redactedFields)
+	case []any:
+		redacted := make([]any, 0, len(typedValue))
+
+		for _, item := range typedValue {
+			redacted = append(redacted, rl.redactValue(item, redactedFields))
+		}
+
+		return redacted
+	}
+
+	return value
+}
+
+func (rl *RequestLogger) redactMap(jsonObject map[string]any, redactedFields []string) map[string]any {
+	redacted := make(map[string]any, len(jsonObject))
+
+	for key, value := range jsonObject {
+		if key == "__http_error__" {
+			continue
+		}
+
+		if funk.ContainsString(redactedFields, key) {
+			redacted[key] = REDACTED_VALUE
+			continue
+		}
+
+		redacted[key] = rl.redactValue(value, redactedFields)
+	}
+
+	return redacted
+}
+
+// JSON where (like Mongo one) is redacted by the keys, other
+// (like SQL one) is redacted whole if it mentions any of the
+// fields
+func (rl *RequestLogger) redactQuery(query string, redactedFields []string) string {
+	if len(redactedFields) == 0 {
+		return query
+	}
+
+	var jsonQuery any
+
+	if err := json.Unmarshal([]byte(query), &jsonQuery); err == nil {
+		redactedJSON, err := json.Marshal(rl.redactValue(jsonQuery, redactedFields))
+
+		if err == nil {
+			return string(redactedJSON)
+		}
+	}
+
+	for _, field := range redactedFields {
+		if strings.Contains(query, field) {
+			return REDACTED_VALUE
+		}
+	}
+
+	return query
+}
@@ -0,0 +1,74 @@
+package go_cake
+
+import (
+	"sync"
+	"time"
+)
+
+// IdempotencyStore kept in memory, records are not shared
+// between the instances of the API
+type MemoryIdempotencyStore struct {
+	records      map[string]*IdempotencyRecord
+	cleanupTime  time.Time
+	recordsMutex sync.Mutex
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records:     make(map[string]*IdempotencyRecord),
+		cleanupTime: time.Now(),
+	}
+}
+
+func (mis *MemoryIdempotencyStore) Reserve(record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	mis.recordsMutex.Lock()
+	defer mis.recordsMutex.Unlock()
+
+	now := time.Now()
+
+	mis.cleanup(now)
+
+	if existing, ok := mis.records[record.Key]; ok && existing.ExpireTime.After(now) {
+		existingCopy := *existing
+
+		return &existingCopy, nil
+	}
+
+	recordCopy := *record
+	mis.records[record.Key] = &recordCopy
+
+	return nil, nil
+}
+
+func (mis *MemoryIdempotencyStore) Complete(record *IdempotencyRecord) error {
+	mis.recordsMutex.Lock()
+	defer mis.recordsMutex.Unlock()
+
+	recordCopy := *record
+	mis.records[record.Key] = &recordCopy
+
+	return nil
+}
+
+func (mis *MemoryIdempotencyStore) Remove(key string) error {
+	mis.recordsMutex.Lock()
+	defer mis.recordsMutex.Unlock()
+
+	delete(mis.records, key)
+
+	return nil
+}
+
+func (mis *MemoryIdempotencyStore) cleanup(now time.Time) {
+	if now.Sub(mis.cleanupTime) < time.Minute {
+		return
+	}
+
+	mis.cleanupTime = now
+
+	for key, record := range mis.records {
+		if !record.ExpireTime.After(now) {
+			delete(mis.records, key)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package go_cake
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	record := &IdempotencyRecord{Key: "key", RequestHash: "hash", ExpireTime: time.Now().Add(time.Minute)}
+
+	existing, err := store.Reserve(record)
+
+	if err != nil || existing != nil {
+		t.Fatalf("Reserve() of an unused key = %+v, %v, want nil", existing, err)
+	}
+
+	// a retry while the first request is in progress
+	if existing, _ = store.Reserve(record); existing == nil || existing.Completed {
+		t.Fatalf("Reserve() of a reserved key = %+v, want the pending record", existing)
+	}
+
+	record.Completed = true
+	record.StatusCode = http.StatusCreated
+	record.Body = []byte(`{"_items":[]}`)
+
+	if err = store.Complete(record); err != nil {
+		t.Fatal(err)
+	}
+
+	existing, _ = store.Reserve(&IdempotencyRecord{Key: "key", RequestHash: "hash"})
+
+	if existing == nil || !existing.Completed || existing.StatusCode != http.StatusCreated ||
+		string(existing.Body) != string(record.Body) {
+		t.Fatalf("Reserve() of a completed key = %+v, want the completed record", existing)
+	}
+
+	if err = store.Remove("key"); err != nil {
+		t.Fatal(err)
+	}
+
+	if existing, _ = store.Reserve(record); existing != nil {
+		t.Errorf("Reserve() after Remove() = %+v, want nil", existing)
+	}
+}
+
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+
+	store.Complete(&IdempotencyRecord{Key: "key", RequestHash: "old", Completed: true, ExpireTime: time.Now().Add(-time.Second)})
+
+	if existing, _ := store.Reserve(&IdempotencyRecord{Key: "key", RequestHash: "new", ExpireTime: time.Now().Add(time.Minute)}); existing != nil {
+		t.Fatalf("Reserve() of an expired key = %+v, want nil", existing)
+	}
+
+	if existing, _ := store.Reserve(&IdempotencyRecord{Key: "key"}); existing == nil || existing.RequestHash != "new" {
+		t.Errorf("Reserve() = %+v, want the record replacing the expired one", existing)
+	}
+}
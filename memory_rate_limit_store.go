@@ -0,0 +1,85 @@
+package go_cake
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const RATE_LIMIT_CLEANUP_INTERVAL = time.Minute
+
+type tokenBucket struct {
+	tokens      float64
+	updatedTime time.Time
+	period      time.Duration
+}
+
+// RateLimitStore kept in memory, buckets are not shared
+// between the instances of the API
+type MemoryRateLimitStore struct {
+	buckets      map[string]*tokenBucket
+	cleanupTime  time.Time
+	bucketsMutex sync.Mutex
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:     make(map[string]*tokenBucket),
+		cleanupTime: time.Now(),
+	}
+}
+
+func (mrls *MemoryRateLimitStore) Take(key string, limit *RateLimit, cost int64) (*RateLimitResult, error) {
+	mrls.bucketsMutex.Lock()
+	defer mrls.bucketsMutex.Unlock()
+
+	now := time.Now()
+	capacity := float64(limit.Requests)
+	refillPerSecond := capacity / limit.Period.Seconds()
+
+	mrls.cleanup(now)
+
+	bucket, ok := mrls.buckets[key]
+
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updatedTime: now}
+		mrls.buckets[key] = bucket
+	}
+
+	bucket.period = limit.Period
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updatedTime).Seconds()*refillPerSecond)
+	bucket.updatedTime = now
+
+	result := RateLimitResult{}
+
+	if bucket.tokens >= float64(cost) {
+		bucket.tokens -= float64(cost)
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((float64(cost) - bucket.tokens) / refillPerSecond)
+	}
+
+	result.Remaining = int64(math.Floor(bucket.tokens))
+	result.Reset = secondsToDuration((capacity - bucket.tokens) / refillPerSecond)
+
+	return &result, nil
+}
+
+// Removes full buckets, they are the same as missing ones
+func (mrls *MemoryRateLimitStore) cleanup(now time.Time) {
+	if now.Sub(mrls.cleanupTime) < RATE_LIMIT_CLEANUP_INTERVAL {
+		return
+	}
+
+	mrls.cleanupTime = now
+
+	for key, bucket := range mrls.buckets {
+		if now.Sub(bucket.updatedTime) > bucket.period {
+			delete(mrls.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
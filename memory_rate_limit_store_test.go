@@ -0,0 +1,95 @@
+package go_cake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	limit := &RateLimit{Requests: 10, Period: 10 * time.Second} // one token per second
+	store := NewMemoryRateLimitStore()
+
+	result, err := store.Take("client", limit, 3)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Allowed || result.Remaining != 7 {
+		t.Fatalf("Take(3) of a full bucket = %+v, want allowed with 7 remaining", result)
+	}
+
+	if seconds := durationToSeconds(result.Reset); seconds != 3 {
+		t.Errorf("Reset = %v, want 3s until the bucket is full", result.Reset)
+	}
+
+	// not allowed takes nothing, so smaller requests still pass
+	result, _ = store.Take("client", limit, 8)
+
+	if result.Allowed || result.Remaining != 7 {
+		t.Fatalf("Take(8) = %+v, want not allowed with 7 remaining", result)
+	}
+
+	if seconds := durationToSeconds(result.RetryAfter); seconds != 1 {
+		t.Errorf("RetryAfter = %v, want about 1s for the missing token", result.RetryAfter)
+	}
+
+	if result, _ = store.Take("client", limit, 7); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("Take(7) = %+v, want allowed with 0 remaining", result)
+	}
+
+	// cost 0 only reads the bucket
+	if result, _ = store.Take("client", limit, 0); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Take(0) = %+v, want allowed with 0 remaining", result)
+	}
+
+	if result, _ = store.Take("client", limit, 1); result.Allowed {
+		t.Errorf("Take(1) of an empty bucket = %+v, want not allowed", result)
+	}
+
+	if result, _ = store.Take("other client", limit, 1); !result.Allowed || result.Remaining != 9 {
+		t.Errorf("Take(1) of another key = %+v, want its own full bucket", result)
+	}
+}
+
+func TestMemoryRateLimitStoreRefill(t *testing.T) {
+	limit := &RateLimit{Requests: 5, Period: 250 * time.Millisecond} // one token per 50ms
+	store := NewMemoryRateLimitStore()
+
+	store.Take("client", limit, limit.Requests)
+
+	if result, _ := store.Take("client", limit, 1); result.Allowed {
+		t.Fatal("Take() allowed from an empty bucket")
+	}
+
+	time.Sleep(120 * time.Millisecond)
+
+	if result, _ := store.Take("client", limit, 2); !result.Allowed {
+		t.Fatalf("Take() = %+v, want allowed after refill", result)
+	}
+
+	time.Sleep(2 * limit.Period)
+
+	if result, _ := store.Take("client", limit, 0); result.Remaining != limit.Requests {
+		t.Errorf("Remaining = %v, want %v, the bucket is not filled above the capacity", result.Remaining, limit.Requests)
+	}
+}
+
+func TestDurationToSeconds(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		want     int64
+	}{
+		{0, 0},
+		{time.Nanosecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+	}
+
+	for _, test := range tests {
+		if got := durationToSeconds(test.duration); got != test.want {
+			t.Errorf("durationToSeconds(%v) = %v, want %v", test.duration, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package go_cake
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const MEMORY_RESPONSE_CACHE_DEFAULT_MAX_ENTRIES = 1000
+
+type memoryCacheEntry struct {
+	resourceName string
+	key          string
+	response     *CachedResponse
+}
+
+// ResponseCache kept in memory, least recently used responses
+// are removed when the cache is full
+type MemoryResponseCache struct {
+	maxEntries   int
+	entries      *list.List
+	keys         map[string]map[string]*list.Element // by resource name
+	invalidated  map[string]time.Time
+	entriesMutex sync.Mutex
+}
+
+func NewMemoryResponseCache(maxEntries int) *MemoryResponseCache {
+	if maxEntries <= 0 {
+		maxEntries = MEMORY_RESPONSE_CACHE_DEFAULT_MAX_ENTRIES
+	}
+
+	return &MemoryResponseCache{
+		maxEntries:  maxEntries,
+		entries:     list.New(),
+		keys:        make(map[string]map[string]*list.Element),
+		invalidated: make(map[string]time.Time),
+	}
+}
+
+func (mrc *MemoryResponseCache) Get(resourceName string, key string) (*CachedResponse, error) {
+	mrc.entriesMutex.Lock()
+	defer mrc.entriesMutex.Unlock()
+
+	element, ok := mrc.keys[resourceName][key]
+
+	if !ok {
+		return nil, nil
+	}
+
+	entry := element.Value.(*memoryCacheEntry)
+
+	if !entry.response.ExpireTime.After(time.Now()) {
+		mrc.removeElement(element)
+
+		return nil, nil
+	}
+
+	mrc.entries.MoveToFront(element)
+
+	return entry.response, nil
+}
+
+func (mrc *MemoryResponseCache) Set(resourceName string, key string, response *CachedResponse) error {
+	mrc.entriesMutex.Lock()
+	defer mrc.entriesMutex.Unlock()
+
+	if invalidatedTime, ok := mrc.invalidated[resourceName]; ok && !response.StoreTime.After(invalidatedTime) {
+		// read before the documents were changed
+		return nil
+	}
+
+	if element, ok := mrc.keys[resourceName][key]; ok {
+		element.Value.(*memoryCacheEntry).response = response
+		mrc.entries.MoveToFront(element)
+
+		return nil
+	}
+
+	if _, ok := mrc.keys[resourceName]; !ok {
+		mrc.keys[resourceName] = make(map[string]*list.Element)
+	}
+
+	mrc.keys[resourceName][key] = mrc.entries.PushFront(&memoryCacheEntry{
+		resourceName: resourceName,
+		key:          key,
+		response:     response,
+	})
+
+	for mrc.entries.Len() > mrc.maxEntries {
+		mrc.removeElement(mrc.entries.Back())
+	}
+
+	return nil
+}
+
+func (mrc *MemoryResponseCache) Invalidate(resourceName string) error {
+	mrc.entriesMutex.Lock()
+	defer mrc.entriesMutex.Unlock()
+
+	for _, element := range mrc.keys[resourceName] {
+		mrc.entries.Remove(element)
+	}
+
+	delete(mrc.keys, resourceName)
+
+	mrc.invalidated[resourceName] = time.Now()
+
+	return nil
+}
+
+func (mrc *MemoryResponseCache) removeElement(element *list.Element) {
+	entry := mrc.entries.Remove(element).(*memoryCacheEntry)
+	resourceKeys := mrc.keys[entry.resourceName]
+
+	delete(resourceKeys, entry.key)
+
+	if len(resourceKeys) == 0 {
+		delete(mrc.keys, entry.resourceName)
+	}
+}
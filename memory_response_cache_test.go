@@ -0,0 +1,113 @@
+package go_cake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryResponseCacheEviction(t *testing.T) {
+	tests := []struct {
+		name     string
+		calls    []string // "set:key" or "get:key"
+		wantKeys []string // left of a, b and c
+	}{
+		{
+			name:     "least recently set evicted",
+			calls:    []string{"set:a", "set:b", "set:c"},
+			wantKeys: []string{"b", "c"},
+		},
+		{
+			name:     "read entry kept",
+			calls:    []string{"set:a", "set:b", "get:a", "set:c"},
+			wantKeys: []string{"a", "c"},
+		},
+		{
+			name:     "replaced entry kept",
+			calls:    []string{"set:a", "set:b", "set:a", "set:c"},
+			wantKeys: []string{"a", "c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache := NewMemoryResponseCache(2)
+
+			for _, call := range test.calls {
+				if key := call[4:]; call[:4] == "get:" {
+					cache.Get("users", key)
+				} else if err := cache.Set("users", key, newTestCachedResponse(key, time.Minute)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			keys := make([]string, 0)
+
+			for _, key := range []string{"a", "b", "c"} {
+				if response, _ := cache.Get("users", key); response != nil {
+					keys = append(keys, string(response.Body))
+				}
+			}
+
+			if len(keys) != len(test.wantKeys) || keys[0] != test.wantKeys[0] || keys[1] != test.wantKeys[1] {
+				t.Errorf("cached keys = %v, want %v", keys, test.wantKeys)
+			}
+		})
+	}
+}
+
+func TestMemoryResponseCacheExpiry(t *testing.T) {
+	cache := NewMemoryResponseCache(0)
+
+	cache.Set("users", "a", newTestCachedResponse("a", -time.Second))
+
+	if response, _ := cache.Get("users", "a"); response != nil {
+		t.Fatalf("Get() = %s, want nil for an expired response", response.Body)
+	}
+}
+
+func TestMemoryResponseCacheInvalidate(t *testing.T) {
+	cache := NewMemoryResponseCache(0)
+
+	// read by a GET running concurrently with the write
+	staleResponse := newTestCachedResponse("stale", time.Minute)
+
+	cache.Set("users", "a", newTestCachedResponse("a", time.Minute))
+	cache.Set("orders", "a", newTestCachedResponse("a", time.Minute))
+
+	if err := cache.Invalidate("users"); err != nil {
+		t.Fatal(err)
+	}
+
+	if response, _ := cache.Get("users", "a"); response != nil {
+		t.Errorf("Get() = %s after Invalidate(), want nil", response.Body)
+	}
+
+	if response, _ := cache.Get("orders", "a"); response == nil {
+		t.Error("Get() = nil, want the response of another resource kept")
+	}
+
+	cache.Set("users", "b", staleResponse)
+
+	if response, _ := cache.Get("users", "b"); response != nil {
+		t.Errorf("Get() = %s, want the response read before Invalidate() not stored", response.Body)
+	}
+
+	freshResponse := newTestCachedResponse("fresh", time.Minute)
+	freshResponse.StoreTime = freshResponse.StoreTime.Add(time.Millisecond) // coarse clocks
+
+	cache.Set("users", "b", freshResponse)
+
+	if response, _ := cache.Get("users", "b"); response == nil || string(response.Body) != "fresh" {
+		t.Error("Get() = nil, want the response read after Invalidate()")
+	}
+}
+
+func newTestCachedResponse(body string, ttl time.Duration) *CachedResponse {
+	now := time.Now()
+
+	return &CachedResponse{
+		Body:       []byte(body),
+		StoreTime:  now,
+		ExpireTime: now.Add(ttl),
+	}
+}
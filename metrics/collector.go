@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+)
+
+const DEFAULT_NAMESPACE = "go_cake"
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+const OPERATION_CORS = "cors"
+const OPERATION_UNKNOWN = "unknown"
+const VERSION_UNSUPPORTED = "unsupported"
+const RESULT_OK = "ok"
+const RESULT_ERROR = "error"
+const ITEMS_INPUT = "input"
+const ITEMS_OUTPUT = "output"
+
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+var DefaultItemsBuckets = []float64{0, 1, 5, 10, 50, 100, 500, 1000}
+
+type CollectorConfig struct {
+	Namespace       string    // prefix of the metric names, DEFAULT_NAMESPACE by default
+	DurationBuckets []float64 // seconds
+	ItemsBuckets    []float64
+}
+
+// Collects request and driver metrics, add it to the handler
+// by go_cake.Handler.AddRequestObserver(), wrap the drivers by
+// InstrumentDriver() and serve it as the scrape endpoint
+type Collector struct {
+	requests       *CounterVec
+	requestLatency *HistogramVec
+	requestItems   *HistogramVec
+	driverLatency  *HistogramVec
+	driverItems    *HistogramVec
+	metrics        []metric
+}
+
+func NewCollector(config CollectorConfig) *Collector {
+	if config.Namespace == "" {
+		config.Namespace = DEFAULT_NAMESPACE
+	}
+
+	if len(config.DurationBuckets) == 0 {
+		config.DurationBuckets = DefaultDurationBuckets
+	}
+
+	if len(config.ItemsBuckets) == 0 {
+		config.ItemsBuckets = DefaultItemsBuckets
+	}
+
+	requestLabels := []string{"resource", "operation", "status", "version"}
+	driverLabels := []string{"method", "db_path"}
+
+	collector := Collector{
+		requests: NewCounterVec(
+			config.Namespace+"_requests_total",
+			"Number of processed requests.",
+			requestLabels),
+		requestLatency: NewHistogramVec(
+			config.Namespace+"_request_duration_seconds",
+			"Request processing time.",
+			requestLabels,
+			config.DurationBuckets),
+		requestItems: NewHistogramVec(
+			config.Namespace+"_request_items",
+			"Number of payload (input) and response (output) items per request.",
+			[]string{"resource", "operation", "direction"},
+			config.ItemsBuckets),
+		driverLatency: NewHistogramVec(
+			config.Namespace+"_driver_call_duration_seconds",
+			"Database driver call time.",
+			[]string{"method", "db_path", "result"},
+			config.DurationBuckets),
+		driverItems: NewHistogramVec(
+			config.Namespace+"_driver_call_documents",
+			"Number of documents per database driver write call.",
+			driverLabels,
+			config.ItemsBuckets),
+	}
+
+	collector.metrics = []metric{
+		collector.requests,
+		collector.requestLatency,
+		collector.requestItems,
+		collector.driverLatency,
+		collector.driverItems,
+	}
+
+	return &collector
+}
+
+// go_cake.RequestObserver
+func (c *Collector) ObserveRequest(stats *go_cake.RequestStats) {
+	resource := stats.Resource.ResourceName
+	operation := c.getOperation(stats.Request)
+	status := strconv.Itoa(stats.StatusCode)
+	version := c.getVersion(stats)
+
+	c.requests.Inc(resource, operation, status, version)
+	c.requestLatency.Observe(stats.Duration.Seconds(), resource, operation, status, version)
+
+	if stats.InputItems > 0 {
+		c.requestItems.Observe(float64(stats.InputItems), resource, operation, ITEMS_INPUT)
+	}
+
+	c.requestItems.Observe(float64(stats.OutputItems), resource, operation, ITEMS_OUTPUT)
+}
+
+func (c *Collector) getOperation(request *go_cake.Request) string {
+	if request.IsCORS {
+		return OPERATION_CORS
+	}
+
+	if operation := request.Operation(); operation != "" {
+		return operation
+	}
+
+	// not parsed request
+	return OPERATION_UNKNOWN
+}
+
+// Supported version pattern instead of the version from the
+// URL so clients cannot create new series
+func (c *Collector) getVersion(stats *go_cake.RequestStats) string {
+	if version := stats.Resource.GetMatchingSupportedVersion(stats.Request.Version); version != "" {
+		return version
+	}
+
+	return VERSION_UNSUPPORTED
+}
+
+// Scrape endpoint
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buffer bytes.Buffer
+
+	for _, metric := range c.metrics {
+		metric.write(&buffer)
+	}
+
+	w.Header().Set("Content-Type", CONTENT_TYPE)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method != http.MethodHead {
+		w.Write(buffer.Bytes())
+	}
+}
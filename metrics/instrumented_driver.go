@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"time"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+)
+
+type instrumentedDriver struct {
+	driver    go_cake.DatabaseDriver
+	collector *Collector
+}
+
+// Wraps the driver to measure the time of each call, use the
+// result as go_cake.Resource.DatabaseDriver
+func (c *Collector) InstrumentDriver(driver go_cake.DatabaseDriver) go_cake.DatabaseDriver {
+	return &instrumentedDriver{driver: driver, collector: c}
+}
+
+func (id *instrumentedDriver) observe(method string, dbPath string, timeStart time.Time, httpErr go_cake.HTTPError) {
+	result := RESULT_OK
+
+	if httpErr != nil {
+		result = RESULT_ERROR
+	}
+
+	id.collector.driverLatency.Observe(time.Since(timeStart).Seconds(), method, dbPath, result)
+}
+
+func (id *instrumentedDriver) observeDocuments(method string, dbPath string, documents []go_cake.GoCakeModel) {
+	id.collector.driverItems.Observe(float64(len(documents)), method, dbPath)
+}
+
+func (id *instrumentedDriver) Unwrap() go_cake.DatabaseDriver {
+	return id.driver
+}
+
+// Ping(), Stats() and Close() are forwarded to the wrapped
+// driver, no-ops if it does not implement them
+func (id *instrumentedDriver) Ping(ctx context.Context) error {
+	if pinger, ok := id.driver.(go_cake.DatabaseDriverPinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (id *instrumentedDriver) Stats() map[string]any {
+	if statsProvider, ok := id.driver.(go_cake.DatabaseDriverStatsProvider); ok {
+		return statsProvider.Stats()
+	}
+
+	return nil
+}
+
+func (id *instrumentedDriver) Close() error {
+	if closer, ok := id.driver.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func (id *instrumentedDriver) GetUnderlyingDriver() any {
+	return id.driver.GetUnderlyingDriver()
+}
+
+func (id *instrumentedDriver) TestModel(
+	idField string,
+	etagField string,
+	model go_cake.GoCakeModel,
+	dbPath string) error {
+	return id.driver.TestModel(idField, etagField, model, dbPath)
+}
+
+func (id *instrumentedDriver) Find(
+	model go_cake.GoCakeModel,
+	dbPath string,
+	where, sort string,
+	search *go_cake.SearchQuery,
+	geo *go_cake.GeoQuery,
+	serverFilter map[string]any,
+	page, perPage int64,
+	ctx context.Context,
+	userData any) ([]go_cake.GoCakeModel, go_cake.HTTPError) {
+	timeStart := time.Now()
+
+	documents, httpErr := id.driver.Find(
+		model, dbPath, where, sort, search, geo, serverFilter, page, perPage, ctx, userData)
+
+	id.observe("Find", dbPath, timeStart, httpErr)
+
+	return documents, httpErr
+}
+
+func (id *instrumentedDriver) Delete(
+	model go_cake.GoCakeModel,
+	dbPath string,
+	documents []go_cake.GoCakeModel,
+	serverFilter map[string]any,
+	ctx context.Context,
+	userData any) go_cake.HTTPError {
+	timeStart := time.Now()
+
+	httpErr := id.driver.Delete(model, dbPath, documents, serverFilter, ctx, userData)
+
+	id.observe("Delete", dbPath, timeStart, httpErr)
+	id.observeDocuments("Delete", dbPath, documents)
+
+	return httpErr
+}
+
+func (id *instrumentedDriver) Total(
+	model go_cake.GoCakeModel,
+	dbPath string,
+	where string,
+	search *go_cake.SearchQuery,
+	geo *go_cake.GeoQuery,
+	serverFilter map[string]any,
+	ctx context.Context,
+	userData any) (uint64, go_cake.HTTPError) {
+	timeStart := time.Now()
+
+	total, httpErr := id.driver.Total(model, dbPath, where, search, geo, serverFilter, ctx, userData)
+
+	id.observe("Total", dbPath, timeStart, httpErr)
+
+	return total, httpErr
+}
+
+func (id *instrumentedDriver) Insert(
+	model go_cake.GoCakeModel,
+	dbPath string,
+	documents []go_cake.GoCakeModel,
+	ctx context.Context,
+	userData any) go_cake.HTTPError {
+	timeStart := time.Now()
+
+	httpErr := id.driver.Insert(model, dbPath, documents, ctx, userData)
+
+	id.observe("Insert", dbPath, timeStart, httpErr)
+	id.observeDocuments("Insert", dbPath, documents)
+
+	return httpErr
+}
+
+func (id *instrumentedDriver) Update(
+	model go_cake.GoCakeModel,
+	dbPath string,
+	documents []go_cake.GoCakeModel,
+	serverFilter map[string]any,
+	ctx context.Context,
+	userData any) go_cake.HTTPError {
+	timeStart := time.Now()
+
+	httpErr := id.driver.Update(model, dbPath, documents, serverFilter, ctx, userData)
+
+	id.observe("Update", dbPath, timeStart, httpErr)
+	id.observeDocuments("Update", dbPath, documents)
+
+	return httpErr
+}
+
+func (id *instrumentedDriver) UpdateFields(
+	model go_cake.GoCakeModel,
+	dbPath string,
+	documents []go_cake.GoCakeModel,
+	jsonFields []string,
+	serverFilter map[string]any,
+	ctx context.Context,
+	userData any) go_cake.HTTPError {
+	timeStart := time.Now()
+
+	httpErr := id.driver.UpdateFields(model, dbPath, documents, jsonFields, serverFilter, ctx, userData)
+
+	id.observe("UpdateFields", dbPath, timeStart, httpErr)
+	id.observeDocuments("UpdateFields", dbPath, documents)
+
+	return httpErr
+}
+
+func (id *instrumentedDriver) GetWhereFields(model go_cake.GoCakeModel, where string) ([]string, go_cake.HTTPError) {
+	return id.driver.GetWhereFields(model, where)
+}
+
+func (id *instrumentedDriver) GetSortFields(model go_cake.GoCakeModel, sort string) ([]string, go_cake.HTTPError) {
+	return id.driver.GetSortFields(model, sort)
+}
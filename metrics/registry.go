@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Minimal counters and histograms with labels, written in
+// Prometheus text exposition format
+type metric interface {
+	write(writer io.Writer)
+}
+
+type series struct {
+	labelValues []string
+	value       float64  // counter
+	bucketsHits []uint64 // histogram, cumulative when written
+	sum         float64  // histogram
+	count       uint64   // histogram
+}
+
+type metricVec struct {
+	name        string
+	help        string
+	metricType  string
+	labelNames  []string
+	buckets     []float64 // histogram upper bounds, sorted
+	series      map[string]*series
+	seriesMutex sync.Mutex
+}
+
+type CounterVec struct {
+	metricVec
+}
+
+type HistogramVec struct {
+	metricVec
+}
+
+func NewCounterVec(name, help string, labelNames []string) *CounterVec {
+	return &CounterVec{metricVec{
+		name:       name,
+		help:       help,
+		metricType: "counter",
+		labelNames: labelNames,
+		series:     make(map[string]*series),
+	}}
+}
+
+func NewHistogramVec(name, help string, labelNames []string, buckets []float64) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &HistogramVec{metricVec{
+		name:       name,
+		help:       help,
+		metricType: "histogram",
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}}
+}
+
+// Must be called with seriesMutex locked
+func (mv *metricVec) getSeries(labelValues []string) *series {
+	if len(labelValues) != len(mv.labelNames) {
+		panic(fmt.Sprintf("%v expects %v label values, got %v", mv.name, len(mv.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	s, ok := mv.series[key]
+
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+
+		if mv.buckets != nil {
+			s.bucketsHits = make([]uint64, len(mv.buckets))
+		}
+
+		mv.series[key] = s
+	}
+
+	return s
+}
+
+func (cv *CounterVec) Add(value float64, labelValues ...string) {
+	cv.seriesMutex.Lock()
+	defer cv.seriesMutex.Unlock()
+
+	cv.getSeries(labelValues).value += value
+}
+
+func (cv *CounterVec) Inc(labelValues ...string) {
+	cv.Add(1, labelValues...)
+}
+
+func (hv *HistogramVec) Observe(value float64, labelValues ...string) {
+	hv.seriesMutex.Lock()
+	defer hv.seriesMutex.Unlock()
+
+	s := hv.getSeries(labelValues)
+
+	for i, bucket := range hv.buckets {
+		if value <= bucket {
+			s.bucketsHits[i]++
+			break
+		}
+	}
+
+	s.sum += value
+	s.count++
+}
+
+func (mv *metricVec) write(writer io.Writer) {
+	mv.seriesMutex.Lock()
+	defer mv.seriesMutex.Unlock()
+
+	if len(mv.series) == 0 {
+		return
+	}
+
+	fmt.Fprintf(writer, "# HELP %v %v\n", mv.name, escapeHelp(mv.help))
+	fmt.Fprintf(writer, "# TYPE %v %v\n", mv.name, mv.metricType)
+
+	keys := make([]string, 0, len(mv.series))
+
+	for key := range mv.series {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := mv.series[key]
+		labels := mv.formatLabels(s.labelValues)
+
+		if mv.buckets == nil {
+			fmt.Fprintf(writer, "%v%v %v\n", mv.name, wrapLabels(labels), formatFloat(s.value))
+			continue
+		}
+
+		cumulative := uint64(0)
+
+		for i, bucket := range mv.buckets {
+			cumulative += s.bucketsHits[i]
+
+			fmt.Fprintf(writer, "%v_bucket%v %v\n",
+				mv.name,
+				wrapLabels(appendLabel(labels, "le", formatFloat(bucket))),
+				cumulative)
+		}
+
+		fmt.Fprintf(writer, "%v_bucket%v %v\n", mv.name, wrapLabels(appendLabel(labels, "le", "+Inf")), s.count)
+		fmt.Fprintf(writer, "%v_sum%v %v\n", mv.name, wrapLabels(labels), formatFloat(s.sum))
+		fmt.Fprintf(writer, "%v_count%v %v\n", mv.name, wrapLabels(labels), s.count)
+	}
+}
+
+func (mv *metricVec) formatLabels(labelValues []string) string {
+	pairs := make([]string, 0, len(labelValues))
+
+	for i, value := range labelValues {
+		pairs = append(pairs, fmt.Sprintf(`%v="%v"`, mv.labelNames[i], escapeLabelValue(value)))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func appendLabel(labels string, name string, value string) string {
+	pair := fmt.Sprintf(`%v="%v"`, name, value)
+
+	if labels == "" {
+		return pair
+	}
+
+	return labels + "," + pair
+}
+
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+
+	return "{" + labels + "}"
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
@@ -0,0 +1,223 @@
+package go_cake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const COMPRESSION_MIN_SIZE = 1024
+const COMPRESSION_ENCODING_GZIP = "gzip"
+const COMPRESSION_ENCODING_DEFLATE = "deflate"
+
+// in order of preference
+var compressionEncodings = []string{
+	COMPRESSION_ENCODING_GZIP,
+	COMPRESSION_ENCODING_DEFLATE}
+
+// Compresses responses with gzip or deflate depending on
+// Accept-Encoding request header, uses COMPRESSION_MIN_SIZE
+// and default compression level
+func CompressionMiddleware(next http.Handler) http.Handler {
+	return NewCompressionMiddleware(COMPRESSION_MIN_SIZE, gzip.DefaultCompression)(next)
+}
+
+// Bodies smaller than minSize are sent uncompressed, level is
+// one of compress/flate levels
+func NewCompressionMiddleware(minSize int, level int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			cw := &compressionResponseWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				minSize:        minSize,
+				level:          level,
+				statusCode:     http.StatusOK,
+			}
+
+			next.ServeHTTP(cw, r)
+
+			cw.finish()
+		})
+	}
+}
+
+// Returns the best supported encoding from Accept-Encoding
+// header value, or empty string if none is acceptable
+func negotiateEncoding(acceptEncoding string) string {
+	bestEncoding := ""
+	bestQuality := 0.0
+
+	qualities := make(map[string]float64)
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+
+		if name == "" {
+			continue
+		}
+
+		quality := 1.0
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+
+			if err != nil {
+				parsed = 0
+			}
+
+			quality = parsed
+		}
+
+		qualities[name] = quality
+	}
+
+	for _, encoding := range compressionEncodings {
+		quality, ok := qualities[encoding]
+
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+
+		if !ok || quality <= 0 {
+			continue
+		}
+
+		if quality > bestQuality {
+			bestEncoding = encoding
+			bestQuality = quality
+		}
+	}
+
+	return bestEncoding
+}
+
+// Buffers the response so its size is known before choosing
+// whether to compress it, Flush() switches to pass-through
+// mode for streaming responses
+type compressionResponseWriter struct {
+	http.ResponseWriter
+	encoding      string
+	minSize       int
+	level         int
+	statusCode    int
+	buffer        bytes.Buffer
+	headerWritten bool
+	passThrough   bool
+}
+
+func (cw *compressionResponseWriter) WriteHeader(statusCode int) {
+	if cw.headerWritten {
+		return
+	}
+
+	cw.statusCode = statusCode
+	cw.headerWritten = true
+}
+
+func (cw *compressionResponseWriter) Write(data []byte) (int, error) {
+	if cw.passThrough {
+		return cw.ResponseWriter.Write(data)
+	}
+
+	cw.headerWritten = true
+
+	return cw.buffer.Write(data)
+}
+
+func (cw *compressionResponseWriter) Flush() {
+	if !cw.passThrough {
+		cw.passThrough = true
+
+		cw.ResponseWriter.WriteHeader(cw.statusCode)
+		cw.ResponseWriter.Write(cw.buffer.Bytes())
+		cw.buffer.Reset()
+	}
+
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (cw *compressionResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressionResponseWriter) canCompress() bool {
+	if cw.buffer.Len() < cw.minSize {
+		return false
+	}
+
+	if cw.statusCode < http.StatusOK ||
+		cw.statusCode == http.StatusNoContent ||
+		cw.statusCode == http.StatusNotModified {
+		return false
+	}
+
+	// already encoded by the handler
+	return cw.Header().Get("Content-Encoding") == ""
+}
+
+func (cw *compressionResponseWriter) newEncoder(w io.Writer) (io.WriteCloser, error) {
+	if cw.encoding == COMPRESSION_ENCODING_DEFLATE {
+		return zlib.NewWriterLevel(w, cw.level)
+	}
+
+	return gzip.NewWriterLevel(w, cw.level)
+}
+
+func (cw *compressionResponseWriter) finish() {
+	if cw.passThrough {
+		return
+	}
+
+	body := cw.buffer.Bytes()
+
+	if cw.canCompress() {
+		var compressed bytes.Buffer
+
+		encoder, err := cw.newEncoder(&compressed)
+
+		if err == nil {
+			_, err = encoder.Write(body)
+
+			if closeErr := encoder.Close(); err == nil {
+				err = closeErr
+			}
+		}
+
+		if err == nil {
+			body = compressed.Bytes()
+
+			cw.Header().Set("Content-Encoding", cw.encoding)
+		}
+	}
+
+	if cw.Header().Get("Content-Length") != "" {
+		cw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+	cw.ResponseWriter.Write(body)
+}
@@ -0,0 +1,113 @@
+package go_cake
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// RFC 8288 pagination links, pages are counted from 0
+// like the "page" URL parameter
+type PaginationLinks struct {
+	First string
+	Prev  string
+	Next  string
+	Last  string
+}
+
+func NewPaginationLinks(requestURL *url.URL, page, perPage int64, total uint64) *PaginationLinks {
+	links := PaginationLinks{}
+
+	if requestURL == nil || perPage <= 0 {
+		return &links
+	}
+
+	lastPage := int64(0)
+
+	if total > 0 {
+		lastPage = (int64(total) - 1) / perPage
+	}
+
+	links.First = links.pageURL(requestURL, 0, perPage)
+	links.Last = links.pageURL(requestURL, lastPage, perPage)
+
+	if page > 0 {
+		prevPage := page - 1
+
+		if prevPage > lastPage {
+			prevPage = lastPage
+		}
+
+		links.Prev = links.pageURL(requestURL, prevPage, perPage)
+	}
+
+	if page < lastPage {
+		links.Next = links.pageURL(requestURL, page+1, perPage)
+	}
+
+	return &links
+}
+
+// Links of the collection page for both the Link header and the
+// envelope, nil if the response is not a page of the collection
+func newResponsePaginationLinks(request *Request, response *ResponseJSON) *PaginationLinks {
+	if !request.IsGet ||
+		request.HasItemID() ||
+		request.Request == nil ||
+		response.Meta.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	return NewPaginationLinks(
+		request.Request.URL,
+		response.Meta.Page,
+		response.Meta.PerPage,
+		response.Meta.Total)
+}
+
+func (pl *PaginationLinks) pageURL(requestURL *url.URL, page, perPage int64) string {
+	pageURL := *requestURL
+	query := pageURL.Query()
+
+	query.Set("page", strconv.FormatInt(page, 10))
+	query.Set("per_page", strconv.FormatInt(perPage, 10))
+
+	pageURL.RawQuery = query.Encode()
+
+	return pageURL.String()
+}
+
+func (pl *PaginationLinks) ToMap() map[string]string {
+	links := make(map[string]string)
+
+	for rel, link := range map[string]string{
+		"first": pl.First,
+		"prev":  pl.Prev,
+		"next":  pl.Next,
+		"last":  pl.Last} {
+		if link != "" {
+			links[rel] = link
+		}
+	}
+
+	return links
+}
+
+func (pl *PaginationLinks) ToLinkHeader() string {
+	parts := make([]string, 0)
+	links := pl.ToMap()
+
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		link, ok := links[rel]
+
+		if !ok {
+			continue
+		}
+
+		parts = append(parts, fmt.Sprintf(`<%s>; rel="%s"`, link, rel))
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,42 @@
+package go_cake
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestPaginationLinksOfHeaderAndEnvelope(t *testing.T) {
+	handler, resource := newTestHandler(t, newTestDriver("a", "b", "c", "d", "e"))
+
+	resource.HATEOAS = true
+
+	recorder := serveTestRequest(handler, http.MethodGet, "/v1/api/items?page=1&per_page=2", "")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v", recorder.Code, http.StatusOK)
+	}
+
+	links := decodeTestResponse(t, recorder).Links
+	wantHeader := ""
+
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		if links[rel] == "" {
+			t.Fatalf("_links = %v, want %v link", links, rel)
+		}
+
+		if wantHeader != "" {
+			wantHeader += ", "
+		}
+
+		wantHeader += fmt.Sprintf(`<%s>; rel="%s"`, links[rel], rel)
+	}
+
+	if linkHeader := recorder.Header().Get("Link"); linkHeader != wantHeader {
+		t.Errorf("Link = %q, want the _links of the envelope %q", linkHeader, wantHeader)
+	}
+
+	if links["last"] != "/v1/api/items?page=2&per_page=2" {
+		t.Errorf("last = %q, want page 2", links["last"])
+	}
+}
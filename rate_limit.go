@@ -0,0 +1,126 @@
+package go_cake
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const RATE_LIMIT_AUTH_FAILURES_KEY_PREFIX = "auth_failures|"
+
+// Token bucket of Requests tokens refilled evenly over Period
+type RateLimit struct {
+	Requests int64
+	Period   time.Duration
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int64
+	Reset      time.Duration // until the bucket is full
+	RetryAfter time.Duration // until cost tokens are available, if not allowed
+}
+
+// Storage of the token buckets, implementations must be
+// safe for concurrent use, cost 0 returns the state of the
+// bucket without taking tokens
+type RateLimitStore interface {
+	Take(key string, limit *RateLimit, cost int64) (*RateLimitResult, error)
+}
+
+// app callback, returns the client part of the bucket key,
+// empty string skips the limit
+type RateLimitKeyCallback func(
+	resource *Resource,
+	request *Request) string
+
+// Buckets are kept per resource, operation and client, the client
+// is the auth subject or the IP address by default, failed auths
+// are counted per client IP in all resources and checked before
+// the auth so the credentials cannot be guessed
+type RateLimitConfig struct {
+	Store          RateLimitStore        // MemoryRateLimitStore if nil
+	Default        *RateLimit            // operations without own limit, unlimited if nil
+	Operations     map[string]*RateLimit // per OPERATION_* limits
+	AuthFailures   *RateLimit            // Default if nil
+	KeyCallback    RateLimitKeyCallback
+	CostPerItem    bool // bulk requests take one token per payload item, the full bucket at most
+	TrustProxy     bool // client IP from X-Forwarded-For header
+	TrustedProxies int  // proxies appending to X-Forwarded-For, 1 if 0
+	storeOnce      sync.Once
+}
+
+func NewRateLimitConfig(defaultLimit *RateLimit) *RateLimitConfig {
+	return &RateLimitConfig{
+		Store:      NewMemoryRateLimitStore(),
+		Default:    defaultLimit,
+		Operations: make(map[string]*RateLimit),
+	}
+}
+
+func (rlc *RateLimitConfig) SetOperationLimit(operation string, limit *RateLimit) *RateLimitConfig {
+	if rlc.Operations == nil {
+		rlc.Operations = make(map[string]*RateLimit)
+	}
+
+	rlc.Operations[operation] = limit
+
+	return rlc
+}
+
+func (rlc *RateLimitConfig) GetStore() RateLimitStore {
+	rlc.storeOnce.Do(func() {
+		if rlc.Store == nil {
+			rlc.Store = NewMemoryRateLimitStore()
+		}
+	})
+
+	return rlc.Store
+}
+
+func (rlc *RateLimitConfig) GetLimit(operation string) *RateLimit {
+	if limit, ok := rlc.Operations[operation]; ok {
+		return limit
+	}
+
+	return rlc.Default
+}
+
+func (rlc *RateLimitConfig) GetClientKey(resource *Resource, request *Request) string {
+	if rlc.KeyCallback != nil {
+		return rlc.KeyCallback(resource, request)
+	}
+
+	if request.Identity != nil && request.Identity.Subject != "" {
+		return "subject:" + request.Identity.Provider + ":" + request.Identity.Subject
+	}
+
+	return rlc.GetClientIPKey(request)
+}
+
+func (rlc *RateLimitConfig) GetClientIPKey(request *Request) string {
+	if request.Request == nil {
+		return ""
+	}
+
+	trustedProxies := 0
+
+	if rlc.TrustProxy {
+		trustedProxies = max(rlc.TrustedProxies, 1)
+	}
+
+	return "ip:" + GetClientIP(request.Request, trustedProxies)
+}
+
+func (rlc *RateLimitConfig) GetAuthFailuresLimit() *RateLimit {
+	if rlc.AuthFailures != nil {
+		return rlc.AuthFailures
+	}
+
+	return rlc.Default
+}
+
+// Whole seconds, rounded up, as used by Retry-After header
+func durationToSeconds(duration time.Duration) int64 {
+	return int64(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,164 @@
+package go_cake
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitRejectsWithRetryAfter(t *testing.T) {
+	driver := newTestDriver("a", "b")
+	handler, resource := newTestHandler(t, driver)
+
+	// one token per 30 seconds
+	resource.RateLimitConfig = NewRateLimitConfig(&RateLimit{Requests: 2, Period: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		recorder := serveTestRequest(handler, http.MethodGet, "/v1/api/items", "")
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("request %v status = %v, want %v", i, recorder.Code, http.StatusOK)
+		}
+
+		if remaining := recorder.Header().Get("RateLimit-Remaining"); remaining != strconv.Itoa(1-i) {
+			t.Errorf("request %v RateLimit-Remaining = %q, want %v", i, remaining, 1-i)
+		}
+	}
+
+	recorder := serveTestRequest(handler, http.MethodGet, "/v1/api/items", "")
+
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %v, want %v", recorder.Code, http.StatusTooManyRequests)
+	}
+
+	if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != "30" {
+		t.Errorf("Retry-After = %q, want 30", retryAfter)
+	}
+
+	if limit := recorder.Header().Get("RateLimit-Limit"); limit != "2" {
+		t.Errorf("RateLimit-Limit = %q, want 2", limit)
+	}
+
+	if driver.findCalls != 2 {
+		t.Errorf("Find called %v times, want 2, the rejected request must not reach the driver", driver.findCalls)
+	}
+
+	// other clients and operations have their own buckets
+	recorder = serveTestRequest(handler, http.MethodGet, "/v1/api/items", "", "X-Forwarded-For", "198.51.100.7")
+
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %v with X-Forwarded-For and no TrustProxy, want %v", recorder.Code, http.StatusTooManyRequests)
+	}
+
+	resource.RateLimitConfig.TrustProxy = true
+
+	if recorder = serveTestRequest(handler, http.MethodGet, "/v1/api/items", "", "X-Forwarded-For", "198.51.100.7"); recorder.Code != http.StatusOK {
+		t.Errorf("status of another client = %v, want %v", recorder.Code, http.StatusOK)
+	}
+
+	if recorder = serveTestRequest(handler, http.MethodPost, "/v1/api/items", `[{"name":"c"}]`); recorder.Code != http.StatusOK {
+		t.Errorf("status of another operation = %v, want %v", recorder.Code, http.StatusOK)
+	}
+}
+
+func TestRateLimitCostPerItem(t *testing.T) {
+	driver := newTestDriver()
+	handler, resource := newTestHandler(t, driver)
+
+	resource.RateLimitConfig = NewRateLimitConfig(&RateLimit{Requests: 5, Period: time.Minute})
+	resource.RateLimitConfig.CostPerItem = true
+
+	recorder := serveTestRequest(handler, http.MethodPost, "/v1/api/items", `[{"name":"a"},{"name":"b"},{"name":"c"}]`)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v", recorder.Code, http.StatusOK)
+	}
+
+	if remaining := recorder.Header().Get("RateLimit-Remaining"); remaining != "2" {
+		t.Errorf("RateLimit-Remaining = %q, want 2 after 3 items", remaining)
+	}
+
+	recorder = serveTestRequest(handler, http.MethodPost, "/v1/api/items", `[{"name":"d"},{"name":"e"},{"name":"f"}]`)
+
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %v, want %v", recorder.Code, http.StatusTooManyRequests)
+	}
+
+	if len(driver.items) != 3 {
+		t.Errorf("%v items inserted, want 3", len(driver.items))
+	}
+}
+
+func TestRateLimitCostAboveLimit(t *testing.T) {
+	driver := newTestDriver()
+	handler, resource := newTestHandler(t, driver)
+
+	resource.RateLimitConfig = NewRateLimitConfig(&RateLimit{Requests: 2, Period: time.Minute})
+	resource.RateLimitConfig.CostPerItem = true
+
+	// takes the full bucket instead of being rejected for good
+	recorder := serveTestRequest(handler, http.MethodPost, "/v1/api/items", `[{"name":"a"},{"name":"b"},{"name":"c"}]`)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v", recorder.Code, http.StatusOK)
+	}
+
+	if remaining := recorder.Header().Get("RateLimit-Remaining"); remaining != "0" {
+		t.Errorf("RateLimit-Remaining = %q, want 0", remaining)
+	}
+
+	recorder = serveTestRequest(handler, http.MethodPost, "/v1/api/items", `[{"name":"d"},{"name":"e"},{"name":"f"}]`)
+
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %v, want %v", recorder.Code, http.StatusTooManyRequests)
+	}
+
+	if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != "60" {
+		t.Errorf("Retry-After = %q, want 60 until the bucket is full", retryAfter)
+	}
+}
+
+func TestRateLimitFailedAuths(t *testing.T) {
+	handler, resource := newTestHandler(t, newTestDriver("a"))
+
+	resource.RateLimitConfig = NewRateLimitConfig(nil)
+	resource.RateLimitConfig.AuthFailures = &RateLimit{Requests: 2, Period: time.Minute}
+	resource.ResourceCallback.AuthErrorCallback = func(
+		resource *Resource,
+		request *Request,
+		response *ResponseJSON) HTTPError {
+		if request.Request.Header.Get("Authorization") != "Bearer valid" {
+			return NewUnauthorizedHTTPError(nil)
+		}
+
+		return nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if recorder := serveTestRequest(handler, http.MethodGet, "/v1/api/items", "", "Authorization", "Bearer guess"); recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("status of failed auth %v = %v, want %v", i, recorder.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// rejected before the auth, even with valid credentials
+	recorder := serveTestRequest(handler, http.MethodGet, "/v1/api/items", "", "Authorization", "Bearer valid")
+
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status after the failed auths = %v, want %v", recorder.Code, http.StatusTooManyRequests)
+	}
+
+	if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != "30" {
+		t.Errorf("Retry-After = %q, want 30", retryAfter)
+	}
+
+	if recorder = serveTestRequest(handler, http.MethodGet, "/v1/api/items", "", "Authorization", "Bearer valid", "X-Forwarded-For", "203.0.113.9"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("status with X-Forwarded-For and no TrustProxy = %v, want %v", recorder.Code, http.StatusTooManyRequests)
+	}
+
+	resource.RateLimitConfig.TrustProxy = true
+
+	if recorder = serveTestRequest(handler, http.MethodGet, "/v1/api/items", "", "Authorization", "Bearer valid", "X-Forwarded-For", "203.0.113.9"); recorder.Code != http.StatusOK {
+		t.Errorf("status of another client IP = %v, want %v", recorder.Code, http.StatusOK)
+	}
+}
@@ -1,6 +1,7 @@
 package go_cake
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/skazanyNaGlany/go-cake/utils"
 	"github.com/thoas/go-funk"
@@ -16,19 +18,29 @@ import (
 
 var allowedAcceptValues []string = []string{
 	ALLOWED_ACCEPT_HEADER_0,
-	ALLOWED_ACCEPT_HEADER_1}
+	ALLOWED_ACCEPT_HEADER_1,
+	ALLOWED_ACCEPT_HEADER_2}
 
 type Request struct {
 	ResourcePattern  *regexp.Regexp
 	Version          string
 	Resource         string
+	CollectionPath   string
+	ItemID           string
+	Action           string
 	Where            string
 	Sort             string
+	Search           string
+	SortByScore      bool
+	IncludeDeleted   bool
+	DocumentVersion  int64
+	Geo              *GeoQuery
 	Projection       map[string]bool
 	ProjectionFields []string
 	Page             int64
 	PerPage          int64
 	UniqueID         string
+	TraceID          string // OpenTelemetry trace ID, zeros without traceparent and tracer provider
 	Method           string
 	URL              string
 	Body             []byte
@@ -37,11 +49,18 @@ type Request struct {
 	Request          *http.Request
 	ResponseWriter   http.ResponseWriter
 	UserData         any
+	Identity         *AuthIdentity
+	Tenant           string
+	IdempotencyKey   string
+	ClientTimeout    time.Duration // from REQUEST_TIMEOUT_HEADER, 0 if not sent
+	Deadline         time.Time     // zero for event streams
 	IsGet            bool
+	IsHead           bool
 	IsInsert         bool
 	IsUpdate         bool
 	IsDelete         bool
 	IsCORS           bool
+	traceContext     context.Context
 }
 
 func (rhr Request) HasWhere() bool {
@@ -52,6 +71,61 @@ func (rhr Request) HasSort() bool {
 	return rhr.Sort != ""
 }
 
+func (rhr Request) HasSearch() bool {
+	return rhr.Search != ""
+}
+
+func (rhr Request) HasGeo() bool {
+	return rhr.Geo != nil
+}
+
+func (rhr Request) IsAuthenticated() bool {
+	return rhr.Identity != nil
+}
+
+// Returns one of OPERATION_* constants, or empty string
+// for CORS requests
+func (rhr Request) Operation() string {
+	if rhr.Action == ACTION_RESTORE && !rhr.IsCORS {
+		return OPERATION_RESTORE
+	} else if rhr.Action == ACTION_REVERT && !rhr.IsCORS {
+		return OPERATION_REVERT
+	} else if rhr.IsGet {
+		return OPERATION_READ
+	} else if rhr.IsInsert {
+		return OPERATION_INSERT
+	} else if rhr.IsUpdate {
+		return OPERATION_UPDATE
+	} else if rhr.IsDelete {
+		return OPERATION_DELETE
+	}
+
+	return ""
+}
+
+func (rhr Request) HasItemID() bool {
+	return rhr.ItemID != ""
+}
+
+func (rhr Request) HasAction() bool {
+	return rhr.Action != ""
+}
+
+// Context of the current span limited by the Deadline, to be
+// used as the parent of the driver contexts, see
+// Resource.createContext()
+func (rhr *Request) TraceContext() context.Context {
+	if rhr.traceContext != nil {
+		return rhr.traceContext
+	}
+
+	if rhr.Request != nil {
+		return rhr.Request.Context()
+	}
+
+	return context.Background()
+}
+
 func (rhr Request) HasPage() bool {
 	return rhr.Page > 0
 }
@@ -76,6 +150,10 @@ func (rhr *Request) Parse(r *http.Request) HTTPError {
 
 	if rhr.MethodIsGet(rhr.Method) {
 		rhr.IsGet = true
+	} else if rhr.MethodIsHead(rhr.Method) {
+		// HEAD runs the GET pipeline without fetching the documents
+		rhr.IsGet = true
+		rhr.IsHead = true
 	} else if rhr.MethodIsDelete(rhr.Method) {
 		rhr.IsDelete = true
 	} else if rhr.MethodIsInsert(rhr.Method) {
@@ -92,9 +170,31 @@ func (rhr *Request) Parse(r *http.Request) HTTPError {
 		}
 	}
 
+	if clientTimeout := strings.TrimSpace(r.Header.Get(REQUEST_TIMEOUT_HEADER)); clientTimeout != "" {
+		timeoutMs, err := strconv.ParseInt(clientTimeout, 10, 64)
+
+		if err != nil || timeoutMs <= 0 {
+			return NewInvalidRequestTimeoutHTTPError(err)
+		}
+
+		rhr.ClientTimeout = time.Duration(timeoutMs) * time.Millisecond
+	}
+
+	if rhr.IsInsert || rhr.IsUpdate {
+		rhr.IdempotencyKey = strings.TrimSpace(r.Header.Get(IDEMPOTENCY_KEY_HEADER))
+
+		if len(rhr.IdempotencyKey) > IDEMPOTENCY_MAX_KEY_LENGTH {
+			return NewInvalidIdempotencyKeyHTTPError(nil)
+		}
+	}
+
+	if rhr.CollectionPath == "" {
+		rhr.CollectionPath = r.URL.Path
+	}
+
 	urlParts := utils.RegExUtilsInstance.FindNamedMatches(
 		rhr.ResourcePattern,
-		r.URL.Path)
+		rhr.CollectionPath)
 
 	if _, ok := urlParts["url"]; !ok {
 		return NewUnableToParseRequestHTTPError(nil)
@@ -110,9 +210,14 @@ func (rhr *Request) Parse(r *http.Request) HTTPError {
 
 	where := strings.TrimSpace(query.Get("where"))
 	sort := strings.TrimSpace(query.Get("sort"))
+	search := strings.TrimSpace(query.Get("q"))
+	sortByScore := strings.TrimSpace(query.Get("sort_by_score"))
+	geo := strings.TrimSpace(query.Get("geo"))
 	projection := strings.TrimSpace(query.Get("projection"))
 	perPage := strings.TrimSpace(query.Get("per_page"))
 	page := strings.TrimSpace(query.Get("page"))
+	includeDeleted := strings.TrimSpace(query.Get("include_deleted"))
+	documentVersion := strings.TrimSpace(query.Get("version"))
 
 	if where != "" {
 		rhr.Where = where
@@ -122,6 +227,36 @@ func (rhr *Request) Parse(r *http.Request) HTTPError {
 		rhr.Sort = sort
 	}
 
+	if search == "" {
+		search = strings.TrimSpace(query.Get("search"))
+	}
+
+	if search != "" {
+		rhr.Search = search
+	}
+
+	if sortByScore != "" {
+		rhr.SortByScore, _ = strconv.ParseBool(sortByScore)
+	}
+
+	if includeDeleted != "" {
+		rhr.IncludeDeleted, _ = strconv.ParseBool(includeDeleted)
+	}
+
+	if documentVersion != "" {
+		rhr.DocumentVersion, err = strconv.ParseInt(documentVersion, 10, 64)
+
+		if err != nil || rhr.DocumentVersion < 1 {
+			return NewUnableToParseRequestHTTPError(err)
+		}
+	}
+
+	if geo != "" {
+		if httpErr = rhr.parseGeo(geo); httpErr != nil {
+			return httpErr
+		}
+	}
+
 	if projection != "" {
 		if httpErr = rhr.parseProjection(projection); httpErr != nil {
 			return httpErr
@@ -157,6 +292,10 @@ func (rhr *Request) GetGetMethods() []string {
 	return []string{HTTP_REQUEST_GET_METHOD}
 }
 
+func (rhr *Request) GetHeadMethods() []string {
+	return []string{HTTP_REQUEST_HEAD_METHOD}
+}
+
 func (rhr *Request) GetDeleteMethods() []string {
 	return []string{HTTP_REQUEST_DELETE_METHOD}
 }
@@ -177,6 +316,10 @@ func (rhr *Request) MethodIsGet(method string) bool {
 	return funk.ContainsString(rhr.GetGetMethods(), method)
 }
 
+func (rhr *Request) MethodIsHead(method string) bool {
+	return funk.ContainsString(rhr.GetHeadMethods(), method)
+}
+
 func (rhr *Request) MethodIsDelete(method string) bool {
 	return funk.ContainsString(rhr.GetDeleteMethods(), method)
 }
@@ -251,6 +394,25 @@ func (rhr *Request) requestBodyToArrayOfMaps() ([]map[string]any, HTTPError) {
 	return decodedSlice, nil
 }
 
+func (rhr *Request) parseGeo(geo string) HTTPError {
+	geoQuery := GeoQuery{}
+
+	decoder := json.NewDecoder(strings.NewReader(geo))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&geoQuery); err != nil {
+		return NewMalformedGeoHTTPError(err)
+	}
+
+	if err := geoQuery.Validate(); err != nil {
+		return NewMalformedGeoHTTPError(err)
+	}
+
+	rhr.Geo = &geoQuery
+
+	return nil
+}
+
 func (rhr *Request) parseProjection(projection string) HTTPError {
 	var err error
 
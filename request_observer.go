@@ -0,0 +1,19 @@
+package go_cake
+
+import "time"
+
+// Summary of the processed request
+type RequestStats struct {
+	Resource    *Resource
+	Request     *Request
+	StatusCode  int
+	Duration    time.Duration
+	InputItems  int // decoded payload items
+	OutputItems int // returned items
+}
+
+// Called by the Handler after each request to a resource,
+// before the response is written, should not block
+type RequestObserver interface {
+	ObserveRequest(stats *RequestStats)
+}
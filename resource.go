@@ -3,7 +3,11 @@ package go_cake
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/skazanyNaGlany/go-cake/utils"
@@ -24,10 +28,22 @@ type Resource struct {
 	ResourceCallback              *ResourceCallback
 	JSONSchemaConfig              *JSONSchemaConfig
 	CORSConfig                    *CORSConfig
+	AccessPolicy                  *AccessPolicy
+	TenantConfig                  *TenantConfig
+	RateLimitConfig               *RateLimitConfig
+	IdempotencyConfig             *IdempotencyConfig
+	TimeoutConfig                 *TimeoutConfig
+	CacheControl                  *CacheControl // caching of GET responses
+	HistoryStore                  HistoryStore  // previous versions of updated and deleted documents
+	AuditSink                     AuditSink
+	EventListeners                []EventListener
+	EventBroker                   *EventBroker // enables <collection>/_events stream
 	GetAllowed                    bool
 	DeleteAllowed                 bool
 	InsertAllowed                 bool
 	UpdateAllowed                 bool
+	HATEOAS                       bool   // add _links to the response
+	SoftDeleteField               string // JSON field set by DELETE instead of removing the documents
 	GetMaxOutputItems             int64
 	DeleteMaxInputItems           int64
 	DeleteMaxInputPayloadSize     int64
@@ -36,6 +52,9 @@ type Resource struct {
 	UpdateMaxInputItems           int64
 	UpdateMaxInputPayloadSize     int64
 	compiledSupportedVersion      []*regexp.Regexp
+	baseContext                   context.Context // canceled by Handler.Shutdown()
+	inFlightRequests              sync.WaitGroup
+	activeRequests                atomic.Int64
 }
 
 func NewResource(
@@ -152,13 +171,116 @@ func (rhr *Resource) createContext(
 	request *Request,
 	response *ResponseJSON,
 	contextType ContextType) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), 30*time.Second)
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	// canceled when the client disconnects, carries the span
+	// of the current stage
+	if request.Deadline.IsZero() {
+		ctx, cancel = context.WithTimeout(request.TraceContext(), rhr.GetTimeout(request.Operation()))
+	} else {
+		ctx, cancel = context.WithDeadline(request.TraceContext(), request.Deadline)
+	}
+
+	if rhr.baseContext == nil {
+		return ctx, cancel
+	}
+
+	// canceled also by Handler.Shutdown()
+	stop := context.AfterFunc(rhr.baseContext, cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
 }
 
+// Timeout of the operation, MAX_REQUEST_TIME_MS without
+// TimeoutConfig
+func (rhr *Resource) GetTimeout(operation string) time.Duration {
+	if rhr.TimeoutConfig == nil {
+		return MAX_REQUEST_TIME_MS * time.Millisecond
+	}
+
+	return rhr.TimeoutConfig.GetTimeout(operation)
+}
+
+// Ends the event streams of the resource, drivers and sinks
+// are closed by Handler.Shutdown() since they can be shared
 func (rhr *Resource) Close() error {
+	if rhr.EventBroker != nil {
+		rhr.EventBroker.Close()
+	}
+
+	return nil
+}
+
+// Number of the requests in progress
+func (rhr *Resource) ActiveRequests() int64 {
+	return rhr.activeRequests.Load()
+}
+
+func (rhr *Resource) AddEventListener(listener EventListener) {
+	rhr.EventListeners = append(rhr.EventListeners, listener)
+}
+
+func (rhr *Resource) IsSoftDelete() bool {
+	return rhr.SoftDeleteField != ""
+}
+
+// Marker stored in SoftDeleteField, true/false for bool fields,
+// the current time/nil for other fields (like *time.Time)
+func (rhr *Resource) GetSoftDeleteMarker(deleted bool) any {
+	if rhr.softDeleteFieldIsBool() {
+		return deleted
+	}
+
+	if deleted {
+		return time.Now().UTC()
+	}
+
 	return nil
 }
 
+// Server filter value of the documents which are not deleted,
+// bool fields are compared with "not true" so the documents
+// stored before enabling the soft delete (without the field
+// or with NULL) stay visible, like nil for other fields
+func (rhr *Resource) GetSoftDeleteFilter() any {
+	if rhr.softDeleteFieldIsBool() {
+		return NotEqualFilter{Value: true, Default: false}
+	}
+
+	return nil
+}
+
+func (rhr *Resource) softDeleteFieldIsBool() bool {
+	modelType := reflect.TypeOf(rhr.DbModel)
+
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		jsonField, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if jsonField != rhr.SoftDeleteField {
+			continue
+		}
+
+		fieldType := field.Type
+
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		return fieldType.Kind() == reflect.Bool
+	}
+
+	return false
+}
+
 func (rhr *Resource) testResource() error {
 	if rhr.Pattern == "" {
 		return NewNoResourcePatternSetError(rhr, nil)
@@ -285,6 +407,18 @@ func (rhr *Resource) compileSupportedVersions() error {
 	return nil
 }
 
+// Pattern from SupportedVersion matching the version,
+// empty if the version is not supported
+func (rhr *Resource) GetMatchingSupportedVersion(version string) string {
+	for i, compiled := range rhr.compiledSupportedVersion {
+		if compiled.MatchString(version) {
+			return rhr.SupportedVersion[i]
+		}
+	}
+
+	return ""
+}
+
 func (rhr *Resource) checkSchemaConfigFields() error {
 	allFields := rhr.JSONSchemaConfig.GetAllFields()
 
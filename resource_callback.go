@@ -2,6 +2,8 @@ package go_cake
 
 type ResourceCallback struct {
 	AuthCallback        AuthCallback
+	AuthErrorCallback   AuthErrorCallback
+	ServerFilter        ServerFilterCallback
 	PreRequestCallback  PrePostRequestCallback
 	PostRequestCallback PrePostRequestCallback
 	FetchedDocuments    DocumentsCallback
@@ -0,0 +1,99 @@
+package go_cake
+
+import (
+	"maps"
+	"time"
+
+	"github.com/skazanyNaGlany/go-cake/utils"
+	"github.com/thoas/go-funk"
+)
+
+const EVENT_INSERTED = "inserted"
+const EVENT_UPDATED = "updated"
+const EVENT_DELETED = "deleted"
+
+// Emitted after successful driver writes, documents contain
+// only successfully changed documents without hidden and
+// erased fields
+type ResourceEvent struct {
+	ID              string           `json:"id"`
+	Type            string           `json:"type"`
+	Resource        string           `json:"resource"`
+	Tenant          string           `json:"tenant,omitempty"`
+	RequestUniqueID string           `json:"request_unique_id"`
+	Time            time.Time        `json:"time"`
+	Documents       []map[string]any `json:"documents"`
+	hiddenDocuments []map[string]any // hidden fields of Documents for the server filters, not sent
+}
+
+// Called synchronously by the request, should not block
+type EventListener interface {
+	OnResourceEvent(event *ResourceEvent)
+}
+
+// Removes meta, hidden and erased fields of the resource from
+// the documents, used also by the external event sources like
+// change streams
+func NewResourceEvent(
+	resource *Resource,
+	eventType string,
+	tenant string,
+	requestUniqueID string,
+	jsonDocuments []map[string]any) *ResourceEvent {
+	hiddenFields := resource.JSONSchemaConfig.HiddenFields
+	erasedFields := resource.JSONSchemaConfig.ErasedFields
+
+	if funk.ContainsString(hiddenFields, FIELD_ANY) {
+		hiddenFields = resource.DbModelJSONFieldsNoReserved
+	}
+
+	if funk.ContainsString(erasedFields, FIELD_ANY) {
+		erasedFields = resource.DbModelJSONFieldsNoReserved
+	}
+
+	hiddenDocuments := make([]map[string]any, len(jsonDocuments))
+
+	for i, jsonObject := range jsonDocuments {
+		delete(jsonObject, "_meta")
+
+		hiddenDocuments[i] = make(map[string]any)
+
+		for _, iHiddenField := range hiddenFields {
+			if value, exists := jsonObject[iHiddenField]; exists {
+				hiddenDocuments[i][iHiddenField] = value
+			}
+
+			delete(jsonObject, iHiddenField)
+		}
+
+		for _, iErasedField := range erasedFields {
+			if _, isStr := jsonObject[iErasedField].(string); isStr {
+				jsonObject[iErasedField] = ""
+			}
+		}
+	}
+
+	return &ResourceEvent{
+		ID:              utils.StringUtilsInstance.NewUUID(),
+		Type:            eventType,
+		Resource:        resource.ResourceName,
+		Tenant:          tenant,
+		RequestUniqueID: requestUniqueID,
+		Time:            time.Now().UTC(),
+		Documents:       jsonDocuments,
+		hiddenDocuments: hiddenDocuments,
+	}
+}
+
+// Document with its hidden fields for the server filters, the
+// hidden fields are lost when the event is sent to other nodes
+func (re *ResourceEvent) getServerFilterDocument(i int) map[string]any {
+	if i >= len(re.hiddenDocuments) || len(re.hiddenDocuments[i]) == 0 {
+		return re.Documents[i]
+	}
+
+	document := maps.Clone(re.Documents[i])
+	maps.Copy(document, re.hiddenDocuments[i])
+
+	return document
+}
@@ -1,8 +1,9 @@
 package go_cake
 
 type ResponseJSON struct {
-	Items []map[string]any `json:"_items"`
-	Meta  MetaJSON         `json:"_meta"`
+	Items []map[string]any  `json:"_items"`
+	Meta  MetaJSON          `json:"_meta"`
+	Links map[string]string `json:"_links,omitempty"`
 }
 
 type MetaJSON struct {
@@ -0,0 +1,89 @@
+package go_cake
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const CACHE_CONTROL_DEFAULT_TTL = time.Minute
+const CACHE_CONTROL_BYPASS_VALUE = "no-cache"
+
+// GET response stored by the ResponseCache
+type CachedResponse struct {
+	Body       []byte    // JSON response
+	StoreTime  time.Time // before the documents were read
+	ExpireTime time.Time
+}
+
+// Storage of the GET responses, implementations must be safe
+// for concurrent use
+type ResponseCache interface {
+	// Returns nil if the key does not exist or is expired
+	Get(resourceName string, key string) (*CachedResponse, error)
+
+	// Should skip responses stored before the last invalidation
+	// of the resource, they may contain changed documents
+	Set(resourceName string, key string, response *CachedResponse) error
+
+	// Removes all the responses of the resource
+	Invalidate(resourceName string) error
+}
+
+// Caches successful GET responses of the resource, the responses
+// are invalidated by inserts, updates and deletes made through
+// the resource, external changes can be passed to OnResourceEvent()
+type CacheControl struct {
+	Cache     ResponseCache // MemoryResponseCache if nil
+	TTL       time.Duration // max-age of the responses, CACHE_CONTROL_DEFAULT_TTL if 0
+	Public    bool          // allow shared caches to store anonymous responses
+	cacheOnce sync.Once
+}
+
+func NewCacheControl(cache ResponseCache, ttl time.Duration) *CacheControl {
+	if cache == nil {
+		cache = NewMemoryResponseCache(MEMORY_RESPONSE_CACHE_DEFAULT_MAX_ENTRIES)
+	}
+
+	return &CacheControl{
+		Cache: cache,
+		TTL:   ttl,
+	}
+}
+
+func (cc *CacheControl) GetCache() ResponseCache {
+	cc.cacheOnce.Do(func() {
+		if cc.Cache == nil {
+			cc.Cache = NewMemoryResponseCache(MEMORY_RESPONSE_CACHE_DEFAULT_MAX_ENTRIES)
+		}
+	})
+
+	return cc.Cache
+}
+
+func (cc *CacheControl) GetTTL() time.Duration {
+	if cc.TTL <= 0 {
+		return CACHE_CONTROL_DEFAULT_TTL
+	}
+
+	return cc.TTL
+}
+
+// Value of Cache-Control response header, responses of
+// authenticated requests are always private
+func (cc *CacheControl) GetHeaderValue(authenticated bool) string {
+	visibility := "private"
+
+	if cc.Public && !authenticated {
+		visibility = "public"
+	}
+
+	return fmt.Sprintf("%v, max-age=%v", visibility, durationToSeconds(cc.GetTTL()))
+}
+
+func (cc *CacheControl) OnResourceEvent(event *ResourceEvent) {
+	if err := cc.GetCache().Invalidate(event.Resource); err != nil {
+		log.Printf("Unable to invalidate cached responses of %v: %v", event.Resource, err)
+	}
+}
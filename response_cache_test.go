@@ -0,0 +1,84 @@
+package go_cake
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestResponseCacheInvalidatedOnWrite(t *testing.T) {
+	driver := newTestDriver("a", "b")
+	handler, resource := newTestHandler(t, driver)
+
+	resource.CacheControl = NewCacheControl(nil, time.Minute)
+
+	// names of the fetched items, and whether Find was called
+	get := func(headers ...string) ([]string, bool) {
+		t.Helper()
+
+		findCalls := driver.findCalls
+		recorder := serveTestRequest(handler, http.MethodGet, "/v1/api/items", "", headers...)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("GET status = %v, want %v", recorder.Code, http.StatusOK)
+		}
+
+		if cacheControl := recorder.Header().Get("Cache-Control"); cacheControl != "private, max-age=60" {
+			t.Errorf("Cache-Control = %q, want private, max-age=60", cacheControl)
+		}
+
+		names := make([]string, 0)
+
+		for _, item := range decodeTestResponse(t, recorder).Items {
+			names = append(names, fmt.Sprint(item["name"]))
+		}
+
+		return names, driver.findCalls > findCalls
+	}
+
+	write := func(method string, body string) {
+		t.Helper()
+
+		if recorder := serveTestRequest(handler, method, "/v1/api/items", body); recorder.Code != http.StatusOK {
+			t.Fatalf("%v status = %v, want %v: %v", method, recorder.Code, http.StatusOK, recorder.Body.String())
+		}
+	}
+
+	if names, found := get(); !found || !slices.Equal(names, []string{"a", "b"}) {
+		t.Fatalf("first GET = %v, found %v, want [a b] from the driver", names, found)
+	}
+
+	if names, found := get(); found || !slices.Equal(names, []string{"a", "b"}) {
+		t.Fatalf("second GET = %v, found %v, want [a b] from the cache", names, found)
+	}
+
+	if _, found := get("Cache-Control", "no-cache"); !found {
+		t.Error("GET with Cache-Control: no-cache served from the cache")
+	}
+
+	write(http.MethodPost, `[{"name":"c"}]`)
+
+	if names, found := get(); !found || !slices.Equal(names, []string{"a", "b", "c"}) {
+		t.Fatalf("GET after insert = %v, found %v, want [a b c] from the driver", names, found)
+	}
+
+	item := driver.items["1"]
+	write(http.MethodPatch, fmt.Sprintf(`[{"id":"1","_etag":%q,"name":"a2"}]`, *item.ETag))
+
+	if names, found := get(); !found || !slices.Equal(names, []string{"a2", "b", "c"}) {
+		t.Fatalf("GET after update = %v, found %v, want [a2 b c] from the driver", names, found)
+	}
+
+	item = driver.items["2"]
+	write(http.MethodDelete, fmt.Sprintf(`[{"id":"2","_etag":%q}]`, *item.ETag))
+
+	if names, found := get(); !found || !slices.Equal(names, []string{"a2", "c"}) {
+		t.Fatalf("GET after delete = %v, found %v, want [a2 c] from the driver", names, found)
+	}
+
+	if _, found := get(); found {
+		t.Error("GET after a cached GET reached the driver")
+	}
+}
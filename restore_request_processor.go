@@ -0,0 +1,145 @@
+package go_cake
+
+import "github.com/thoas/go-funk"
+
+// Clears SoftDeleteField of soft deleted documents,
+// POST <collection>/_restore with the same payload as DELETE
+type RestoreRequestProcessor struct {
+	BaseRequestProcessor
+}
+
+func NewRestoreRequestProcessor(request *Request, resource *Resource) *RestoreRequestProcessor {
+	var restoreRequestProcessor RestoreRequestProcessor
+
+	restoreRequestProcessor.request = request
+	restoreRequestProcessor.resource = resource
+	restoreRequestProcessor.subRequestProcessor = &restoreRequestProcessor
+
+	return &restoreRequestProcessor
+}
+
+func (rrp *RestoreRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoCakeModel, HTTPError) {
+	var httpErr HTTPError
+
+	if !rrp.request.IsInsert || !rrp.resource.UpdateAllowed {
+		return nil, NewMethodNotAllowedHTTPError(nil)
+	}
+
+	if httpErr = rrp.checkRanges(); httpErr != nil {
+		return nil, httpErr
+	}
+
+	if rrp.request.HasWhere() ||
+		rrp.request.HasSort() ||
+		rrp.request.HasSearch() ||
+		rrp.request.HasGeo() ||
+		rrp.request.HasPage() {
+		return nil, NewModifiersNotAllowedHTTPError(nil)
+	}
+
+	rrp.preRequestJSONActions(rrp.request.DecodedJsonSlice)
+
+	converted, err := rrp.decodedJsonSliceToDBModels()
+
+	if err != nil {
+		return converted, err
+	}
+
+	httpErr = rrp.checkDocumentsForErrors(converted)
+
+	if httpErr != nil {
+		return converted, httpErr
+	}
+
+	httpErr = rrp.callUpdatingDocumentsHandlers(converted, nil)
+
+	if httpErr != nil {
+		return converted, httpErr
+	}
+
+	ctx, cancel := rrp.resource.ResourceCallback.CreateContext(
+		rrp.resource,
+		rrp.request,
+		response,
+		ctxDbDriverUpdate)
+	defer cancel()
+
+	rrp.preChangeActions(converted, ctx)
+
+	httpErr = rrp.driver.UpdateFields(
+		rrp.resource.DbModel,
+		rrp.dbPath,
+		converted,
+		[]string{rrp.resource.SoftDeleteField},
+		rrp.serverFilter,
+		ctx,
+		rrp.request.UserData)
+
+	rrp.saveDocumentVersions(converted, OPERATION_RESTORE, httpErr, ctx)
+	rrp.emitResourceEvent(EVENT_UPDATED, converted, httpErr)
+
+	httpErr = rrp.callUpdatedDocumentsHandlers(converted, httpErr)
+
+	if httpErr != nil {
+		return converted, httpErr
+	}
+
+	return converted, nil
+}
+
+func (rrp *RestoreRequestProcessor) checkRanges() HTTPError {
+	if rrp.request.ContentLength > rrp.resource.UpdateMaxInputPayloadSize {
+		return NewPayloadTooBigHTTPError(rrp.resource.UpdateMaxInputPayloadSize, nil)
+	}
+
+	if int64(len(rrp.request.Body)) > rrp.resource.UpdateMaxInputPayloadSize {
+		return NewPayloadTooBigHTTPError(rrp.resource.UpdateMaxInputPayloadSize, nil)
+	}
+
+	lenDecodedJsonSlice := len(rrp.request.DecodedJsonSlice)
+
+	if lenDecodedJsonSlice > int(rrp.resource.UpdateMaxInputItems) {
+		return NewTooManyInputItemsHTTPError(rrp.resource.UpdateMaxInputItems, lenDecodedJsonSlice, nil)
+	}
+
+	return nil
+}
+
+// Only ID and ETag are taken from the payload
+func (rrp *RestoreRequestProcessor) preRequestJSONActions(jsonDocuments []map[string]any) {
+	var httpErr HTTPError
+
+	requireOnDeleteFields := rrp.resource.JSONSchemaConfig.RequiredOnDeleteFields
+
+	if funk.ContainsString(requireOnDeleteFields, FIELD_ANY) {
+		requireOnDeleteFields = rrp.resource.DbModelJSONFields
+	}
+
+	keepFields := []string{
+		rrp.resource.JSONSchemaConfig.IDField,
+		rrp.resource.JSONSchemaConfig.ETagField}
+
+	marker := rrp.resource.GetSoftDeleteMarker(false)
+
+	for _, jsonObject := range jsonDocuments {
+		if httpErr = rrp.preRequestRequireOnUpdateChecks(
+			jsonObject,
+			requireOnDeleteFields); httpErr != nil {
+			jsonObject["__http_error__"] = httpErr
+			continue
+		}
+
+		for iJsonField := range jsonObject {
+			if !funk.ContainsString(keepFields, iJsonField) {
+				delete(jsonObject, iJsonField)
+			}
+		}
+
+		if httpErr = rrp.preRequestServerFilterActions(jsonObject); httpErr != nil {
+			jsonObject["__http_error__"] = httpErr
+			continue
+		}
+
+		jsonObject[rrp.resource.SoftDeleteField] = marker
+	}
+}
@@ -0,0 +1,253 @@
+package go_cake
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/thoas/go-funk"
+)
+
+// Reverts the documents to their versions from the HistoryStore,
+// POST <collection>/_revert with ID, current ETag and
+// REVERT_VERSION_FIELD of each document
+type RevertRequestProcessor struct {
+	BaseRequestProcessor
+}
+
+func NewRevertRequestProcessor(request *Request, resource *Resource) *RevertRequestProcessor {
+	var revertRequestProcessor RevertRequestProcessor
+
+	revertRequestProcessor.request = request
+	revertRequestProcessor.resource = resource
+	revertRequestProcessor.subRequestProcessor = &revertRequestProcessor
+
+	return &revertRequestProcessor
+}
+
+func (rrp *RevertRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoCakeModel, HTTPError) {
+	var httpErr HTTPError
+
+	if !rrp.request.IsInsert || !rrp.resource.UpdateAllowed {
+		return nil, NewMethodNotAllowedHTTPError(nil)
+	}
+
+	if httpErr = rrp.checkRanges(); httpErr != nil {
+		return nil, httpErr
+	}
+
+	if rrp.request.HasWhere() ||
+		rrp.request.HasSort() ||
+		rrp.request.HasSearch() ||
+		rrp.request.HasGeo() ||
+		rrp.request.HasPage() {
+		return nil, NewModifiersNotAllowedHTTPError(nil)
+	}
+
+	ctx, cancel := rrp.resource.ResourceCallback.CreateContext(
+		rrp.resource,
+		rrp.request,
+		response,
+		ctxDbDriverUpdate)
+	defer cancel()
+
+	rrp.preRequestJSONActions(rrp.request.DecodedJsonSlice, ctx)
+
+	converted, err := rrp.decodedJsonSliceToDBModels()
+
+	if err != nil {
+		return converted, err
+	}
+
+	httpErr = rrp.checkDocumentsForErrors(converted)
+
+	if httpErr != nil {
+		return converted, httpErr
+	}
+
+	httpErr = rrp.callUpdatingDocumentsHandlers(converted, nil)
+
+	if httpErr != nil {
+		return converted, httpErr
+	}
+
+	rrp.preChangeActions(converted, ctx)
+
+	httpErr = rrp.driver.UpdateFields(
+		rrp.resource.DbModel,
+		rrp.dbPath,
+		converted,
+		rrp.getRevertableFields(),
+		rrp.serverFilter,
+		ctx,
+		rrp.request.UserData)
+
+	rrp.saveDocumentVersions(converted, OPERATION_REVERT, httpErr, ctx)
+	rrp.emitResourceEvent(EVENT_UPDATED, converted, httpErr)
+
+	httpErr = rrp.callUpdatedDocumentsHandlers(converted, httpErr)
+
+	if httpErr != nil {
+		return converted, httpErr
+	}
+
+	return converted, nil
+}
+
+func (rrp *RevertRequestProcessor) checkRanges() HTTPError {
+	if rrp.request.ContentLength > rrp.resource.UpdateMaxInputPayloadSize {
+		return NewPayloadTooBigHTTPError(rrp.resource.UpdateMaxInputPayloadSize, nil)
+	}
+
+	if int64(len(rrp.request.Body)) > rrp.resource.UpdateMaxInputPayloadSize {
+		return NewPayloadTooBigHTTPError(rrp.resource.UpdateMaxInputPayloadSize, nil)
+	}
+
+	lenDecodedJsonSlice := len(rrp.request.DecodedJsonSlice)
+
+	if lenDecodedJsonSlice > int(rrp.resource.UpdateMaxInputItems) {
+		return NewTooManyInputItemsHTTPError(rrp.resource.UpdateMaxInputItems, lenDecodedJsonSlice, nil)
+	}
+
+	return nil
+}
+
+// Only updatable fields which the role can see and write are
+// reverted, soft delete marker is kept
+func (rrp *RevertRequestProcessor) getRevertableFields() []string {
+	updatableFields := rrp.resource.JSONSchemaConfig.UpdatableFields
+	accessPolicy := rrp.resource.AccessPolicy
+
+	if funk.ContainsString(updatableFields, FIELD_ANY) {
+		updatableFields = rrp.resource.DbModelJSONFieldsNoReserved
+	}
+
+	revertableFields := make([]string, 0)
+
+	for _, iJsonField := range updatableFields {
+		if iJsonField == rrp.resource.JSONSchemaConfig.IDField ||
+			iJsonField == rrp.resource.JSONSchemaConfig.ETagField ||
+			iJsonField == rrp.resource.SoftDeleteField {
+			continue
+		}
+
+		if funk.ContainsString(rrp.roleHiddenFields, iJsonField) {
+			continue
+		}
+
+		if accessPolicy != nil && !accessPolicy.IsFieldWritable(rrp.rolePolicies, iJsonField) {
+			// the stored value cannot be written by the role
+			continue
+		}
+
+		revertableFields = append(revertableFields, iJsonField)
+	}
+
+	return revertableFields
+}
+
+// Replaces the payload with the document of the version,
+// keeping the ID and ETag from the payload, the document is
+// validated like the payload of the update
+func (rrp *RevertRequestProcessor) preRequestJSONActions(
+	jsonDocuments []map[string]any,
+	ctx context.Context) {
+	var httpErr HTTPError
+
+	requiredFields := []string{rrp.resource.JSONSchemaConfig.IDField, REVERT_VERSION_FIELD}
+
+	if rrp.resource.JSONSchemaConfig.ETagField != "" {
+		requiredFields = append(requiredFields, rrp.resource.JSONSchemaConfig.ETagField)
+	}
+
+	revertableFields := rrp.getRevertableFields()
+
+	for _, jsonObject := range jsonDocuments {
+		if httpErr = rrp.preRequestRequireOnUpdateChecks(
+			jsonObject,
+			requiredFields); httpErr != nil {
+			jsonObject["__http_error__"] = httpErr
+			continue
+		}
+
+		if httpErr = rrp.preRequestVersionActions(jsonObject, revertableFields, ctx); httpErr != nil {
+			jsonObject["__http_error__"] = httpErr
+			continue
+		}
+
+		if httpErr = rrp.preRequestServerFilterActions(jsonObject); httpErr != nil {
+			jsonObject["__http_error__"] = httpErr
+			continue
+		}
+
+		if httpErr = rrp.preRequestValidateJSON(jsonObject); httpErr != nil {
+			jsonObject["__http_error__"] = httpErr
+			continue
+		}
+	}
+}
+
+func (rrp *RevertRequestProcessor) preRequestValidateJSON(
+	jsonObjectMap map[string]any) HTTPError {
+	if rrp.resource.JSONSchemaConfig == nil ||
+		rrp.resource.JSONSchemaConfig.UpdateValidator == nil {
+		return nil
+	}
+
+	if err := rrp.resource.JSONSchemaConfig.UpdateValidator.Validate(jsonObjectMap); err != nil {
+		return NewClientObjectMalformedHTTPError(err)
+	}
+
+	return nil
+}
+
+func (rrp *RevertRequestProcessor) preRequestVersionActions(
+	jsonObject map[string]any,
+	revertableFields []string,
+	ctx context.Context) HTTPError {
+	idField := rrp.resource.JSONSchemaConfig.IDField
+	etagField := rrp.resource.JSONSchemaConfig.ETagField
+
+	versionNumber, ok := jsonObject[REVERT_VERSION_FIELD].(float64)
+
+	if !ok || versionNumber < 1 || versionNumber != float64(int64(versionNumber)) {
+		return NewClientObjectMalformedHTTPError(
+			fmt.Errorf("%v must be a positive integer", REVERT_VERSION_FIELD))
+	}
+
+	version, err := rrp.resource.HistoryStore.GetVersion(
+		rrp.resource,
+		rrp.driver,
+		rrp.dbPath,
+		fmt.Sprint(jsonObject[idField]),
+		int64(versionNumber),
+		ctx)
+
+	if err != nil {
+		return NewLowLevelDriverHTTPError(err)
+	}
+
+	if version == nil {
+		return NewObjectNotFoundHTTPError(nil)
+	}
+
+	id := jsonObject[idField]
+	etag, hasEtag := jsonObject[etagField]
+
+	maps.DeleteFunc(jsonObject, func(string, any) bool { return true })
+
+	for _, iJsonField := range revertableFields {
+		if value, exists := version.Document[iJsonField]; exists {
+			jsonObject[iJsonField] = value
+		}
+	}
+
+	jsonObject[idField] = id
+
+	if hasEtag {
+		// current ETag is checked by the update
+		jsonObject[etagField] = etag
+	}
+
+	return nil
+}
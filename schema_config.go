@@ -22,6 +22,9 @@ type JSONSchemaConfig struct {
 	OptimizeOnInsertFields []string
 	OptimizeOnUpdateFields []string
 	OptimizeOnDeleteFields []string
+	SearchableFields       []string
+	SearchLanguage         string
+	GeoFields              []string
 	GetValidator           JSONValidator
 	DeleteValidator        JSONValidator
 	InsertValidator        JSONValidator
@@ -47,6 +50,8 @@ func (jsc *JSONSchemaConfig) GetAllFields() []string {
 	allFields = append(allFields, jsc.OptimizeOnInsertFields...)
 	allFields = append(allFields, jsc.OptimizeOnUpdateFields...)
 	allFields = append(allFields, jsc.OptimizeOnDeleteFields...)
+	allFields = append(allFields, jsc.SearchableFields...)
+	allFields = append(allFields, jsc.GeoFields...)
 
 	allFields = funk.UniqString(allFields)
 
@@ -0,0 +1,10 @@
+package go_cake
+
+// Full-text search parameters passed to the DatabaseDriver,
+// Fields holds JSON field names (already validated against the model)
+type SearchQuery struct {
+	Text        string
+	Fields      []string
+	Language    string
+	SortByScore bool
+}
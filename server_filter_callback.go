@@ -0,0 +1,23 @@
+package go_cake
+
+// app callback, returns equality conditions on JSON fields
+// which are always AND-ed with the client's conditions and
+// populated on insert, like {"tenant_id": <from the token>}
+type ServerFilterCallback func(
+	resource *Resource,
+	request *Request) (map[string]any, HTTPError)
+
+// Server filter value matching the documents where the field is
+// not equal to Value, documents without the field and NULL
+// columns match too, Default is set on the inserted documents
+// without the field
+type NotEqualFilter struct {
+	Value   any
+	Default any
+}
+
+// Server filter value matching the documents where the field is
+// equal to any of Values, it is not populated on insert
+type InFilter struct {
+	Values []any
+}
@@ -0,0 +1,245 @@
+package go_cake
+
+import (
+	"container/list"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const TENANT_PLACEHOLDER = "{tenant}"
+const TENANT_DEFAULT_MAX_DRIVERS = 1000
+
+// tenant is used in database paths so only safe names are allowed
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,63}$`)
+
+// app callback, returns empty string if there is no tenant
+type TenantCallback func(
+	resource *Resource,
+	request *Request) (string, HTTPError)
+
+// app callback, returns database driver for the tenant, like
+// MongoDriver.WithDatabase() for per tenant databases, such
+// drivers share the client of the resource's driver
+type TenantDriverCallback func(
+	resource *Resource,
+	tenant string) (DatabaseDriver, error)
+
+// app callback, called when the driver of the least recently used
+// tenant is removed from the cache and released by the last request
+// holding it, like to close it, drivers sharing a client like the
+// ones of MongoDriver.WithDatabase() must not be closed since it
+// disconnects all the tenants
+type TenantDriverEvictedCallback func(
+	tenant string,
+	driver DatabaseDriver)
+
+type TenantConfig struct {
+	Resolver        TenantCallback
+	DbPathPattern   string                      // TENANT_PLACEHOLDER is replaced, Resource.DbPath if empty
+	DriverCallback  TenantDriverCallback        // Resource.DatabaseDriver if nil, called once per tenant
+	Optional        bool                        // requests without tenant use Resource.DbPath and Resource.DatabaseDriver
+	MaxDrivers      int                         // TENANT_DEFAULT_MAX_DRIVERS if 0
+	OnDriverEvicted TenantDriverEvictedCallback // evicted drivers are not closed if nil
+	drivers         *list.List
+	driverElements  map[string]*list.Element // by tenant
+	driversMutex    sync.Mutex
+}
+
+// Driver of the tenant and the models tested with it, the
+// config can be shared by the resources of different models,
+// driver is set holding both mutex and TenantConfig.driversMutex
+// so getDrivers() does not wait for the network calls, references
+// and evicted are guarded by TenantConfig.driversMutex
+type tenantDriver struct {
+	tenant       string
+	driver       DatabaseDriver
+	testedModels map[string]bool // by model type and database path
+	references   int             // taken by GetDriver() and not released yet
+	evicted      bool            // removed from the cache, evicted by the last release
+	mutex        sync.Mutex
+}
+
+func NewTenantConfig(resolver TenantCallback, dbPathPattern string) *TenantConfig {
+	return &TenantConfig{
+		Resolver:      resolver,
+		DbPathPattern: dbPathPattern,
+	}
+}
+
+func (tc *TenantConfig) IsValidTenant(tenant string) bool {
+	return tenantPattern.MatchString(tenant)
+}
+
+func (tc *TenantConfig) GetMaxDrivers() int {
+	if tc.MaxDrivers <= 0 {
+		return TENANT_DEFAULT_MAX_DRIVERS
+	}
+
+	return tc.MaxDrivers
+}
+
+func (tc *TenantConfig) GetDbPath(resource *Resource, tenant string) string {
+	if tc.DbPathPattern == "" {
+		return resource.DbPath
+	}
+
+	return strings.ReplaceAll(tc.DbPathPattern, TENANT_PLACEHOLDER, tenant)
+}
+
+// Returns the driver and database path for the tenant, and the
+// function releasing the driver which must be called when it is
+// no longer used, evicted drivers are passed to OnDriverEvicted
+// after the last release, drivers are created once per tenant
+// and the model is tested for each new model and path, the
+// network calls are made without blocking the other tenants
+func (tc *TenantConfig) GetDriver(resource *Resource, tenant string) (DatabaseDriver, string, func(), error) {
+	dbPath := tc.GetDbPath(resource, tenant)
+	entry := tc.getTenantDriver(tenant)
+	release := sync.OnceFunc(func() {
+		tc.releaseTenantDriver(entry)
+	})
+
+	driver, err := tc.loadDriver(resource, entry, dbPath)
+
+	if err != nil {
+		release()
+
+		return nil, "", nil, err
+	}
+
+	return driver, dbPath, release, nil
+}
+
+func (tc *TenantConfig) loadDriver(resource *Resource, entry *tenantDriver, dbPath string) (DatabaseDriver, error) {
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+
+	if entry.driver == nil {
+		driver := resource.DatabaseDriver
+
+		if tc.DriverCallback != nil {
+			var err error
+
+			if driver, err = tc.DriverCallback(resource, entry.tenant); err != nil {
+				return nil, err
+			}
+		}
+
+		tc.driversMutex.Lock()
+		entry.driver = driver
+		tc.driversMutex.Unlock()
+	}
+
+	modelKey := fmt.Sprintf("%T:%v", resource.DbModel, dbPath)
+
+	if !entry.testedModels[modelKey] {
+		if err := entry.driver.TestModel(
+			resource.DbModelIDField,
+			resource.DbModelETagField,
+			resource.DbModel,
+			dbPath); err != nil {
+			return nil, NewUnableToTestModelError(resource, entry.driver, resource.DbModel, err)
+		}
+
+		entry.testedModels[modelKey] = true
+	}
+
+	return entry.driver, nil
+}
+
+// Returns the cache entry of the tenant with a reference taken,
+// the least recently used entry is removed when the cache is full
+// and evicted when no request holds it
+func (tc *TenantConfig) getTenantDriver(tenant string) *tenantDriver {
+	var evicted *tenantDriver
+
+	tc.driversMutex.Lock()
+
+	if tc.drivers == nil {
+		tc.drivers = list.New()
+		tc.driverElements = make(map[string]*list.Element)
+	}
+
+	element, exists := tc.driverElements[tenant]
+
+	if exists {
+		tc.drivers.MoveToFront(element)
+	} else {
+		element = tc.drivers.PushFront(&tenantDriver{
+			tenant:       tenant,
+			testedModels: make(map[string]bool),
+		})
+		tc.driverElements[tenant] = element
+
+		if tc.drivers.Len() > tc.GetMaxDrivers() {
+			oldest := tc.drivers.Back()
+
+			tc.drivers.Remove(oldest)
+			evicted = oldest.Value.(*tenantDriver)
+			evicted.evicted = true
+			delete(tc.driverElements, evicted.tenant)
+
+			if evicted.references > 0 {
+				// by the last release
+				evicted = nil
+			}
+		}
+	}
+
+	entry := element.Value.(*tenantDriver)
+	entry.references++
+
+	tc.driversMutex.Unlock()
+
+	if evicted != nil {
+		tc.evict(evicted)
+	}
+
+	return entry
+}
+
+func (tc *TenantConfig) releaseTenantDriver(entry *tenantDriver) {
+	tc.driversMutex.Lock()
+
+	entry.references--
+	evict := entry.evicted && entry.references == 0
+
+	tc.driversMutex.Unlock()
+
+	if evict {
+		tc.evict(entry)
+	}
+}
+
+func (tc *TenantConfig) evict(entry *tenantDriver) {
+	entry.mutex.Lock()
+	driver := entry.driver
+	entry.mutex.Unlock()
+
+	// without DriverCallback it is the resource's driver
+	if driver != nil && tc.DriverCallback != nil && tc.OnDriverEvicted != nil {
+		tc.OnDriverEvicted(entry.tenant, driver)
+	}
+}
+
+// Cached drivers by tenant, drivers being created are skipped
+func (tc *TenantConfig) getDrivers() map[string]DatabaseDriver {
+	tc.driversMutex.Lock()
+	defer tc.driversMutex.Unlock()
+
+	drivers := make(map[string]DatabaseDriver)
+
+	if tc.drivers == nil {
+		return drivers
+	}
+
+	for element := tc.drivers.Front(); element != nil; element = element.Next() {
+		if entry := element.Value.(*tenantDriver); entry.driver != nil {
+			drivers[entry.tenant] = entry.driver
+		}
+	}
+
+	return drivers
+}
@@ -0,0 +1,62 @@
+package go_cake
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTenantConfigEvictsAfterLastRelease(t *testing.T) {
+	_, resource := newTestHandler(t, newTestDriver())
+	var evicted []string
+
+	tenantConfig := NewTenantConfig(nil, "db_"+TENANT_PLACEHOLDER)
+	tenantConfig.MaxDrivers = 1
+	tenantConfig.DriverCallback = func(resource *Resource, tenant string) (DatabaseDriver, error) {
+		return newTestDriver(), nil
+	}
+	tenantConfig.OnDriverEvicted = func(tenant string, driver DatabaseDriver) {
+		evicted = append(evicted, tenant)
+	}
+
+	_, dbPath, releaseA, err := tenantConfig.GetDriver(resource, "a")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dbPath != "db_a" {
+		t.Errorf("dbPath = %q, want db_a", dbPath)
+	}
+
+	// removes a from the cache while it is held
+	_, _, releaseB, err := tenantConfig.GetDriver(resource, "b")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(evicted) != 0 {
+		t.Fatalf("evicted = %v while held, want none", evicted)
+	}
+
+	releaseA()
+	releaseA()
+
+	if !slices.Equal(evicted, []string{"a"}) {
+		t.Fatalf("evicted = %v after the release, want [a]", evicted)
+	}
+
+	releaseB()
+
+	if _, exists := tenantConfig.getDrivers()["b"]; !exists || len(evicted) != 1 {
+		t.Errorf("evicted = %v, want the released b kept in the cache", evicted)
+	}
+
+	// not held, evicted right away
+	_, _, releaseC, _ := tenantConfig.GetDriver(resource, "c")
+	defer releaseC()
+
+	if !slices.Equal(evicted, []string{"a", "b"}) {
+		t.Errorf("evicted = %v, want [a b]", evicted)
+	}
+}
@@ -0,0 +1,74 @@
+package go_cake
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Tenant from the subdomain of the base domain, like "acme"
+// for acme.api.example.com with api.example.com base domain,
+// returns empty string for the base domain itself, IP hosts,
+// other domains and nested subdomains are rejected
+func NewHostTenantResolver(baseDomain string) TenantCallback {
+	baseDomain = strings.ToLower(strings.Trim(baseDomain, "."))
+
+	return func(resource *Resource, request *Request) (string, HTTPError) {
+		if request.Request == nil {
+			return "", nil
+		}
+
+		host := request.Request.Host
+
+		if hostOnly, _, err := net.SplitHostPort(host); err == nil {
+			host = hostOnly
+		}
+
+		host = strings.ToLower(strings.TrimSuffix(strings.Trim(host, "[]"), "."))
+
+		if net.ParseIP(host) != nil {
+			return "", NewInvalidTenantHTTPError(fmt.Errorf("host %v is an IP address", host))
+		}
+
+		if host == baseDomain {
+			// no subdomain
+			return "", nil
+		}
+
+		subdomain, found := strings.CutSuffix(host, "."+baseDomain)
+
+		if !found || strings.Contains(subdomain, ".") {
+			return "", NewInvalidTenantHTTPError(fmt.Errorf("host %v is not a subdomain of %v", host, baseDomain))
+		}
+
+		return subdomain, nil
+	}
+}
+
+func NewHeaderTenantResolver(header string) TenantCallback {
+	return func(resource *Resource, request *Request) (string, HTTPError) {
+		if request.Request == nil {
+			return "", nil
+		}
+
+		return strings.TrimSpace(request.Request.Header.Get(header)), nil
+	}
+}
+
+// Tenant from the claim of authenticated identity, requires
+// an auth callback which sets Request.Identity
+func NewClaimTenantResolver(claim string) TenantCallback {
+	return func(resource *Resource, request *Request) (string, HTTPError) {
+		if request.Identity == nil {
+			return "", nil
+		}
+
+		value, exists := request.Identity.Claims[claim]
+
+		if !exists || value == nil {
+			return "", nil
+		}
+
+		return fmt.Sprintf("%v", value), nil
+	}
+}
@@ -0,0 +1,44 @@
+package go_cake
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostTenantResolver(t *testing.T) {
+	resolver := NewHostTenantResolver("api.example.com")
+
+	tests := []struct {
+		host    string
+		want    string
+		wantErr bool
+	}{
+		{host: "acme.api.example.com", want: "acme"},
+		{host: "ACME.api.example.com:8080", want: "acme"},
+		{host: "acme.api.example.com.", want: "acme"},
+		{host: "api.example.com", want: ""},
+		{host: "api.example.com:443", want: ""},
+		{host: "example.com", wantErr: true},
+		{host: "acme.other.com", wantErr: true},
+		{host: "acmeapi.example.com", wantErr: true},
+		{host: "www.acme.api.example.com", wantErr: true},
+		{host: "10.0.0.1:8080", wantErr: true},
+		{host: "[::1]:8080", wantErr: true},
+	}
+
+	for _, test := range tests {
+		httpRequest := httptest.NewRequest("GET", "/v1/api/items", nil)
+		httpRequest.Host = test.host
+
+		tenant, httpErr := resolver(nil, &Request{Request: httpRequest})
+
+		if (httpErr != nil) != test.wantErr {
+			t.Errorf("resolver(%q) error = %v, want error %v", test.host, httpErr, test.wantErr)
+			continue
+		}
+
+		if tenant != test.want {
+			t.Errorf("resolver(%q) = %q, want %q", test.host, tenant, test.want)
+		}
+	}
+}
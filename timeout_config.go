@@ -0,0 +1,40 @@
+package go_cake
+
+import "time"
+
+// Time limits of the requests by operation, the client can
+// shorten the limit by REQUEST_TIMEOUT_HEADER but not extend it,
+// requests exceeding the limit end with 504
+type TimeoutConfig struct {
+	Default    time.Duration            // operations without own timeout, MAX_REQUEST_TIME_MS if 0
+	Operations map[string]time.Duration // per OPERATION_* timeouts
+}
+
+func NewTimeoutConfig(defaultTimeout time.Duration) *TimeoutConfig {
+	return &TimeoutConfig{
+		Default:    defaultTimeout,
+		Operations: make(map[string]time.Duration),
+	}
+}
+
+func (tc *TimeoutConfig) SetOperationTimeout(operation string, timeout time.Duration) *TimeoutConfig {
+	if tc.Operations == nil {
+		tc.Operations = make(map[string]time.Duration)
+	}
+
+	tc.Operations[operation] = timeout
+
+	return tc
+}
+
+func (tc *TimeoutConfig) GetTimeout(operation string) time.Duration {
+	if timeout, ok := tc.Operations[operation]; ok && timeout > 0 {
+		return timeout
+	}
+
+	if tc.Default > 0 {
+		return tc.Default
+	}
+
+	return MAX_REQUEST_TIME_MS * time.Millisecond
+}
@@ -0,0 +1,35 @@
+package go_cake
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TRACER_NAME = "github.com/skazanyNaGlany/go-cake"
+const SPAN_ATTRIBUTE_PREFIX = "go_cake."
+
+// Spans are no-op until a tracer provider is installed, see
+// the tracing package
+func getTracer() trace.Tracer {
+	return otel.Tracer(TRACER_NAME)
+}
+
+func startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return getTracer().Start(ctx, SPAN_ATTRIBUTE_PREFIX+name, trace.WithAttributes(attributes...))
+}
+
+func endSpan(span trace.Span, httpErr HTTPError) {
+	if httpErr != nil {
+		span.SetAttributes(attribute.Int(SPAN_ATTRIBUTE_PREFIX+"status_code", httpErr.GetStatusCode()))
+
+		if httpErr.GetStatusCode() >= 500 {
+			span.SetStatus(codes.Error, httpErr.GetStatusMessage())
+		}
+	}
+
+	span.End()
+}
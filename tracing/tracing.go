@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const DEFAULT_SERVICE_NAME = "go-cake"
+
+// Sets the global tracer provider used by go_cake and the
+// drivers, and W3C trace context propagator. Any provider can be
+// used, like the SDK one with OTLP exporter
+func Install(provider trace.TracerProvider) {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+// Writes finished spans as JSON, shut it down to flush
+func NewStdoutTracerProvider(writer io.Writer, serviceName string) (*sdktrace.TracerProvider, error) {
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(writer))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(newResource(serviceName))), nil
+}
+
+// Keeps finished spans in the exporter, see
+// tracetest.InMemoryExporter.GetSpans()
+func NewInMemoryTracerProvider(serviceName string) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(newResource(serviceName)))
+
+	return provider, exporter
+}
+
+func newResource(serviceName string) *sdkresource.Resource {
+	if serviceName == "" {
+		serviceName = DEFAULT_SERVICE_NAME
+	}
+
+	return sdkresource.NewSchemaless(attribute.String("service.name", serviceName))
+}
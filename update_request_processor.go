@@ -1,6 +1,8 @@
 package go_cake
 
 import (
+	"slices"
+
 	"github.com/thoas/go-funk"
 )
 
@@ -29,7 +31,11 @@ func (urp *UpdateRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoC
 		return nil, httpErr
 	}
 
-	if urp.request.HasWhere() || urp.request.HasSort() || urp.request.HasPage() {
+	if urp.request.HasWhere() ||
+		urp.request.HasSort() ||
+		urp.request.HasSearch() ||
+		urp.request.HasGeo() ||
+		urp.request.HasPage() {
 		return nil, NewModifiersNotAllowedHTTPError(nil)
 	}
 
@@ -61,11 +67,18 @@ func (urp *UpdateRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoC
 		ctxDbDriverUpdate)
 	defer cancel()
 
-	httpErr = urp.resource.DatabaseDriver.Update(
+	urp.preChangeActions(converted, ctx)
+
+	httpErr = urp.driver.Update(
 		urp.resource.DbModel,
+		urp.dbPath,
 		converted,
+		urp.serverFilter,
 		ctx,
-		nil)
+		urp.request.UserData)
+
+	urp.saveDocumentVersions(converted, OPERATION_UPDATE, httpErr, ctx)
+	urp.emitResourceEvent(EVENT_UPDATED, converted, httpErr)
 
 	httpErr = urp.callUpdatedDocumentsHandlers(converted, httpErr)
 
@@ -105,7 +118,15 @@ func (urp *UpdateRequestProcessor) preRequestJSONActions(jsonDocuments []map[str
 		updatableFields = urp.resource.DbModelJSONFields
 	}
 
+	// populated by the server
+	updatableFields = append(slices.Clone(updatableFields), urp.getServerFilterFields()...)
+
 	for _, jsonObject := range jsonDocuments {
+		if httpErr = urp.preRequestServerFilterActions(jsonObject); httpErr != nil {
+			jsonObject["__http_error__"] = httpErr
+			continue
+		}
+
 		if httpErr = urp.preRequestRequireOnUpdateChecks(
 			jsonObject,
 			requireOnUpdateFields); httpErr != nil {
@@ -0,0 +1,87 @@
+package go_cake
+
+// Lists previous versions of the item from the HistoryStore,
+// GET <collection>/<id>/_versions, newest first
+type VersionsRequestProcessor struct {
+	BaseRequestProcessor
+}
+
+func NewVersionsRequestProcessor(request *Request, resource *Resource) *VersionsRequestProcessor {
+	var versionsRequestProcessor VersionsRequestProcessor
+
+	versionsRequestProcessor.request = request
+	versionsRequestProcessor.resource = resource
+	versionsRequestProcessor.subRequestProcessor = &versionsRequestProcessor
+
+	return &versionsRequestProcessor
+}
+
+func (vrp *VersionsRequestProcessor) ProcessRequest(response *ResponseJSON) ([]GoCakeModel, HTTPError) {
+	if !vrp.request.IsGet || !vrp.resource.GetAllowed {
+		return nil, NewMethodNotAllowedHTTPError(nil)
+	}
+
+	if vrp.request.HasWhere() ||
+		vrp.request.HasSort() ||
+		vrp.request.HasSearch() ||
+		vrp.request.HasGeo() ||
+		vrp.request.DocumentVersion > 0 {
+		return nil, NewModifiersNotAllowedHTTPError(nil)
+	}
+
+	if vrp.request.PerPage == 0 {
+		vrp.request.PerPage = vrp.resource.GetMaxOutputItems
+	}
+
+	if vrp.request.PerPage > vrp.resource.GetMaxOutputItems {
+		return nil, NewPerPageTooLargeHTTPError(vrp.resource.GetMaxOutputItems, nil)
+	}
+
+	response.Meta.Page = vrp.request.Page
+	response.Meta.PerPage = vrp.request.PerPage
+
+	ctx, cancel := vrp.resource.ResourceCallback.CreateContext(
+		vrp.resource,
+		vrp.request,
+		response,
+		ctxDbDriverFind)
+	defer cancel()
+
+	// the item must be visible for the request
+	current, httpErr := vrp.findCurrentDocument(vrp.request.ItemID, ctx)
+
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	if current == nil {
+		return nil, NewObjectNotFoundHTTPError(nil)
+	}
+
+	versions, err := vrp.resource.HistoryStore.GetVersions(
+		vrp.resource,
+		vrp.driver,
+		vrp.dbPath,
+		vrp.request.ItemID,
+		ctx)
+
+	if err != nil {
+		return nil, NewLowLevelDriverHTTPError(err)
+	}
+
+	response.Meta.Total = uint64(len(versions))
+
+	documents := make([]GoCakeModel, 0)
+
+	if vrp.request.IsHead {
+		return documents, nil
+	}
+
+	start := vrp.request.Page * vrp.request.PerPage
+
+	for i := start; i < int64(len(versions)) && i < start+vrp.request.PerPage; i++ {
+		documents = append(documents, vrp.versionToDocument(versions[i]))
+	}
+
+	return documents, nil
+}
@@ -0,0 +1,34 @@
+package webhook
+
+import (
+	"time"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+)
+
+// Pending event for one subscription, kept in the Outbox
+// until delivered or out of attempts
+type Delivery struct {
+	ID              string                 `json:"id"`
+	SubscriptionID  string                 `json:"subscription_id"`
+	Event           *go_cake.ResourceEvent `json:"event"`
+	Attempts        int                    `json:"attempts"`
+	NextAttemptTime time.Time              `json:"next_attempt_time"`
+	CreatedTime     time.Time              `json:"created_time"`
+}
+
+// Result of a single delivery attempt
+type DeliveryRecord struct {
+	DeliveryID     string        `json:"delivery_id"`
+	SubscriptionID string        `json:"subscription_id"`
+	EventID        string        `json:"event_id"`
+	EventType      string        `json:"event_type"`
+	URL            string        `json:"url"`
+	Attempt        int           `json:"attempt"`
+	StatusCode     int           `json:"status_code,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	Duration       time.Duration `json:"duration"`
+	Time           time.Time     `json:"time"`
+	Delivered      bool          `json:"delivered"`
+	Dropped        bool          `json:"dropped"` // no more attempts
+}
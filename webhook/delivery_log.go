@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+const MEMORY_DELIVERY_LOG_DEFAULT_CAPACITY = 1000
+
+type DeliveryLog interface {
+	LogDelivery(record *DeliveryRecord) error
+}
+
+// Keeps the last records in memory
+type MemoryDeliveryLog struct {
+	capacity     int
+	records      []*DeliveryRecord
+	recordsMutex sync.RWMutex
+}
+
+func NewMemoryDeliveryLog(capacity int) *MemoryDeliveryLog {
+	if capacity <= 0 {
+		capacity = MEMORY_DELIVERY_LOG_DEFAULT_CAPACITY
+	}
+
+	return &MemoryDeliveryLog{capacity: capacity}
+}
+
+func (mdl *MemoryDeliveryLog) LogDelivery(record *DeliveryRecord) error {
+	mdl.recordsMutex.Lock()
+	defer mdl.recordsMutex.Unlock()
+
+	mdl.records = append(mdl.records, record)
+
+	if len(mdl.records) > mdl.capacity {
+		mdl.records = mdl.records[len(mdl.records)-mdl.capacity:]
+	}
+
+	return nil
+}
+
+// Oldest first
+func (mdl *MemoryDeliveryLog) GetRecords() []*DeliveryRecord {
+	mdl.recordsMutex.RLock()
+	defer mdl.recordsMutex.RUnlock()
+
+	records := make([]*DeliveryRecord, len(mdl.records))
+	copy(records, mdl.records)
+
+	return records
+}
+
+// Writes one JSON record per line
+type JSONLDeliveryLog struct {
+	writer      io.Writer
+	writerMutex sync.Mutex
+}
+
+func NewJSONLDeliveryLog(writer io.Writer) *JSONLDeliveryLog {
+	return &JSONLDeliveryLog{writer: writer}
+}
+
+func (jdl *JSONLDeliveryLog) LogDelivery(record *DeliveryRecord) error {
+	line, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	jdl.writerMutex.Lock()
+	defer jdl.writerMutex.Unlock()
+
+	_, err = jdl.writer.Write(append(line, '\n'))
+
+	return err
+}
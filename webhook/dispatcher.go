@@ -0,0 +1,418 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+	"github.com/skazanyNaGlany/go-cake/utils"
+)
+
+const ID_HEADER = "X-Webhook-ID"
+const EVENT_HEADER = "X-Webhook-Event"
+const TIMESTAMP_HEADER = "X-Webhook-Timestamp"
+const SIGNATURE_HEADER = "X-Webhook-Signature"
+const DEFAULT_MAX_ATTEMPTS = 8
+const DEFAULT_INITIAL_BACKOFF = time.Second
+const DEFAULT_MAX_BACKOFF = time.Hour
+const DEFAULT_TIMEOUT = 10 * time.Second
+const DEFAULT_WORKERS = 4
+const DEFAULT_POLL_INTERVAL = time.Second
+const MAX_RESPONSE_BODY_SIZE = 64 * 1024
+
+type DispatcherConfig struct {
+	Outbox         Outbox      // MemoryOutbox by default, use FileOutbox to survive restarts
+	DeliveryLog    DeliveryLog // optional
+	MaxAttempts    int
+	InitialBackoff time.Duration // doubled after each failed attempt
+	MaxBackoff     time.Duration
+	Timeout        time.Duration // of a single attempt
+	Workers        int           // concurrent deliveries
+	PollInterval   time.Duration // of the outbox
+	HTTPClient     *http.Client
+	Logger         *slog.Logger // of the outbox and delivery log errors, slog.Default() by default
+}
+
+// Delivers go_cake.ResourceEvent to the subscriptions, add it
+// to the resources by go_cake.Resource.AddEventListener(), events
+// are stored in the Outbox first and delivered by Start(),
+// add the subscriptions before Start() since deliveries of
+// unknown subscriptions are postponed by MaxBackoff
+type Dispatcher struct {
+	config             DispatcherConfig
+	subscriptions      map[string]*Subscription
+	subscriptionsMutex sync.RWMutex
+	wakeUp             chan struct{}
+	stop               chan struct{}
+	stopped            chan struct{}
+	runningMutex       sync.Mutex
+}
+
+func NewDispatcher(config DispatcherConfig) *Dispatcher {
+	if config.Outbox == nil {
+		config.Outbox = NewMemoryOutbox()
+	}
+
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DEFAULT_MAX_ATTEMPTS
+	}
+
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = DEFAULT_INITIAL_BACKOFF
+	}
+
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DEFAULT_MAX_BACKOFF
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = DEFAULT_TIMEOUT
+	}
+
+	if config.Workers <= 0 {
+		config.Workers = DEFAULT_WORKERS
+	}
+
+	if config.PollInterval <= 0 {
+		config.PollInterval = DEFAULT_POLL_INTERVAL
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{}
+	}
+
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	return &Dispatcher{
+		config:        config,
+		subscriptions: make(map[string]*Subscription),
+		wakeUp:        make(chan struct{}, 1),
+	}
+}
+
+func (d *Dispatcher) AddSubscription(subscription *Subscription) error {
+	if subscription.URL == "" {
+		return errors.New("no subscription URL set")
+	}
+
+	// deliveries in the Outbox refer to the ID, it must stay
+	// the same across restarts
+	if subscription.ID == "" {
+		return errors.New("no subscription ID set")
+	}
+
+	d.subscriptionsMutex.Lock()
+	defer d.subscriptionsMutex.Unlock()
+
+	d.subscriptions[subscription.ID] = subscription
+
+	return nil
+}
+
+// Pending deliveries of the subscription are dropped
+func (d *Dispatcher) RemoveSubscription(subscriptionID string) error {
+	d.subscriptionsMutex.Lock()
+	delete(d.subscriptions, subscriptionID)
+	d.subscriptionsMutex.Unlock()
+
+	return d.config.Outbox.RemoveBySubscription(subscriptionID)
+}
+
+func (d *Dispatcher) GetSubscription(subscriptionID string) *Subscription {
+	d.subscriptionsMutex.RLock()
+	defer d.subscriptionsMutex.RUnlock()
+
+	return d.subscriptions[subscriptionID]
+}
+
+func (d *Dispatcher) getMatchingSubscriptions(event *go_cake.ResourceEvent) []*Subscription {
+	d.subscriptionsMutex.RLock()
+	defer d.subscriptionsMutex.RUnlock()
+
+	matching := make([]*Subscription, 0)
+
+	for _, subscription := range d.subscriptions {
+		if subscription.Matches(event) {
+			matching = append(matching, subscription)
+		}
+	}
+
+	return matching
+}
+
+// go_cake.EventListener, called after successful writes
+func (d *Dispatcher) OnResourceEvent(event *go_cake.ResourceEvent) {
+	now := time.Now().UTC()
+	added := false
+
+	for _, subscription := range d.getMatchingSubscriptions(event) {
+		delivery := Delivery{
+			ID:              utils.StringUtilsInstance.NewUUID(),
+			SubscriptionID:  subscription.ID,
+			Event:           event,
+			NextAttemptTime: now,
+			CreatedTime:     now,
+		}
+
+		if err := d.config.Outbox.Add(&delivery); err != nil {
+			d.config.Logger.Error("unable to add webhook delivery",
+				slog.String("event_id", event.ID),
+				slog.String("subscription_id", subscription.ID),
+				slog.Any("error", err))
+			continue
+		}
+
+		added = true
+	}
+
+	if added {
+		d.notify()
+	}
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wakeUp <- struct{}{}:
+	default:
+	}
+}
+
+// Starts delivering in the background, deliveries left in
+// a persistent Outbox are resumed
+func (d *Dispatcher) Start() error {
+	d.runningMutex.Lock()
+	defer d.runningMutex.Unlock()
+
+	if d.stop != nil {
+		return errors.New("dispatcher already started")
+	}
+
+	d.stop = make(chan struct{})
+	d.stopped = make(chan struct{})
+
+	go d.run(d.stop, d.stopped)
+
+	return nil
+}
+
+// Waits for the deliveries in progress, pending deliveries
+// are kept in the Outbox
+func (d *Dispatcher) Stop() {
+	d.runningMutex.Lock()
+	defer d.runningMutex.Unlock()
+
+	if d.stop == nil {
+		return
+	}
+
+	close(d.stop)
+	<-d.stopped
+
+	d.stop = nil
+	d.stopped = nil
+}
+
+func (d *Dispatcher) run(stop chan struct{}, stopped chan struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverDue()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-d.wakeUp:
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue() {
+	deliveries, err := d.config.Outbox.Due(time.Now().UTC(), d.config.Workers*2)
+
+	if err != nil {
+		d.config.Logger.Error("unable to get webhook deliveries", slog.Any("error", err))
+		return
+	}
+
+	semaphore := make(chan struct{}, d.config.Workers)
+	var waitGroup sync.WaitGroup
+
+	for _, delivery := range deliveries {
+		semaphore <- struct{}{}
+		waitGroup.Add(1)
+
+		go func(delivery *Delivery) {
+			defer func() {
+				<-semaphore
+				waitGroup.Done()
+			}()
+
+			d.processDelivery(delivery)
+		}(delivery)
+	}
+
+	waitGroup.Wait()
+}
+
+func (d *Dispatcher) processDelivery(delivery *Delivery) {
+	subscription := d.GetSubscription(delivery.SubscriptionID)
+
+	if subscription == nil {
+		// not registered (yet) after restart, kept without
+		// counting the attempt
+		delivery.NextAttemptTime = time.Now().UTC().Add(d.config.MaxBackoff)
+
+		if err := d.config.Outbox.Update(delivery); err != nil {
+			d.logUpdateError(delivery, err)
+		}
+
+		return
+	}
+
+	delivery.Attempts++
+
+	startTime := time.Now()
+	statusCode, err := d.send(subscription, delivery)
+
+	record := DeliveryRecord{
+		DeliveryID:     delivery.ID,
+		SubscriptionID: subscription.ID,
+		EventID:        delivery.Event.ID,
+		EventType:      delivery.Event.Type,
+		URL:            subscription.URL,
+		Attempt:        delivery.Attempts,
+		StatusCode:     statusCode,
+		Duration:       time.Since(startTime),
+		Time:           startTime.UTC(),
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if err == nil {
+		record.Delivered = true
+		d.removeDelivery(delivery)
+	} else if delivery.Attempts >= d.config.MaxAttempts {
+		record.Dropped = true
+		d.removeDelivery(delivery)
+	} else {
+		delivery.NextAttemptTime = time.Now().UTC().Add(d.getBackoff(delivery.Attempts))
+
+		if err := d.config.Outbox.Update(delivery); err != nil {
+			d.logUpdateError(delivery, err)
+		}
+	}
+
+	d.logDelivery(&record)
+}
+
+func (d *Dispatcher) removeDelivery(delivery *Delivery) {
+	if err := d.config.Outbox.Remove(delivery.ID); err != nil {
+		d.config.Logger.Error("unable to remove webhook delivery",
+			slog.String("delivery_id", delivery.ID),
+			slog.Any("error", err))
+	}
+}
+
+func (d *Dispatcher) logUpdateError(delivery *Delivery, err error) {
+	d.config.Logger.Error("unable to update webhook delivery",
+		slog.String("delivery_id", delivery.ID),
+		slog.Any("error", err))
+}
+
+func (d *Dispatcher) logDelivery(record *DeliveryRecord) {
+	if d.config.DeliveryLog == nil {
+		return
+	}
+
+	if err := d.config.DeliveryLog.LogDelivery(record); err != nil {
+		d.config.Logger.Error("unable to log webhook delivery",
+			slog.String("delivery_id", record.DeliveryID),
+			slog.Any("error", err))
+	}
+}
+
+// Exponential backoff with up to 20% of jitter so failed
+// deliveries of the same subscription are spread out
+func (d *Dispatcher) getBackoff(attempts int) time.Duration {
+	backoff := d.config.InitialBackoff
+
+	for i := 1; i < attempts && backoff < d.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > d.config.MaxBackoff {
+		backoff = d.config.MaxBackoff
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(backoff)/5 + 1))
+
+	return backoff - jitter
+}
+
+// Any 2xx status means delivered
+func (d *Dispatcher) send(subscription *Subscription, delivery *Delivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.Timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+
+	for name, value := range subscription.Headers {
+		request.Header.Set(name, value)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(ID_HEADER, delivery.ID)
+	request.Header.Set(EVENT_HEADER, delivery.Event.Type)
+	request.Header.Set(TIMESTAMP_HEADER, strconv.FormatInt(timestamp, 10))
+
+	if subscription.Secret != "" {
+		request.Header.Set(SIGNATURE_HEADER, Sign(subscription.Secret, timestamp, body))
+	}
+
+	response, err := d.config.HTTPClient.Do(request)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	// drain so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, MAX_RESPONSE_BODY_SIZE))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected status %v", response.Status)
+	}
+
+	return response.StatusCode, nil
+}
@@ -0,0 +1,270 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+)
+
+func TestDispatcherGetBackoff(t *testing.T) {
+	dispatcher := NewDispatcher(DispatcherConfig{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+	})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration // without the jitter
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 100; i++ {
+			backoff := dispatcher.getBackoff(test.attempts)
+
+			// up to 20% of jitter
+			if backoff > test.want || backoff < test.want-test.want/5 {
+				t.Fatalf("getBackoff(%v) = %v, want within [%v, %v]",
+					test.attempts, backoff, test.want-test.want/5, test.want)
+			}
+		}
+	}
+}
+
+func TestDispatcherDeliversSignedEvent(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	receivedBody := make(chan []byte, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		received <- r
+		receivedBody <- body
+	}))
+	defer server.Close()
+
+	deliveryLog := NewMemoryDeliveryLog(0)
+	dispatcher := NewDispatcher(DispatcherConfig{DeliveryLog: deliveryLog})
+
+	dispatcher.AddSubscription(&Subscription{
+		ID:        "orders",
+		URL:       server.URL,
+		Secret:    "secret",
+		Resources: []string{"orders"},
+		Events:    []string{go_cake.EVENT_INSERTED},
+	})
+
+	// not matching the subscription
+	dispatcher.OnResourceEvent(&go_cake.ResourceEvent{ID: "1", Type: go_cake.EVENT_DELETED, Resource: "orders"})
+	dispatcher.OnResourceEvent(&go_cake.ResourceEvent{ID: "2", Type: go_cake.EVENT_INSERTED, Resource: "users"})
+
+	dispatcher.OnResourceEvent(&go_cake.ResourceEvent{
+		ID:        "3",
+		Type:      go_cake.EVENT_INSERTED,
+		Resource:  "orders",
+		Documents: []map[string]any{{"id": "a"}},
+	})
+
+	dispatcher.deliverDue()
+
+	request := <-received
+	body := <-receivedBody
+
+	timestamp, err := strconv.ParseInt(request.Header.Get(TIMESTAMP_HEADER), 10, 64)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !Verify("secret", timestamp, body, request.Header.Get(SIGNATURE_HEADER)) {
+		t.Errorf("%v = %q does not match the body", SIGNATURE_HEADER, request.Header.Get(SIGNATURE_HEADER))
+	}
+
+	var event go_cake.ResourceEvent
+
+	if err = json.Unmarshal(body, &event); err != nil || event.ID != "3" {
+		t.Errorf("delivered event = %s, want event 3", body)
+	}
+
+	if eventType := request.Header.Get(EVENT_HEADER); eventType != go_cake.EVENT_INSERTED {
+		t.Errorf("%v = %q, want %v", EVENT_HEADER, eventType, go_cake.EVENT_INSERTED)
+	}
+
+	if due, _ := dispatcher.config.Outbox.Due(time.Now().Add(time.Hour), 0); len(due) != 0 {
+		t.Errorf("%v deliveries left in the outbox, want none", len(due))
+	}
+
+	records := deliveryLog.GetRecords()
+
+	if len(records) != 1 || !records[0].Delivered || records[0].StatusCode != http.StatusOK || records[0].EventID != "3" {
+		t.Errorf("delivery log = %+v, want one delivered record of event 3", records)
+	}
+}
+
+func TestDispatcherRetriesFailedDelivery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	outbox := NewMemoryOutbox()
+	deliveryLog := NewMemoryDeliveryLog(0)
+	dispatcher := NewDispatcher(DispatcherConfig{
+		Outbox:         outbox,
+		DeliveryLog:    deliveryLog,
+		MaxAttempts:    2,
+		InitialBackoff: time.Minute,
+	})
+
+	dispatcher.AddSubscription(&Subscription{ID: "all", URL: server.URL})
+	dispatcher.OnResourceEvent(&go_cake.ResourceEvent{ID: "1", Type: go_cake.EVENT_UPDATED, Resource: "orders"})
+
+	dispatcher.deliverDue()
+
+	due, _ := outbox.Due(time.Now().Add(2*time.Minute), 0)
+
+	if len(due) != 1 || due[0].Attempts != 1 {
+		t.Fatalf("outbox = %+v, want the delivery kept after the first attempt", due)
+	}
+
+	if wait := time.Until(due[0].NextAttemptTime); wait < 47*time.Second || wait > time.Minute {
+		t.Errorf("next attempt in %v, want InitialBackoff with up to 20%% of jitter", wait)
+	}
+
+	// no attempt before the backoff
+	dispatcher.deliverDue()
+
+	if records := deliveryLog.GetRecords(); len(records) != 1 || records[0].StatusCode != http.StatusServiceUnavailable || records[0].Dropped {
+		t.Fatalf("delivery log = %+v, want one failed attempt", records)
+	}
+
+	delivery := *due[0]
+	delivery.NextAttemptTime = time.Now().UTC()
+
+	outbox.Update(&delivery)
+	dispatcher.deliverDue()
+
+	if due, _ = outbox.Due(time.Now().Add(time.Hour), 0); len(due) != 0 {
+		t.Errorf("outbox = %+v, want the delivery dropped after MaxAttempts", due)
+	}
+
+	if records := deliveryLog.GetRecords(); len(records) != 2 || !records[1].Dropped || records[1].Attempt != 2 {
+		t.Errorf("delivery log = %+v, want the second attempt dropped", records)
+	}
+}
+
+func TestDispatcherAddSubscription(t *testing.T) {
+	tests := []struct {
+		name         string
+		subscription *Subscription
+		wantErr      bool
+	}{
+		{
+			name:         "valid",
+			subscription: &Subscription{ID: "orders", URL: "http://localhost/hook"},
+		},
+		{
+			name:         "no ID",
+			subscription: &Subscription{URL: "http://localhost/hook"},
+			wantErr:      true,
+		},
+		{
+			name:         "no URL",
+			subscription: &Subscription{ID: "orders"},
+			wantErr:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dispatcher := NewDispatcher(DispatcherConfig{})
+
+			err := dispatcher.AddSubscription(test.subscription)
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("AddSubscription() error = %v, want error %v", err, test.wantErr)
+			}
+
+			if registered := dispatcher.GetSubscription(test.subscription.ID) != nil; registered == test.wantErr {
+				t.Errorf("registered = %v, want %v", registered, !test.wantErr)
+			}
+		})
+	}
+}
+
+func TestDispatcherKeepsDeliveriesOfUnknownSubscriptions(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	dispatcher := NewDispatcher(DispatcherConfig{Outbox: outbox, MaxBackoff: time.Minute})
+	now := time.Now().UTC()
+
+	outbox.Add(&Delivery{ID: "a", SubscriptionID: "not-registered", NextAttemptTime: now})
+
+	dispatcher.deliverDue()
+
+	due, _ := outbox.Due(now.Add(2*time.Minute), 0)
+
+	if len(due) != 1 {
+		t.Fatalf("Due() = %v, want the delivery kept", getDeliveryIDs(due))
+	}
+
+	if due[0].Attempts != 0 {
+		t.Errorf("Attempts = %v, want 0", due[0].Attempts)
+	}
+
+	if !due[0].NextAttemptTime.After(now.Add(time.Minute - time.Second)) {
+		t.Errorf("NextAttemptTime = %v, want postponed by MaxBackoff", due[0].NextAttemptTime)
+	}
+
+	if err := dispatcher.RemoveSubscription("not-registered"); err != nil {
+		t.Fatal(err)
+	}
+
+	if due, _ = outbox.Due(now.Add(2*time.Minute), 0); len(due) != 0 {
+		t.Errorf("Due() = %v after RemoveSubscription, want none", getDeliveryIDs(due))
+	}
+}
+
+// Outbox failing the updates
+type testFailingOutbox struct {
+	*MemoryOutbox
+}
+
+func (tfo testFailingOutbox) Update(delivery *Delivery) error {
+	return errors.New("outbox not writable")
+}
+
+func TestDispatcherLogsOutboxErrors(t *testing.T) {
+	var logged bytes.Buffer
+
+	outbox := testFailingOutbox{NewMemoryOutbox()}
+	dispatcher := NewDispatcher(DispatcherConfig{
+		Outbox: outbox,
+		Logger: slog.New(slog.NewTextHandler(&logged, nil)),
+	})
+
+	outbox.Add(&Delivery{ID: "a", SubscriptionID: "not-registered", NextAttemptTime: time.Now().UTC()})
+
+	dispatcher.deliverDue()
+
+	for _, want := range []string{"level=ERROR", "unable to update webhook delivery", "delivery_id=a", "outbox not writable"} {
+		if !strings.Contains(logged.String(), want) {
+			t.Errorf("logged %q, want %q", logged.String(), want)
+		}
+	}
+}
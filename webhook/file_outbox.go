@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const FILE_OUTBOX_EXTENSION = ".json"
+
+// delivery IDs are used as file names
+var deliveryIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Persistent Outbox keeping each delivery in its own JSON file,
+// files are replaced atomically so deliveries survive restarts
+// and crashes, the schedule of the deliveries is indexed in
+// memory so Due reads only the files of the due deliveries
+type FileOutbox struct {
+	directory      string
+	index          map[string]*Delivery // ID, SubscriptionID and NextAttemptTime only
+	directoryMutex sync.Mutex
+}
+
+func NewFileOutbox(directory string) (*FileOutbox, error) {
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, err
+	}
+
+	fileOutbox := FileOutbox{
+		directory: directory,
+		index:     make(map[string]*Delivery),
+	}
+
+	if err := fileOutbox.loadIndex(); err != nil {
+		return nil, err
+	}
+
+	return &fileOutbox, nil
+}
+
+func (fo *FileOutbox) loadIndex() error {
+	entries, err := os.ReadDir(fo.directory)
+
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		if entry.IsDir() || !strings.HasSuffix(name, FILE_OUTBOX_EXTENSION) {
+			continue
+		}
+
+		delivery, err := fo.readDelivery(filepath.Join(fo.directory, name))
+
+		if err != nil {
+			return err
+		}
+
+		if delivery == nil {
+			// skip corrupted files instead of blocking the outbox
+			continue
+		}
+
+		fo.index[delivery.ID] = fo.toIndexEntry(delivery)
+	}
+
+	return nil
+}
+
+// Returns nil if the file is corrupted
+func (fo *FileOutbox) readDelivery(path string) (*Delivery, error) {
+	var delivery Delivery
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &delivery); err != nil {
+		return nil, nil
+	}
+
+	return &delivery, nil
+}
+
+func (fo *FileOutbox) toIndexEntry(delivery *Delivery) *Delivery {
+	return &Delivery{
+		ID:              delivery.ID,
+		SubscriptionID:  delivery.SubscriptionID,
+		NextAttemptTime: delivery.NextAttemptTime,
+	}
+}
+
+func (fo *FileOutbox) getPath(deliveryID string) (string, error) {
+	if !deliveryIDPattern.MatchString(deliveryID) {
+		return "", errors.New("invalid delivery ID " + deliveryID)
+	}
+
+	return filepath.Join(fo.directory, deliveryID+FILE_OUTBOX_EXTENSION), nil
+}
+
+func (fo *FileOutbox) Add(delivery *Delivery) error {
+	path, err := fo.getPath(delivery.ID)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(delivery)
+
+	if err != nil {
+		return err
+	}
+
+	fo.directoryMutex.Lock()
+	defer fo.directoryMutex.Unlock()
+
+	tempFile, err := os.CreateTemp(fo.directory, ".tmp-*")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tempFile.Write(data)
+
+	if err == nil {
+		err = tempFile.Sync()
+	}
+
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tempFile.Name())
+
+		return err
+	}
+
+	if err = os.Rename(tempFile.Name(), path); err != nil {
+		return err
+	}
+
+	fo.index[delivery.ID] = fo.toIndexEntry(delivery)
+
+	return nil
+}
+
+func (fo *FileOutbox) Update(delivery *Delivery) error {
+	return fo.Add(delivery)
+}
+
+func (fo *FileOutbox) Remove(deliveryID string) error {
+	path, err := fo.getPath(deliveryID)
+
+	if err != nil {
+		return err
+	}
+
+	fo.directoryMutex.Lock()
+	defer fo.directoryMutex.Unlock()
+
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	delete(fo.index, deliveryID)
+
+	return nil
+}
+
+func (fo *FileOutbox) RemoveBySubscription(subscriptionID string) error {
+	fo.directoryMutex.Lock()
+	defer fo.directoryMutex.Unlock()
+
+	for deliveryID, indexEntry := range fo.index {
+		if indexEntry.SubscriptionID != subscriptionID {
+			continue
+		}
+
+		path, err := fo.getPath(deliveryID)
+
+		if err != nil {
+			return err
+		}
+
+		if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		delete(fo.index, deliveryID)
+	}
+
+	return nil
+}
+
+// Files are read without holding the lock, deliveries removed
+// in the meantime are skipped
+func (fo *FileOutbox) Due(now time.Time, limit int) ([]*Delivery, error) {
+	fo.directoryMutex.Lock()
+
+	dueEntries := make([]*Delivery, 0)
+
+	for _, indexEntry := range fo.index {
+		if !indexEntry.NextAttemptTime.After(now) {
+			dueEntries = append(dueEntries, indexEntry)
+		}
+	}
+
+	dueEntries = limitDueDeliveries(dueEntries, limit)
+
+	fo.directoryMutex.Unlock()
+
+	due := make([]*Delivery, 0, len(dueEntries))
+
+	for _, indexEntry := range dueEntries {
+		path, err := fo.getPath(indexEntry.ID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		delivery, err := fo.readDelivery(path)
+
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if delivery == nil {
+			// skip corrupted files instead of blocking the outbox
+			continue
+		}
+
+		due = append(due, delivery)
+	}
+
+	return due, nil
+}
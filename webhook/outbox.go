@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Storage of pending deliveries
+type Outbox interface {
+	Add(delivery *Delivery) error
+	Update(delivery *Delivery) error
+	Remove(deliveryID string) error
+	RemoveBySubscription(subscriptionID string) error
+
+	// at most limit deliveries with NextAttemptTime before
+	// now, the oldest first
+	Due(now time.Time, limit int) ([]*Delivery, error)
+}
+
+// Outbox kept in memory, deliveries are lost on restart
+type MemoryOutbox struct {
+	deliveries      map[string]*Delivery
+	deliveriesMutex sync.Mutex
+}
+
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{deliveries: make(map[string]*Delivery)}
+}
+
+func (mo *MemoryOutbox) Add(delivery *Delivery) error {
+	mo.deliveriesMutex.Lock()
+	defer mo.deliveriesMutex.Unlock()
+
+	mo.deliveries[delivery.ID] = delivery
+
+	return nil
+}
+
+func (mo *MemoryOutbox) Update(delivery *Delivery) error {
+	return mo.Add(delivery)
+}
+
+func (mo *MemoryOutbox) Remove(deliveryID string) error {
+	mo.deliveriesMutex.Lock()
+	defer mo.deliveriesMutex.Unlock()
+
+	delete(mo.deliveries, deliveryID)
+
+	return nil
+}
+
+func (mo *MemoryOutbox) RemoveBySubscription(subscriptionID string) error {
+	mo.deliveriesMutex.Lock()
+	defer mo.deliveriesMutex.Unlock()
+
+	for deliveryID, delivery := range mo.deliveries {
+		if delivery.SubscriptionID == subscriptionID {
+			delete(mo.deliveries, deliveryID)
+		}
+	}
+
+	return nil
+}
+
+func (mo *MemoryOutbox) Due(now time.Time, limit int) ([]*Delivery, error) {
+	mo.deliveriesMutex.Lock()
+	defer mo.deliveriesMutex.Unlock()
+
+	due := make([]*Delivery, 0)
+
+	for _, delivery := range mo.deliveries {
+		if !delivery.NextAttemptTime.After(now) {
+			due = append(due, delivery)
+		}
+	}
+
+	return limitDueDeliveries(due, limit), nil
+}
+
+func limitDueDeliveries(due []*Delivery, limit int) []*Delivery {
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptTime.Before(due[j].NextAttemptTime)
+	})
+
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due
+}
@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOutboxDue(t *testing.T) {
+	now := time.Now().UTC()
+
+	deliveries := []*Delivery{
+		{ID: "c", SubscriptionID: "s1", NextAttemptTime: now.Add(-time.Second)},
+		{ID: "a", SubscriptionID: "s1", NextAttemptTime: now.Add(-3 * time.Second)},
+		{ID: "future", SubscriptionID: "s1", NextAttemptTime: now.Add(time.Minute)},
+		{ID: "b", SubscriptionID: "s2", NextAttemptTime: now.Add(-2 * time.Second)},
+		{ID: "now", SubscriptionID: "s2", NextAttemptTime: now},
+	}
+
+	tests := []struct {
+		name   string
+		limit  int
+		remove []string
+		want   []string
+	}{
+		{
+			name:  "oldest first",
+			limit: 0,
+			want:  []string{"a", "b", "c", "now"},
+		},
+		{
+			name:  "limited",
+			limit: 2,
+			want:  []string{"a", "b"},
+		},
+		{
+			name:   "removed skipped",
+			limit:  10,
+			remove: []string{"b"},
+			want:   []string{"a", "c", "now"},
+		},
+	}
+
+	for outboxName, newOutbox := range getTestOutboxes() {
+		for _, test := range tests {
+			t.Run(outboxName+"/"+test.name, func(t *testing.T) {
+				outbox := newOutbox(t)
+
+				for _, delivery := range deliveries {
+					deliveryCopy := *delivery
+
+					if err := outbox.Add(&deliveryCopy); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				for _, deliveryID := range test.remove {
+					if err := outbox.Remove(deliveryID); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				due, err := outbox.Due(now, test.limit)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got := getDeliveryIDs(due); !slices.Equal(got, test.want) {
+					t.Errorf("Due() = %v, want %v", got, test.want)
+				}
+			})
+		}
+	}
+}
+
+func TestOutboxUpdateAndRemoveBySubscription(t *testing.T) {
+	now := time.Now().UTC()
+
+	for outboxName, newOutbox := range getTestOutboxes() {
+		t.Run(outboxName, func(t *testing.T) {
+			outbox := newOutbox(t)
+
+			outbox.Add(&Delivery{ID: "a", SubscriptionID: "s1", NextAttemptTime: now.Add(-2 * time.Second)})
+			outbox.Add(&Delivery{ID: "b", SubscriptionID: "s2", NextAttemptTime: now})
+			outbox.Add(&Delivery{ID: "c", SubscriptionID: "s1", NextAttemptTime: now.Add(-time.Second)})
+
+			// postponed like after a failed attempt
+			if err := outbox.Update(&Delivery{ID: "b", SubscriptionID: "s2", Attempts: 1, NextAttemptTime: now.Add(time.Minute)}); err != nil {
+				t.Fatal(err)
+			}
+
+			due, _ := outbox.Due(now, 0)
+
+			if got := getDeliveryIDs(due); !slices.Equal(got, []string{"a", "c"}) {
+				t.Fatalf("Due() = %v, want [a c]", got)
+			}
+
+			if err := outbox.RemoveBySubscription("s1"); err != nil {
+				t.Fatal(err)
+			}
+
+			due, _ = outbox.Due(now.Add(time.Hour), 0)
+
+			if len(due) != 1 || due[0].ID != "b" || due[0].Attempts != 1 {
+				t.Fatalf("Due() = %+v, want the updated delivery b", due)
+			}
+		})
+	}
+}
+
+func TestFileOutboxReload(t *testing.T) {
+	now := time.Now().UTC()
+	directory := t.TempDir()
+
+	outbox, err := NewFileOutbox(directory)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outbox.Add(&Delivery{ID: "b", SubscriptionID: "s1", NextAttemptTime: now.Add(-time.Second)})
+	outbox.Add(&Delivery{ID: "a", SubscriptionID: "s1", NextAttemptTime: now.Add(-2 * time.Second)})
+
+	if err = os.WriteFile(filepath.Join(directory, "corrupted"+FILE_OUTBOX_EXTENSION), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewFileOutbox(directory)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	due, err := reloaded.Due(now, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := getDeliveryIDs(due); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("Due() after reload = %v, want [a b]", got)
+	}
+
+	if err = reloaded.Add(&Delivery{ID: "../escape"}); err == nil {
+		t.Error("Add() accepted a delivery ID which is not a file name")
+	}
+}
+
+func getTestOutboxes() map[string]func(t *testing.T) Outbox {
+	return map[string]func(t *testing.T) Outbox{
+		"memory": func(*testing.T) Outbox {
+			return NewMemoryOutbox()
+		},
+		"file": func(t *testing.T) Outbox {
+			outbox, err := NewFileOutbox(t.TempDir())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return outbox
+		},
+	}
+}
+
+func getDeliveryIDs(deliveries []*Delivery) []string {
+	ids := make([]string, 0, len(deliveries))
+
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.ID)
+	}
+
+	return ids
+}
@@ -0,0 +1,31 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+const SIGNATURE_PREFIX = "sha256="
+
+// "sha256=" and hex encoded HMAC-SHA256 of the unix timestamp
+// and the body separated by a dot, sent in SIGNATURE_HEADER
+// with the timestamp in TIMESTAMP_HEADER
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return SIGNATURE_PREFIX + hex.EncodeToString(mac.Sum(nil))
+}
+
+// For the receivers, the timestamp should be checked too
+// to reject replayed requests
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	expected := Sign(secret, timestamp, body)
+
+	return hmac.Equal([]byte(expected), []byte(strings.TrimSpace(signature)))
+}
@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"slices"
+
+	go_cake "github.com/skazanyNaGlany/go-cake"
+)
+
+const SUBSCRIPTION_ANY = "*"
+
+type Subscription struct {
+	ID        string
+	URL       string
+	Secret    string            // HMAC key of the signature, not signed if empty
+	Resources []string          // resource names, SUBSCRIPTION_ANY or empty for all
+	Events    []string          // go_cake.EVENT_* types, SUBSCRIPTION_ANY or empty for all
+	Headers   map[string]string // additional request headers
+}
+
+func (s *Subscription) Matches(event *go_cake.ResourceEvent) bool {
+	return s.matches(s.Resources, event.Resource) && s.matches(s.Events, event.Type)
+}
+
+func (s *Subscription) matches(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	return slices.Contains(values, SUBSCRIPTION_ANY) || slices.Contains(values, value)
+}